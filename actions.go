@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// pickerAction is an action that can be performed on the entries selected in
+// the picker.
+type pickerAction struct {
+	Name        string
+	Description string
+	// Key is the fzf key that triggers the action directly from the
+	// picker. Actions without a key can only be triggered from the
+	// actions menu.
+	Key string
+	// Run performs the action on the selected entries. It returns true if
+	// the picker should be reopened once the action completes.
+	Run func(entries []FeedEntry, state *State) (reopen bool, err error)
+}
+
+// getPickerActions returns all actions available in the picker. The list is
+// built on each call so that key bindings reflect the loaded settings.
+func (a *App) getPickerActions() []pickerAction {
+	return []pickerAction{
+		{
+			Name:        "play",
+			Description: "Play the selected videos",
+			Key:         "enter",
+			Run:         a.playAction,
+		},
+		{
+			Name:        "audio",
+			Description: "Play the selected videos without video",
+			Key:         a.AudioKey,
+			Run:         a.playAudioAction,
+		},
+		{
+			Name:        "queue",
+			Description: "Add the selected videos to the playlist of a background mpv, and return to the picker",
+			Key:         a.QueueKey,
+			Run:         a.queueAction,
+		},
+		{
+			Name:        "enqueue",
+			Description: "Add the selected videos to the saved queue, to play with `yt-rss queue play`",
+			Key:         a.EnqueueKey,
+			Run:         a.enqueueAction,
+		},
+		{
+			Name:        "pin",
+			Description: "Pin or unpin the selected videos at the top of the list",
+			Key:         a.PinKey,
+			Run:         a.pinAction,
+		},
+		{
+			Name:        "later",
+			Description: "Add the selected videos to the watch-later list, or remove them from it",
+			Key:         a.LaterKey,
+			Run:         a.laterAction,
+		},
+		{
+			Name:        "open",
+			Description: "Open the selected videos in the browser",
+			Key:         a.OpenKey,
+			Run:         a.openAction,
+		},
+		{
+			Name:        "download",
+			Description: "Download the selected videos with yt-dlp",
+			Key:         a.DownloadKey,
+			Run:         a.downloadAction,
+		},
+		{
+			Name:        "copy",
+			Description: "Copy the URLs of the selected videos",
+			Key:         a.CopyKey,
+			Run:         a.copyAction,
+		},
+	}
+}
+
+func findActionByKey(actions []pickerAction, key string) (pickerAction, bool) {
+	if key == "" {
+		key = "enter"
+	}
+	for _, v := range actions {
+		if v.Key == key {
+			return v, true
+		}
+	}
+	return pickerAction{}, false
+}
+
+func findActionByName(actions []pickerAction, name string) (pickerAction, bool) {
+	for _, v := range actions {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return pickerAction{}, false
+}
+
+// playAction plays the entries one after another, in the order they were
+// selected. Playback stops at the first entry that fails to play.
+func (a *App) playAction(entries []FeedEntry, state *State) (bool, error) {
+	return false, a.playEntries(entries, state, a.AudioOnly)
+}
+
+func (a *App) playAudioAction(entries []FeedEntry, state *State) (bool, error) {
+	return false, a.playEntries(entries, state, true)
+}
+
+// playEntries plays the entries in sequence. Audio only is used if audio is
+// true, or if it is enabled for the entry's channel in the URLs file. Entries
+// that are known to be unplayable, e.g. private or deleted videos, are
+// skipped.
+func (a *App) playEntries(entries []FeedEntry, state *State, audio bool) error {
+	for _, entry := range entries {
+		err := a.unpinPlayedEntry(entry, state)
+		if err != nil {
+			return err
+		}
+		entryAudio := audio
+		if !entryAudio {
+			// Fall back to the per-channel setting
+			subscription, ok, err := a.getSubscriptionForEntry(entry)
+			if err != nil {
+				return err
+			}
+			entryAudio = ok && subscription.HasOption("audio")
+		}
+		_, isDownloaded, err := a.findDownloadedFile(entry.YTVideoID)
+		if err != nil {
+			return err
+		}
+		if a.CheckAvailabilityBeforePlaying && a.Backend == backendYouTube && !a.offline && !(isDownloaded && a.PreferLocalFiles) {
+			availability, err := a.checkAvailability(entry)
+			if err == nil && !availability.Available {
+				a.printer.Fprintf(os.Stderr, "Skipping %s: video is %s (%s)\n", entry.WatchURL(), availability.Category, availability.Reason)
+				continue
+			}
+		}
+		err = a.recordPlay(entry)
+		if err != nil {
+			return err
+		}
+		err = a.playEntry(entry, entryAudio)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpinPlayedEntry unpins the entry and removes it from the watch-later list,
+// since played entries no longer need to be in either.
+func (a *App) unpinPlayedEntry(entry FeedEntry, state *State) error {
+	if !state.IsPinned(entry.ID) && !state.InWatchLater(entry.YTVideoID) {
+		return nil
+	}
+	state.Unpin(entry.ID)
+	state.RemoveFromWatchLater(entry.YTVideoID)
+	return a.saveState(state)
+}
+
+// queueAction appends the entries to the playlist of an mpv instance running
+// in the background, so that more videos can be picked while it plays.
+func (a *App) queueAction(entries []FeedEntry, state *State) (bool, error) {
+	for _, entry := range entries {
+		err := a.unpinPlayedEntry(entry, state)
+		if err != nil {
+			return false, err
+		}
+		url, err := a.mpvPlaylistURL(entry)
+		if err != nil {
+			return false, err
+		}
+		err = a.recordPlay(entry)
+		if err != nil {
+			return false, err
+		}
+		err = a.queueInMPV(entry, url)
+		if err != nil {
+			return false, err
+		}
+		a.printer.Fprintf(os.Stderr, "Queued %s\n", entry.WatchURL())
+	}
+	return true, nil
+}
+
+// mpvPlaylistURL returns the URL to add the entry to an mpv playlist with:
+// the downloaded file, if there is one and PreferLocalFiles is set, or the
+// playback URL.
+func (a *App) mpvPlaylistURL(entry FeedEntry) (string, error) {
+	if a.PreferLocalFiles {
+		path, ok, err := a.findDownloadedFile(entry.YTVideoID)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return path, nil
+		}
+	}
+	return a.playbackURL(entry), nil
+}
+
+func (a *App) pinAction(entries []FeedEntry, state *State) (bool, error) {
+	// Toggle the pins, then reopen the picker so the entries move into
+	// (or out of) the pinned section.
+	for _, entry := range entries {
+		state.TogglePin(entry.ID)
+	}
+	return true, a.saveStateWithUndo(state, fmt.Sprintf("pin/unpin %d entries", len(entries)))
+}
+
+func (a *App) openAction(entries []FeedEntry, state *State) (bool, error) {
+	for _, entry := range entries {
+		err := openInBrowser(a.browserURL(entry))
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// runOpen opens videos in the browser, given by their IDs or URLs, on the
+// frontend set with browser_frontend.
+func (a *App) runOpen(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: yt-rss open <video ID or URL>...")
+	}
+	for _, v := range args {
+		if id := parseVideoIDFromURL(v); id != "" {
+			v = id
+		}
+		entry := FeedEntry{YTVideoID: v}
+		a.printer.Fprintf(os.Stderr, "Opening %s in the browser\n", a.browserURL(entry))
+		err := openInBrowser(a.browserURL(entry))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *App) downloadAction(entries []FeedEntry, state *State) (bool, error) {
+	for _, entry := range entries {
+		err := a.downloadEntry(entry)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (a *App) copyAction(entries []FeedEntry, state *State) (bool, error) {
+	var urls []string
+	for _, entry := range entries {
+		urls = append(urls, entry.WatchURL())
+	}
+	if a.printURLs {
+		fmt.Println(strings.Join(urls, "\n"))
+		return !a.urlPicker, nil
+	}
+	return !a.urlPicker, copyToClipboard(strings.Join(urls, "\n"))
+}
+
+// runURL copies the URLs of videos to the clipboard, given by their IDs or
+// watch URLs, or selected in the picker if none are given. With --print, the
+// URLs are printed instead, e.g. to pipe them to another program.
+func (a *App) runURL(args []string) error {
+	fs := flag.NewFlagSet("url", flag.ExitOnError)
+	fs.BoolVar(&a.printURLs, "print", false, "Print the URLs instead of copying them to the clipboard")
+	a.addCacheFlags(fs)
+	fs.Parse(args)
+	args = fs.Args()
+
+	if len(args) == 0 {
+		feedEntries, err := a.loadFeedEntries()
+		if err != nil {
+			return err
+		}
+		a.urlPicker = true
+		return a.selectAndRun(feedEntries, "copy")
+	}
+	var entries []FeedEntry
+	for _, v := range args {
+		if id := parseVideoIDFromURL(v); id != "" {
+			v = id
+		}
+		entries = append(entries, FeedEntry{YTVideoID: v})
+	}
+	_, err := a.copyAction(entries, nil)
+	if err != nil {
+		return err
+	}
+	if !a.printURLs {
+		a.printer.Fprintf(os.Stderr, "Copied %d URLs to the clipboard\n", len(entries))
+	}
+	return nil
+}
+
+// selectActionFromMenu opens a secondary fzf menu listing the actions that
+// can be performed on the entries. The actions shown, and their order, can be
+// configured via ActionsMenu. ok is false if the menu was dismissed.
+func (a *App) selectActionFromMenu(actions []pickerAction, entries []FeedEntry) (action pickerAction, ok bool, err error) {
+	var menuActions []pickerAction
+	if len(a.ActionsMenu) == 0 {
+		menuActions = actions
+	} else {
+		for _, name := range a.ActionsMenu {
+			action, ok := findActionByName(actions, name)
+			if !ok {
+				return pickerAction{}, false, fmt.Errorf("unknown action in actions menu: %s", name)
+			}
+			menuActions = append(menuActions, action)
+		}
+	}
+
+	var lines []string
+	for _, v := range menuActions {
+		line := fmt.Sprintf("%-12s %s", v.Name, v.Description)
+		if v.Key != "" {
+			line += fmt.Sprintf(" (%s)", v.Key)
+		}
+		lines = append(lines, line)
+	}
+
+	prompt := entries[0].ExtraMetadata.NormalizedTitle
+	if len(entries) > 1 {
+		prompt = fmt.Sprintf("%d videos", len(entries))
+	}
+
+	selection, ok, err := a.runMenu(prompt+" > ", lines)
+	if err != nil || !ok {
+		return pickerAction{}, false, err
+	}
+	action, ok = findActionByName(menuActions, strings.Fields(selection)[0])
+	return action, ok, nil
+}
+
+// runMenu shows the options in fzf, in order, and returns the selected
+// option. ok is false if the menu was dismissed.
+func (a *App) runMenu(prompt string, options []string) (selection string, ok bool, err error) {
+	switch a.getPickerBackend() {
+	case pickerPlain:
+		return runPlainMenu(prompt, options)
+	case pickerTUI:
+		return runTUIMenu(prompt, options)
+	case pickerRofi, pickerDmenu, pickerWofi, pickerFuzzel:
+		return a.runLauncherMenu(prompt, options)
+	}
+	r := strings.NewReader(strings.Join(options, "\n"))
+	b := &bytes.Buffer{}
+	args := []string{
+		"--no-sort",
+		"--prompt=" + prompt,
+	}
+	err = runShellCommand("fzf", args, r, b)
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok && e.ExitCode() != 2 {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	selection = strings.TrimSpace(b.String())
+	return selection, selection != "", nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// App holds the configuration and the state of yt-rss. All state is kept
+// here rather than in package-level variables, so that more than one App can
+// run in the same process.
+type App struct {
+	*Config
+
+	configFile string // The URLs file
+
+	// Set by command flags
+	selectedCategory string // Limits the entries to feeds in the category. See --category.
+	forceRefresh     bool   // See addCacheFlags
+	offline          bool
+	progressFormat   string
+	shuffle          bool // See addShuffleFlags
+	watchLaterView   bool // The picker shows the watch-later list instead of the feeds. See `yt-rss later play`.
+	historyView      bool // The picker shows the watched videos instead of the feeds. See `yt-rss history`.
+	urlPicker        bool // The picker exits once the URLs of the selected videos are copied. See `yt-rss url`.
+	printURLs        bool // The copy action prints the URLs instead of copying them. See `yt-rss url --print`.
+	favoritesOnly    bool // Only videos from favorite channels are shown. See --favorites and FavoritesKey.
+	shuffleSeed      int64
+
+	// progressDisplay shows the progress of a refresh while it runs.
+	// Progress reporters created meanwhile draw on it instead of their own
+	// bars.
+	progressDisplay *progressDisplay
+
+	// favoritesView holds both views of the picker while it shows the
+	// feeds, so that FavoritesKey can switch between them.
+	favoritesView *favoritesView
+
+	// replaying is true while a session is being replayed. The picker
+	// prints the entries it would show instead of waiting for a selection.
+	replaying bool
+
+	sessionID string // Identifies this run in the session log
+
+	theme colorTheme // Styles of the picker and the preview. See setupTheme.
+
+	// printer formats user-facing messages in language. Messages are
+	// looked up in the catalog by their English format string, which is
+	// used as-is if there is no translation. See setupLanguage.
+	printer  *message.Printer
+	language language.Tag
+
+	logger *leveledLogger // Diagnostic messages. See setupLogging.
+
+	// location is the time zone dates are shown in, set with timezone. It
+	// defaults to the local time zone, rather than the feed's, which is UTC
+	// for YouTube. See setupTimezone.
+	location *time.Location
+
+	// ctx is cancelled when a refresh is interrupted, which aborts the
+	// requests in flight. See cancelOnInterrupt.
+	ctx context.Context
+
+	httpClient       *http.Client // Used for all requests, unless redirects shouldn't be followed
+	noRedirectClient *http.Client
+	metadataLimiter  *rateLimiter // Rate limits scraping video pages
+
+	// pageCache holds the pages fetched or read from disk in this
+	// session, keyed by video ID. Pages are fetched by concurrent metadata
+	// workers, so it is guarded by pageCacheMutex.
+	pageCacheMutex sync.Mutex
+	pageCache      map[string]string
+	prunePagesOnce sync.Once
+
+	// titleNormalizer normalizes the titles shown, compiled from the
+	// title settings on first use. See normalizeTitle.
+	titleNormalizer     *titleNormalizer
+	titleNormalizerOnce sync.Once
+
+	// db is the SQLite database, opened on first use if Storage is
+	// "sqlite". dbSnapshot holds the rows as they were last read, so that
+	// only changed rows are written back. See openDatabase.
+	db         *sql.DB
+	dbSnapshot databaseSnapshot
+}
+
+// newApp returns an App with the configuration, which should be loaded
+// first, since the HTTP clients and rate limiter are created from it.
+func newApp(config *Config) *App {
+	printer := message.NewPrinter(language.English)
+	return &App{
+		Config:           config,
+		configFile:       getConfigFile(),
+		progressFormat:   progressFormatBar,
+		sessionID:        newSessionID(),
+		printer:          printer,
+		language:         language.English,
+		logger:           &leveledLogger{level: logLevelInfo, printer: printer},
+		location:         time.Local,
+		ctx:              context.Background(),
+		httpClient:       newHTTPClient(config.HTTPTimeout, true),
+		noRedirectClient: newHTTPClient(config.HTTPTimeout, false),
+		metadataLimiter:  newRateLimiter(config.MetadataRateLimit),
+		pageCache:        make(map[string]string),
+	}
+}
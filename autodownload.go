@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// autoDownloadRule is a per-channel rule to download new videos
+// automatically. It is declared with the auto-download option in the URLs
+// file, optionally with a pattern that titles must match:
+//
+//	https://www.youtube.com/feeds/videos.xml?channel_id=UC... auto-download
+//	https://www.youtube.com/feeds/videos.xml?channel_id=UC... auto-download="(?i)review"
+type autoDownloadRule struct {
+	Pattern *regexp.Regexp // nil matches all titles
+}
+
+func (a *App) getAutoDownloadRules() (map[string]autoDownloadRule, error) {
+	subscriptions, err := a.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	rules := make(map[string]autoDownloadRule) // Keyed by feed URL
+	for _, v := range subscriptions {
+		pattern, ok := v.Options["auto-download"]
+		if !ok {
+			continue
+		}
+		var rule autoDownloadRule
+		if pattern != "" {
+			rule.Pattern, err = regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auto-download pattern for %s: %s", v.URL, err)
+			}
+		}
+		rules[v.URL] = rule
+	}
+	return rules, nil
+}
+
+// runAutoDownloads downloads new entries matching the auto-download rules.
+// Entries are new if they were published within AutoDownloadMaxAge, and
+// haven't been downloaded or watched. Entries hidden from the picker, e.g. by
+// the title rules, are not downloaded.
+func (a *App) runAutoDownloads(entries []FeedEntry) error {
+	rules, err := a.getAutoDownloadRules()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	downloads, err := a.loadDownloads()
+	if err != nil {
+		return err
+	}
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	filter, err := a.newEntryFilter()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range entries {
+		rule, ok := rules[v.ExtraMetadata.FeedURL]
+		if !ok {
+			continue
+		}
+		if _, ok := downloads[v.YTVideoID]; ok || history.IsWatched(v.YTVideoID) {
+			continue
+		}
+		if time.Since(v.GetPublishedDate()) > a.AutoDownloadMaxAge {
+			continue
+		}
+		if a.shouldFilterOutEntry(v, filter) {
+			continue
+		}
+		if rule.Pattern != nil && !rule.Pattern.MatchString(v.MediaGroup.Title) {
+			continue
+		}
+		err := a.downloadEntry(v)
+		if err != nil {
+			// Keep going, the next run will retry this entry
+			a.logger.Errorf("failed to download %s: %s\n", v.WatchURL(), err)
+		}
+	}
+	return nil
+}
+
+// runAutoDownload refreshes the feeds if the cache is stale, then downloads
+// new videos matching the auto-download rules, and prunes old downloads. It is
+// meant to be run periodically, e.g. from cron.
+func (a *App) runAutoDownload(args []string) error {
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	entries, err := a.loadFeedEntries()
+	if err != nil {
+		return err
+	}
+	err = a.runAutoDownloads(entries)
+	if err != nil {
+		return err
+	}
+	return a.pruneDownloads(false)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// playabilityStatusRegex extracts the playability status from the player
+// response embedded in a video's watch page, e.g.
+// "playabilityStatus":{"status":"LOGIN_REQUIRED","reason":"This video is private"
+var playabilityStatusRegex = regexp.MustCompile(`"playabilityStatus":\{"status":"([A-Z_]+)"(?:,"reason":"((?:[^"\\]|\\.)*)")?`)
+
+// videoAvailability describes whether a video can be played.
+type videoAvailability struct {
+	Available bool
+	// Category is a short classification of why the video is
+	// unavailable, e.g. "private" or "region-blocked".
+	Category string
+	// Reason is YouTube's explanation of why the video is unavailable.
+	Reason string
+}
+
+// checkAvailability probes the video's watch page to find out if it can be
+// played. If the probe fails, an error is returned, and callers should assume
+// the video is available rather than block playback.
+func (a *App) checkAvailability(entry FeedEntry) (videoAvailability, error) {
+	page, err := a.getCachedVideoPage(entry.YTVideoID, entry.WatchURL())
+	if err != nil {
+		return videoAvailability{}, err
+	}
+
+	matches := playabilityStatusRegex.FindStringSubmatch(page)
+	if len(matches) < 2 || matches[1] == "OK" {
+		// If the status can't be found, let the player try anyway.
+		return videoAvailability{Available: true}, nil
+	}
+	status := matches[1]
+	reason := strings.ReplaceAll(matches[2], `\"`, `"`)
+	return videoAvailability{
+		Available: false,
+		Category:  classifyUnavailability(status, reason),
+		Reason:    reason,
+	}, nil
+}
+
+// classifyUnavailability maps YouTube's playability status and reason to a
+// short category.
+func classifyUnavailability(status, reason string) string {
+	lowerReason := strings.ToLower(reason)
+	switch {
+	case strings.Contains(lowerReason, "private"):
+		return "private"
+	case strings.Contains(lowerReason, "members"):
+		return "members-only"
+	case strings.Contains(lowerReason, "country"):
+		return "region-blocked"
+	case strings.Contains(lowerReason, "age") || status == "CONTENT_CHECK_REQUIRED":
+		return "age-restricted"
+	case strings.Contains(lowerReason, "removed") || strings.Contains(lowerReason, "terminated") || strings.Contains(lowerReason, "deleted"):
+		return "deleted"
+	case status == "LIVE_STREAM_OFFLINE":
+		return "upcoming"
+	default:
+		return "unavailable"
+	}
+}
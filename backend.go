@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Backends to fetch feeds and metadata from, and to play videos through, set
+// with backend. The Invidious and Piped backends don't contact youtube.com,
+// for users who block it, and get durations from the feed or a small API
+// response instead of scraping video pages.
+const (
+	backendYouTube   = "youtube"
+	backendInvidious = "invidious" // Through InvidiousInstance, using its RSS feeds and API
+	backendPiped     = "piped"     // Through PipedInstance, using its API
+)
+
+var backends = []string{backendYouTube, backendInvidious, backendPiped}
+
+// canonicalContentURL is the URL YouTube's feeds have in media:content.
+// Entries from other backends get the same URL, so that cached entries don't
+// depend on the backend they were fetched from.
+func canonicalContentURL(videoID string) string {
+	return "https://www.youtube.com/v/" + videoID + "?version=3"
+}
+
+// watchURL returns the URL of the video's watch page on the backend, for
+// opening in the browser.
+func (a *App) watchURL(entry FeedEntry) string {
+	switch a.Backend {
+	case backendInvidious:
+		return strings.TrimSuffix(a.InvidiousInstance, "/") + "/watch?v=" + entry.YTVideoID
+	case backendPiped:
+		return strings.TrimSuffix(a.PipedFrontend, "/") + "/watch?v=" + entry.YTVideoID
+	default:
+		return entry.WatchURL()
+	}
+}
+
+// Sites that videos can be opened on in the browser. See browserURL.
+const (
+	browserFrontendAuto      = "auto" // The backend's web frontend, as in watchURL
+	browserFrontendYouTube   = "youtube"
+	browserFrontendInvidious = "invidious" // invidious_instance, even without the invidious backend
+	browserFrontendPiped     = "piped"     // piped_frontend, even without the piped backend
+)
+
+// browserURL returns the URL to open the video at in the browser, on the
+// frontend set with browser_frontend.
+func (a *App) browserURL(entry FeedEntry) string {
+	switch a.BrowserFrontend {
+	case browserFrontendYouTube:
+		return entry.WatchURL()
+	case browserFrontendInvidious:
+		return strings.TrimSuffix(a.InvidiousInstance, "/") + "/watch?v=" + entry.YTVideoID
+	case browserFrontendPiped:
+		return strings.TrimSuffix(a.PipedFrontend, "/") + "/watch?v=" + entry.YTVideoID
+	default:
+		return a.watchURL(entry)
+	}
+}
+
+// playbackURL returns the URL the player streams the video from. With the
+// Invidious and Piped backends, it is their watch page, which yt-dlp
+// recognizes for the well-known instances.
+func (a *App) playbackURL(entry FeedEntry) string {
+	if a.Backend == backendYouTube {
+		return entry.MediaGroup.Content.URL
+	}
+	return a.watchURL(entry)
+}
+
+// backendFeedPath returns the path of the feed on the Invidious or Piped
+// instance, e.g. "channel/UC...", or an error if the feed isn't a YouTube
+// channel or playlist feed.
+func backendFeedPath(feedURL string) (kind string, id string, err error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return "", "", err
+	}
+	if id := u.Query().Get("channel_id"); id != "" {
+		return "channel", id, nil
+	}
+	if id := u.Query().Get("playlist_id"); id != "" {
+		return "playlist", id, nil
+	}
+	return "", "", fmt.Errorf("not a YouTube channel or playlist feed: %s", feedURL)
+}
+
+// getBackendFeed fetches the YouTube feed through the Invidious or Piped
+// backend. Feeds that aren't YouTube's, e.g. local test feeds, are fetched
+// directly.
+func (a *App) getBackendFeed(feedURL string) (*Feed, error) {
+	kind, id, err := backendFeedPath(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	var feed *Feed
+	if a.Backend == backendInvidious {
+		feed, err = a.getInvidiousFeed(kind, id)
+	} else {
+		feed, err = a.getPipedFeed(kind, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range feed.Entries {
+		feed.Entries[i].MediaGroup.Content.URL = canonicalContentURL(feed.Entries[i].YTVideoID)
+	}
+	return feed, nil
+}
+
+// getInvidiousFeed fetches a feed from Invidious, whose RSS feeds have the
+// same elements as YouTube's.
+func (a *App) getInvidiousFeed(kind, id string) (*Feed, error) {
+	resp, err := a.getBackendResponse(strings.TrimSuffix(a.InvidiousInstance, "/") + "/feed/" + kind + "/" + id)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch feed")
+	}
+	defer resp.Body.Close()
+	feed := &Feed{}
+	err = xml.NewDecoder(a.limitBody(resp)).Decode(feed)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode feed")
+	}
+	if kind == "playlist" {
+		feed.PlaylistID = id
+	}
+	return feed, nil
+}
+
+// pipedStream is a video in Piped's API responses.
+type pipedStream struct {
+	URL              string `json:"url"` // e.g. "/watch?v=..."
+	Title            string `json:"title"`
+	Thumbnail        string `json:"thumbnail"`
+	UploaderName     string `json:"uploaderName"`
+	UploaderURL      string `json:"uploaderUrl"` // e.g. "/channel/UC..."
+	Uploaded         int64  `json:"uploaded"`    // Unix milliseconds, or -1 if unknown
+	ShortDescription string `json:"shortDescription"`
+	Duration         int64  `json:"duration"` // Seconds, or -1 for livestreams
+	Views            int64  `json:"views"`
+	IsShort          bool   `json:"isShort"`
+}
+
+// getPipedFeed fetches a channel's or playlist's videos from Piped's API, and
+// converts them to a feed. Unlike YouTube's feeds, the videos have durations.
+func (a *App) getPipedFeed(kind, id string) (*Feed, error) {
+	path := "/channel/" + id
+	if kind == "playlist" {
+		path = "/playlists/" + id
+	}
+	resp, err := a.getBackendResponse(strings.TrimSuffix(a.PipedInstance, "/") + path)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch feed")
+	}
+	defer resp.Body.Close()
+	var channel struct {
+		Name           string        `json:"name"`
+		Uploader       string        `json:"uploader"` // The playlist's owner
+		RelatedStreams []pipedStream `json:"relatedStreams"`
+	}
+	err = json.NewDecoder(a.limitBody(resp)).Decode(&channel)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode feed")
+	}
+
+	feed := &Feed{Title: channel.Name}
+	feed.Author.Name = channel.Name
+	if kind == "playlist" {
+		feed.PlaylistID = id
+		feed.Author.Name = channel.Uploader
+	}
+	for _, v := range channel.RelatedStreams {
+		videoID := strings.TrimPrefix(v.URL, "/watch?v=")
+		if videoID == v.URL {
+			continue
+		}
+		entry := FeedEntry{
+			ID:        "yt:video:" + videoID,
+			YTVideoID: videoID,
+			ChannelID: strings.TrimPrefix(v.UploaderURL, "/channel/"),
+		}
+		if v.Uploaded > 0 {
+			entry.Published = time.UnixMilli(v.Uploaded).UTC().Format(time.RFC3339)
+			entry.Updated = entry.Published
+		}
+		entry.Author.Name = v.UploaderName
+		entry.MediaGroup.Title = v.Title
+		entry.MediaGroup.Thumbnail.URL = v.Thumbnail
+		entry.MediaGroup.Description = v.ShortDescription
+		entry.MediaGroup.Community.Statistics.Views = v.Views
+		if v.Duration > 0 {
+			entry.ExtraMetadata.VideoDuration = time.Duration(v.Duration) * time.Second
+			isShort := v.IsShort
+			entry.ExtraMetadata.IsShort = &isShort
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed, nil
+}
+
+// addBackendMetadata adds the duration and live status of the entry from the
+// Invidious or Piped API, instead of scraping its video page. Whether the
+// video is a Short is guessed from its duration, unless the feed said so.
+func (a *App) addBackendMetadata(entry *FeedEntry) error {
+	if entry.ExtraMetadata.VideoDuration == 0 || entry.ExtraMetadata.LiveStatus != "" {
+		var err error
+		if a.Backend == backendInvidious {
+			err = a.addInvidiousMetadata(entry)
+		} else {
+			err = a.addPipedMetadata(entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if entry.ExtraMetadata.IsShort == nil && entry.ExtraMetadata.VideoDuration > 0 {
+		isShort := entry.ExtraMetadata.VideoDuration < a.ShortsThreshold
+		entry.ExtraMetadata.IsShort = &isShort
+	}
+	return nil
+}
+
+func (a *App) addInvidiousMetadata(entry *FeedEntry) error {
+	resp, err := a.getBackendResponse(strings.TrimSuffix(a.InvidiousInstance, "/") + "/api/v1/videos/" + entry.YTVideoID + "?fields=lengthSeconds,liveNow,isUpcoming")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var video struct {
+		LengthSeconds int64 `json:"lengthSeconds"`
+		LiveNow       bool  `json:"liveNow"`
+		IsUpcoming    bool  `json:"isUpcoming"`
+	}
+	err = json.NewDecoder(a.limitBody(resp)).Decode(&video)
+	if err != nil {
+		return errors.Wrap(err, "decode video")
+	}
+	entry.ExtraMetadata.VideoDuration = time.Duration(video.LengthSeconds) * time.Second
+	switch {
+	case video.LiveNow:
+		entry.ExtraMetadata.LiveStatus = liveStatusLive
+	case video.IsUpcoming:
+		// Invidious doesn't tell scheduled livestreams and
+		// premieres apart.
+		entry.ExtraMetadata.LiveStatus = liveStatusUpcoming
+	default:
+		entry.ExtraMetadata.LiveStatus = ""
+	}
+	return nil
+}
+
+func (a *App) addPipedMetadata(entry *FeedEntry) error {
+	resp, err := a.getBackendResponse(strings.TrimSuffix(a.PipedInstance, "/") + "/streams/" + entry.YTVideoID)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var video struct {
+		Duration   int64 `json:"duration"`
+		Livestream bool  `json:"livestream"`
+	}
+	err = json.NewDecoder(a.limitBody(resp)).Decode(&video)
+	if err != nil {
+		return errors.Wrap(err, "decode video")
+	}
+	entry.ExtraMetadata.LiveStatus = ""
+	if video.Livestream {
+		entry.ExtraMetadata.LiveStatus = liveStatusLive
+	} else if video.Duration > 0 {
+		entry.ExtraMetadata.VideoDuration = time.Duration(video.Duration) * time.Second
+	}
+	return nil
+}
+
+// getBackendResponse fetches a URL on the Invidious or Piped instance, and
+// fails unless the response is successful.
+func (a *App) getBackendResponse(url string) (*http.Response, error) {
+	resp, err := a.httpGet(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status from %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
@@ -2,68 +2,249 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
-	"path"
+	"path/filepath"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
+// cacheVersion is the version of the cache's format. Increment it when the
+// format changes in a way that needs migrating, and handle the old version in
+// migrateCache.
+const cacheVersion = 1
+
 type Cache struct {
+	// Version of the format the cache was written in. Caches written
+	// before versioning was added are version 0.
+	Version            int         `json:"version"`
 	LastQueryTimestamp time.Time   `json:"last_query_timestamp"`
 	FeedEntries        []FeedEntry `json:"feed_entries"`
+
+	// When each feed was last fetched, keyed by feed URL. Caches written
+	// before this was added only have LastQueryTimestamp.
+	FeedFetchedAt map[string]time.Time `json:"feed_fetched_at,omitempty"`
+
+	// When each feed's latest video was published, keyed by feed URL. See
+	// recordLastUploads.
+	FeedLastUpload map[string]time.Time `json:"feed_last_upload,omitempty"`
+
+	// Metadata imported from elsewhere, keyed by video ID. See
+	// importInfoJSON.
+	KnownMetadata map[string]VideoMetadata `json:"known_metadata,omitempty"`
 }
 
-func getCacheFile() string {
-	fileName := path.Join(getConfigDir(), "yt-rss/cache.json")
-	return fileName
+// feedCacheDuration returns how long the feed is cached for, based on the
+// type of feed.
+func (a *App) feedCacheDuration(feedURL string) time.Duration {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return a.RSSCacheDuration
+	}
+	switch {
+	case u.Query().Get("channel_id") != "":
+		return a.CacheDuration
+	case u.Query().Get("playlist_id") != "":
+		return a.PlaylistCacheDuration
+	default:
+		return a.RSSCacheDuration
+	}
 }
 
-func getFromCache() (entries []FeedEntry, isStale bool, err error) {
-	cacheFile := getCacheFile()
+// staleFeeds returns the feeds that haven't been fetched within their cache
+// duration.
+func (c *Cache) staleFeeds(feedURLs []string, cacheDuration func(feedURL string) time.Duration) []string {
+	var stale []string
+	for _, v := range feedURLs {
+		fetchedAt, ok := c.FeedFetchedAt[v]
+		if !ok {
+			fetchedAt = c.LastQueryTimestamp
+		}
+		if time.Since(fetchedAt) > cacheDuration(v) {
+			stale = append(stale, v)
+		}
+	}
+	return stale
+}
+
+func (a *App) getCacheFile() string {
+	return a.getDataFile(getCacheDir(), "cache.json")
+}
 
-	_, err = os.Stat(cacheFile)
+func (a *App) getFromCache() (entries []FeedEntry, err error) {
+	cache, err := a.loadCache()
+	if err != nil {
+		return nil, err
+	}
+	return cache.FeedEntries, nil
+}
+
+// loadCache returns the cache, or an empty cache if it doesn't exist. The
+// titles are normalized again, in case the title rules have changed.
+func (a *App) loadCache() (*Cache, error) {
+	useDatabase, err := a.useDatabase()
+	if err != nil {
+		return nil, err
+	}
+	var cache *Cache
+	if useDatabase {
+		cache, err = a.loadCacheFromDatabase()
+	} else {
+		cache, err = a.loadCacheFile()
+	}
+	if cache != nil {
+		a.normalizeTitles(cache.FeedEntries)
+	}
+	return cache, err
+}
+
+// loadCacheFile returns the cache from the JSON cache file.
+func (a *App) loadCacheFile() (*Cache, error) {
+	cacheFile := a.getCacheFile()
+
+	_, err := os.Stat(cacheFile)
 	if os.IsNotExist(err) {
-		return nil, true, nil
+		return &Cache{}, nil
 	}
 
 	f, err := os.Open(cacheFile)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
+	defer f.Close()
 
 	b, err := ioutil.ReadAll(f)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
 	cache := &Cache{}
 	err = json.Unmarshal(b, cache)
 	if err != nil {
-		return nil, false, err
+		// The cache can always be rebuilt, so don't let a corrupted
+		// cache stop yt-rss from starting.
+		return &Cache{}, a.recoverCorruptedCache(cacheFile, err)
+	}
+	err = migrateCache(cache)
+	if err != nil {
+		return nil, err
 	}
+	return cache, nil
+}
 
-	// Check if cache is stale
-	if time.Now().Sub(cache.LastQueryTimestamp) > cacheDuration {
-		return cache.FeedEntries, true, nil
+// recoverCorruptedCache moves the corrupted cache aside, so that a fresh
+// cache is written in its place. The corrupted file is kept for debugging.
+func (a *App) recoverCorruptedCache(cacheFile string, unmarshalErr error) error {
+	a.logger.Warnf("The cache is corrupted (%s), starting with an empty cache\n", unmarshalErr)
+	if a.ReadOnly {
+		return nil
 	}
+	backupFile := fmt.Sprintf("%s.corrupted-%s", cacheFile, time.Now().Format("20060102-150405"))
+	err := os.Rename(cacheFile, backupFile)
+	if err != nil {
+		return errors.Wrap(err, "back up corrupted cache")
+	}
+	a.logger.Warnf("Moved %s to %s\n", cacheFile, backupFile)
+	return nil
+}
 
-	// Cache is still valid
-	return cache.FeedEntries, false, nil
+// migrateCache upgrades a cache written in an older format to the current
+// version.
+func migrateCache(cache *Cache) error {
+	if cache.Version > cacheVersion {
+		return fmt.Errorf("the cache was written by a newer version of yt-rss (cache version %d, supported version %d)", cache.Version, cacheVersion)
+	}
+	// Version 0 only lacks the version field, so there's nothing to
+	// migrate yet.
+	cache.Version = cacheVersion
+	return nil
 }
 
-func writeToCache(entries []FeedEntry) (err error) {
-	cacheFile := getCacheFile()
-	cache := &Cache{
-		LastQueryTimestamp: time.Now(),
-		FeedEntries:        entries,
+func (a *App) writeToCache(cache *Cache) (err error) {
+	if a.ReadOnly {
+		return nil
+	}
+	cache.Version = cacheVersion
+	cache.LastQueryTimestamp = time.Now()
+	useDatabase, err := a.useDatabase()
+	if err != nil {
+		return err
 	}
+	if useDatabase {
+		return a.writeCacheToDatabase(cache)
+	}
+	return a.writeCacheFile(cache)
+}
+
+func (a *App) writeCacheFile(cache *Cache) error {
+	cacheFile := a.getCacheFile()
 	b, err := json.Marshal(cache)
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(cacheFile, b, 0600)
+	return writeFileAtomic(cacheFile, b, 0600)
+}
+
+// writeFileAtomic writes the file by writing to a temporary file in the same
+// directory, then renaming it over the file, so that the file is never left
+// partially written if yt-rss is interrupted.
+func writeFileAtomic(fileName string, b []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".tmp-*")
 	if err != nil {
 		return err
 	}
-	return nil
+	defer os.Remove(f.Name()) // No-op once renamed
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(f.Name(), perm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fileName)
+}
+
+// pruneFeedEntries applies the cache retention limits. YouTube's feeds only
+// return the latest few videos of each channel, so the cache accumulates
+// older entries across refreshes, until they are older than CacheRetention,
+// or exceed CacheMaxEntriesPerFeed. Entries are sorted newest first, so the
+// oldest entries of each feed are pruned first. Entries still in the latest
+// fetch of the feeds, and pinned entries, are always kept.
+func (a *App) pruneFeedEntries(entries []FeedEntry, feeds []Feed, state *State) []FeedEntry {
+	fetched := make(map[string]bool)
+	for _, feed := range feeds {
+		for _, v := range feed.Entries {
+			fetched[v.ID] = true
+		}
+	}
+
+	var kept []FeedEntry
+	perFeed := make(map[string]int) // feed URL to number of entries kept
+	for _, v := range entries {
+		if !fetched[v.ID] && !state.IsPinned(v.ID) {
+			if a.CacheRetention > 0 && time.Since(v.GetPublishedDate()) > a.CacheRetention {
+				continue
+			}
+			if a.CacheMaxEntriesPerFeed > 0 && perFeed[v.ExtraMetadata.FeedURL] >= a.CacheMaxEntriesPerFeed {
+				continue
+			}
+		}
+		perFeed[v.ExtraMetadata.FeedURL]++
+		kept = append(kept, v)
+	}
+	return kept
 }
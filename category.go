@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+)
+
+// parseCategoryHeader parses a category header in the URLs file, e.g.
+// "[music]". Subscriptions after a header belong to that category, until the
+// next header.
+func parseCategoryHeader(line string) (category string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	return strings.TrimSpace(line[1 : len(line)-1]), true
+}
+
+// getFeedCategories returns a lookup of feed URLs to their categories. Feeds
+// without a category are omitted.
+func (a *App) getFeedCategories() (map[string]string, error) {
+	subscriptions, err := a.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	categories := make(map[string]string)
+	for _, v := range subscriptions {
+		if v.Category != "" {
+			categories[v.URL] = v.Category
+		}
+	}
+	return categories, nil
+}
+
+// insertSubscription adds the line to the URLs file's lines, at the end of
+// the category's section. The section is created if it doesn't exist. Lines
+// without a category are added before the first category header, so that
+// they don't end up in a category by accident.
+func insertSubscription(lines []string, line string, category string) []string {
+	current := ""
+	insertAt := -1
+	for i, v := range lines {
+		if c, ok := parseCategoryHeader(v); ok {
+			if current == category {
+				// Reached the end of the category's section
+				break
+			}
+			current = c
+			if current == category {
+				insertAt = i + 1
+			}
+			continue
+		}
+		if current == category && strings.TrimSpace(v) != "" {
+			insertAt = i + 1
+		}
+	}
+	if insertAt == -1 {
+		if category == "" {
+			return append([]string{line}, lines...)
+		}
+		return append(lines, "", "["+category+"]", line)
+	}
+	lines = append(lines[:insertAt], append([]string{line}, lines[insertAt:]...)...)
+	return lines
+}
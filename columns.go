@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Columns of the picker, set with the columns setting
+const (
+	columnDate     = "date"
+	columnDuration = "duration"
+	columnViews    = "views"
+	columnChannel  = "channel"
+	columnWatched  = "watched"
+	columnTitle    = "title"
+)
+
+var pickerColumnNames = []string{columnDate, columnDuration, columnViews, columnChannel, columnWatched, columnTitle}
+
+// columnSeparator separates the columns of a picker line. The tab delimits
+// the fields for fzf's --nth, and is shown as a single space, since fzf is
+// run with --tabstop=1.
+const columnSeparator = " |\t"
+
+// pickerColumns returns the columns shown in the picker, in order. For
+// compatibility, show_views adds the views after the duration if they aren't
+// already a column.
+func (a *App) pickerColumns() []string {
+	columns := slices.Clone(a.Columns)
+	if len(columns) == 0 {
+		columns = defaultConfig().Columns
+	}
+	if a.ShowViews && !slices.Contains(columns, columnViews) {
+		i := slices.Index(columns, columnDuration) + 1
+		if i == 0 {
+			i = len(columns)
+		}
+		columns = slices.Insert(columns, i, columnViews)
+	}
+	return columns
+}
+
+// searchedFields returns the fields of the displayed lines that fzf searches,
+// for --nth, e.g. "3,4" for the channel and title columns of the default
+// layout. It's empty if neither column is shown, so that every column is
+// searched.
+func searchedFields(columns []string) string {
+	var fields []string
+	for i, v := range columns {
+		if v == columnChannel || v == columnTitle {
+			fields = append(fields, fmt.Sprint(i+1))
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// pickerLineDisplay returns the displayed part of a picker line, without the
+// video ID, for the pickers other than fzf.
+func pickerLineDisplay(line string) string {
+	_, display, _ := strings.Cut(line, "\t")
+	return strings.ReplaceAll(display, "\t", " ")
+}
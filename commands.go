@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type command struct {
+	Name        string
+	Description string
+	Run         func(args []string) error
+	// Hidden commands are used internally, and aren't shown in the help.
+	Hidden bool
+}
+
+// getCommands returns the subcommands supported by yt-rss. If no subcommand
+// is given, browse is used.
+func (a *App) getCommands() []command {
+	return []command{
+		{
+			Name:        "browse",
+			Description: "Browse and play videos from your subscriptions (default)",
+			Run:         a.runBrowse,
+		},
+		{
+			Name:        "auto-download",
+			Description: "Download new videos matching the auto-download rules in the URLs file",
+			Run:         a.runAutoDownload,
+		},
+		{
+			Name:        "config",
+			Description: "Check the settings and URLs files for mistakes with `config validate`, or edit them with `config edit [urls]`",
+			Run:         a.runConfig,
+		},
+		{
+			Name:        "count",
+			Description: "Print the number of unwatched videos from the cache, for status bars (--by-category, --json for waybar)",
+			Run:         a.runCount,
+		},
+		{
+			Name:        "daemon",
+			Description: "Keep the feeds refreshed in the background, so that browsing starts instantly",
+			Run:         a.runDaemon,
+		},
+		{
+			Name:        "diff-subscriptions",
+			Description: "Compare subscriptions against a Google Takeout subscriptions.csv",
+			Run:         a.runDiffSubscriptions,
+		},
+		{
+			Name:        "doctor",
+			Description: "Check every subscribed feed, and report dead, renamed, and stale channels",
+			Run:         a.runDoctor,
+		},
+		{
+			Name:        "download",
+			Description: "Download videos with yt-dlp, by video ID or from the picker",
+			Run:         a.runDownload,
+		},
+		{
+			Name:        "export",
+			Description: "Export videos to a file, e.g. `export atom` or `export --output videos.html html` (--list for all formats), or subscriptions with `export --type freetube subscriptions`",
+			Run:         a.runExport,
+		},
+		{
+			Name:        "features",
+			Description: "Show which optional features are available on this system, and why others aren't",
+			Run:         a.runFeatures,
+		},
+		{
+			Name:        "history",
+			Description: "Browse the videos you have watched, to play them again or copy their URLs (--print to list them), or export them with `history export --format csv|json`",
+			Run:         a.runHistory,
+		},
+		{
+			Name:        "import",
+			Description: "Import data: `import watch-history <watch-history.json|takeout.zip>` from Google Takeout, `import info-json <dir>` from yt-dlp, `import subscriptions <file>` from a list of URLs, `import takeout <subscriptions.csv>` from Google Takeout, or `import newpipe <file>` and `import freetube <file>` from those apps",
+			Run:         a.runImport,
+		},
+		{
+			Name:        "notify",
+			Description: "Report videos that are new since the last run, for cron jobs and status bars. Exits with 3 if there are new videos.",
+			Run:         a.runNotify,
+		},
+		{
+			Name:        "now",
+			Description: "Print the video being played, for status bars (--json for waybar, or --format for a template)",
+			Run:         a.runNow,
+		},
+		{
+			Name:        "open",
+			Description: "Open videos in the browser by video ID or URL, on the site set with browser_frontend",
+			Run:         a.runOpen,
+		},
+		{
+			Name:        "prune-downloads",
+			Description: "Delete watched downloads according to the retention settings",
+			Run:         a.runPruneDownloads,
+		},
+		{
+			Name:        "search",
+			Description: "Search titles, channels, and descriptions of all cached videos",
+			Run:         a.runSearch,
+		},
+		{
+			Name:        "serve",
+			Description: "Serve a web UI for browsing and playing videos (--addr, default 127.0.0.1:8080)",
+			Run:         a.runServe,
+		},
+		{
+			Name:        "stats",
+			Description: "Show statistics, such as the disk usage of downloads, or a heatmap of watched videos with --heatmap",
+			Run:         a.runStats,
+		},
+		{
+			Name:        "status",
+			Description: "Show the status of the daemon",
+			Run:         a.runStatus,
+		},
+		{
+			Name:        "subscribe",
+			Description: "Subscribe to the channel or playlist of any YouTube URL",
+			Run:         a.runSubscribe,
+		},
+		{
+			Name:        "subscriptions",
+			Description: "List subscriptions by their latest upload, from the cache, or only those without uploads for a while with `subscriptions --stale 90d`",
+			Run:         a.runSubscriptions,
+		},
+		{
+			Name:        "undo",
+			Description: "Undo the last change to pins, watch history, or subscriptions",
+			Run:         a.runUndo,
+		},
+		{
+			Name:        "replay",
+			Description: "Replay a session from the session log in read-only mode, or list recent sessions",
+			Run:         a.runReplay,
+		},
+		{
+			Name:        "mute",
+			Description: "Hide a channel's videos without unsubscribing: `mute <channel> [--for 30d]`, or list the muted channels",
+			Run:         a.runMute,
+		},
+		{
+			Name:        "unmute",
+			Description: "Show a muted channel's videos again",
+			Run:         a.runUnmute,
+		},
+		{
+			Name:        "later",
+			Description: "Manage the watch-later list: `later add <video>...`, `later remove <video>...`, `later list`, or `later play` to pick from it",
+			Run:         a.runLater,
+		},
+		{
+			Name:        "queue",
+			Description: "Manage the play queue: `queue add <video>...`, `queue remove <video>...`, `queue list`, `queue clear`, or `queue play` to play it in order",
+			Run:         a.runQueue,
+		},
+		{
+			Name:        "list",
+			Description: "Print videos to stdout instead of opening the picker (--json, or --format for a template)",
+			Run:         a.runList,
+		},
+		{
+			Name:        "show",
+			Description: "Print the details and full description of a video, with the links in it",
+			Run:         a.runShow,
+		},
+		{
+			Name:        "url",
+			Description: "Copy the URLs of videos to the clipboard, by video ID or from the picker (--print to print them)",
+			Run:         a.runURL,
+		},
+		{
+			Name:        "preview",
+			Description: "Print details about a video, for fzf's preview pane",
+			Run:         a.runPreview,
+			Hidden:      true,
+		},
+		{
+			Name:        "favorites-toggle",
+			Description: "Switch the picker between all videos and favorites only, for fzf's reload binding",
+			Run:         a.runFavoritesToggle,
+			Hidden:      true,
+		},
+		{
+			Name:        "warm-metadata",
+			Description: "Fetch the metadata that was deferred by a refresh",
+			Run:         a.runWarmMetadata,
+			Hidden:      true,
+		},
+		{
+			Name:        "help",
+			Description: "Show this help",
+			Run:         a.runHelp,
+		},
+	}
+}
+
+// globalFlag is a flag accepted by every command, e.g. `yt-rss list --lang de`.
+type globalFlag struct {
+	Name  string
+	Usage string
+	Set   func(value string) error
+	// Bool flags don't take a value.
+	Bool bool
+}
+
+func (a *App) getGlobalFlags() []globalFlag {
+	return []globalFlag{
+		{
+			Name:  "lang",
+			Usage: "Language of messages, e.g. de. Defaults to the locale.",
+			Set:   setString(&a.UILanguage),
+		},
+		{
+			Name:  "read-only",
+			Usage: "Browse and play without changing the cache, history, pins, subscriptions, or downloads",
+			Set:   setBool(&a.ReadOnly),
+			Bool:  true,
+		},
+		{
+			Name:  "v",
+			Usage: "Verbose: also print the timings of each feed and video page fetched",
+			Set:   func(string) error { a.LogLevel = logLevelNames[logLevelDebug]; return nil },
+			Bool:  true,
+		},
+		{
+			Name:  "q",
+			Usage: "Quiet: only print errors",
+			Set:   func(string) error { a.LogLevel = logLevelNames[logLevelError]; return nil },
+			Bool:  true,
+		},
+		{
+			Name:  "log-file",
+			Usage: "Also write all messages, including debug messages, to this file",
+			Set:   setString(&a.LogFile),
+		},
+	}
+}
+
+// parseGlobalFlags applies the global flags in args, and returns the
+// remaining args for the command. Flags after "--" are left alone.
+func (a *App) parseGlobalFlags(args []string) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		gf, ok := a.findGlobalFlag(name)
+		if !ok || !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if gf.Bool {
+				value = "true"
+			} else {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("flag needs an argument: -%s", name)
+				}
+				i++
+				value = args[i]
+			}
+		}
+		err := gf.Set(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for flag -%s", name)
+		}
+	}
+	return rest, nil
+}
+
+func (a *App) findGlobalFlag(name string) (globalFlag, bool) {
+	for _, v := range a.getGlobalFlags() {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return globalFlag{}, false
+}
+
+func (a *App) findCommand(name string) (command, bool) {
+	for _, v := range a.getCommands() {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return command{}, false
+}
+
+func (a *App) runHelp(args []string) error {
+	a.printUsage()
+	return nil
+}
+
+func (a *App) printUsage() {
+	a.printer.Fprintf(os.Stderr, "Usage: yt-rss [command] [flags]\n\nCommands:\n")
+	for _, v := range a.getCommands() {
+		if v.Hidden {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", v.Name, v.Description)
+	}
+	a.printer.Fprintf(os.Stderr, "\nGlobal flags:\n")
+	for _, v := range a.getGlobalFlags() {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", "--"+v.Name, v.Usage)
+	}
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Config is the configuration of yt-rss. The defaults are compiled in, see
+// defaultConfig, and can be overridden in the settings file.
+type Config struct {
+	CacheDuration           time.Duration // How long channel feeds are cached before they are refreshed
+	PlaylistCacheDuration   time.Duration // How long playlist feeds are cached
+	RSSCacheDuration        time.Duration // How long other RSS feeds are cached
+	CacheRetention          time.Duration // Prune cached entries published longer ago than this. Zero keeps them forever.
+	CacheMaxEntriesPerFeed  int           // Keep at most this many cached entries per feed. Zero is unlimited.
+	PageCacheDuration       time.Duration // How long video watch pages are cached on disk. Zero disables the page cache.
+	HideShorts              bool          // Hides YouTube Shorts from the picker
+	ShortsThreshold         time.Duration // Duration to consider a video a YouTube Short, if it couldn't be checked
+	EnableAuthorNamePadding bool          // Enables padding of author names to align the FZF output
+	AuthorNameMaxWidth      int           // Author names wider than this many terminal cells are truncated with an ellipsis. 0 disables it.
+	HideWatched             bool          // Hides watched videos from the picker
+	HideLive                bool          // Hides livestreams that are currently live
+	HideUpcoming            bool          // Hides premieres and scheduled livestreams that haven't started
+	HideDuplicates          bool          // Hides reuploads and cross-posts whose title and duration match an earlier video
+	MinDuration             time.Duration // Hides videos shorter than this. Zero shows all videos.
+	MaxDuration             time.Duration // Hides videos longer than this, e.g. multi-hour streams. Zero shows all videos.
+	MinViews                int           // Hides videos with fewer views than this. Zero shows all videos.
+	MaxAge                  time.Duration // Hides videos published longer ago than this, e.g. in a long-accumulated cache. Zero shows all videos.
+	StaleAfter              time.Duration // Warns after a refresh about subscriptions without uploads for longer than this. Zero disables it.
+	ReadOnly                bool          // Disables all changes to the cache, history, state, subscriptions, and downloads
+	UILanguage              string        // Language of messages, e.g. "de". Empty uses the locale.
+	Timezone                string        // IANA time zone to show dates in, e.g. "Europe/Berlin". Empty uses the local time zone.
+	LogLevel                string        // Messages written to stderr: "error", "warn", "info", or "debug"
+	LogFile                 string        // Also write all messages to this file, with timestamps. Empty disables it.
+	Storage                 string        // Where the cache and watch history are stored: "json" files, or a "sqlite" database
+
+	// Title rules. Entries with titles matching an exclude pattern or
+	// keyword are hidden. If there are include patterns, only entries
+	// matching one of them are shown. Patterns are regular expressions;
+	// keywords are matched case-insensitively.
+	IncludeTitlePatterns []string
+	ExcludeTitlePatterns []string
+	ExcludeKeywords      []string
+
+	// Title normalization, applied to the titles shown. Rewrites are
+	// regular expressions, with an optional replacement after "=>". See
+	// parseTitleRewrite.
+	TitleSentenceCase   bool
+	TitleStripHashtags  bool
+	TitleStripEmoji     bool
+	TitleStripClickbait bool     // Removes the words in TitleClickbaitWords
+	TitleClickbaitWords []string // Matched case-insensitively, as whole words
+	TitleRewrites       []string
+
+	// Picker key bindings
+	PinKey         string // fzf key to pin or unpin the highlighted entry
+	LaterKey       string // fzf key to add the highlighted entry to the watch-later list, or remove it
+	OpenKey        string // fzf key to open the highlighted entry in the browser
+	DownloadKey    string // fzf key to download the highlighted entry with yt-dlp
+	CopyKey        string // fzf key to copy the URL of the highlighted entry
+	AudioKey       string // fzf key to play the highlighted entry without video
+	QueueKey       string // fzf key to queue the highlighted entry in a background mpv
+	EnqueueKey     string // fzf key to add the selected entries to the saved queue
+	ActionsMenuKey string // fzf key to open the actions menu for the highlighted entry
+	FavoritesKey   string // fzf key to switch between all videos and those from favorite channels
+
+	PinnedMarker       string   // Marker shown before the titles of pinned entries
+	LaterMarker        string   // Marker shown before the titles of entries in the watch-later list
+	FavoriteMarker     string   // Marker shown before the titles of entries from favorite channels
+	FavoritesFirst     bool     // Sorts entries from favorite channels above the others, below pinned entries
+	ReplayMarker       string   // Marker shown with the play count before the titles of played entries
+	WatchedMarker      string   // Marker shown before the titles of watched entries, if they aren't hidden
+	ActionsMenu        []string // Actions to show in the actions menu, in order. Empty shows all actions.
+	EnablePreview      bool     // Enables the fzf preview pane
+	PreviewWindow      string   // fzf --preview-window layout
+	ShowCategories     bool     // Shows the category of each entry's feed before its title, unless browsing a single category
+	DateStyle          string   // How publish dates are shown: "absolute", "relative" (e.g. "3d ago"), or "both"
+	DateFormat         string   // Go time layout of absolute dates, e.g. "02 Jan" or "2006-01-02"
+	DateLocale         string   // Language of month and weekday names in dates, e.g. "de". Empty uses the language of messages.
+	SortOrder          string   // Order of the picker and list, e.g. "published" (newest first) or "duration:asc". See parseSortOrder.
+	ShowViews          bool     // Shows the view count of each entry after its duration
+	Columns            []string // Columns of the picker, in order. See pickerColumns.
+	Theme              string   // Built-in color theme, e.g. "default", or "none" to disable colors
+	Colors             []string // Overrides of the theme's styles, e.g. "channel=bold cyan"
+	ShowPlaylistNames  bool     // Shows the playlist name before the titles of entries from playlist feeds
+	PickerBackend      string   // "fzf", "tui" for the built-in terminal UI, "plain" for a numbered list, a launcher ("rofi", "dmenu", "wofi", or "fuzzel"), or "auto" to use tui or plain when fzf can't be used
+	LoopPicker         bool     // Returns to the picker after playback, keeping the query and position
+	PreviewImageViewer string   // Renders thumbnails in the preview pane. One of the imageViewer constants, or empty to disable.
+	ThumbnailCacheSize string   // Size of the thumbnail cache, e.g. "50M". Empty disables the cache.
+
+	// Playback
+	PlayerCommand string // Player command template. {url} and {title} are substituted.
+	AudioOnly     bool   // Always play audio only. Can also be enabled per channel with the "audio" option in the URLs file.
+	AudioOnlyArgs string // Arguments added after the player binary when playing audio only
+
+	PreferLocalFiles bool // Plays downloaded files instead of streaming, if they exist
+	ResumePlayback   bool // Resumes videos from where they were stopped, and marks videos played to the end as watched. Requires mpv.
+
+	// Sources to play videos from, tried in order until one succeeds. See
+	// the playbackSource constants.
+	PlaybackSources []string
+
+	// The video being played is written to this file while the player
+	// runs, for status bars and stream overlays. See runNow. Empty for
+	// yt-rss-now-playing.json in getRuntimeDir.
+	NowPlayingFile string
+
+	// Recovery options offered when playback fails
+	RetryFormatArgs   string // Arguments passed to the player to retry with a different format
+	InvidiousInstance string // Invidious instance to retry playback through, and to use with the invidious backend; none by default
+
+	// Where feeds and metadata are fetched from, and videos are played
+	// through. See the backend constants.
+	Backend       string
+	PipedInstance string // URL of the Piped API, for the piped backend
+	PipedFrontend string // URL of the Piped web frontend, for watch URLs with the piped backend
+
+	BrowserFrontend string // Where videos are opened in the browser: "auto" (the backend's web frontend), "youtube", "invidious", or "piped"
+
+	// SponsorBlock. Segments in SponsorBlockCategories are subtracted from
+	// durations in the picker, and optionally passed to an mpv script in
+	// the script option SponsorBlockScriptOpt.
+	SponsorBlock           bool
+	SponsorBlockAPI        string
+	SponsorBlockCategories []string
+	SponsorBlockScriptOpt  string
+
+	// Probe videos before playing them, to report deleted, private, or
+	// region-blocked videos clearly instead of as an opaque player error.
+	CheckAvailabilityBeforePlaying bool
+
+	// Downloads. The output template is relative to DownloadDir, which
+	// defaults to ~/Videos/yt-rss.
+	DownloadDir            string
+	DownloadFormat         string
+	DownloadOutputTemplate string
+	DownloadArgs           string        // Extra arguments passed to yt-dlp
+	AutoDownloadMaxAge     time.Duration // Only videos published within this window are downloaded automatically
+	DownloadRetention      time.Duration // Delete downloads this long after they were watched. Zero keeps them forever.
+	DownloadMaxSize        string        // Delete the earliest watched downloads when downloads exceed this size, e.g. "50G"
+
+	// Network
+	FetchConcurrency    int           // Number of feeds fetched at the same time
+	MetadataConcurrency int           // Number of videos whose metadata is fetched at the same time
+	MetadataRateLimit   int           // Maximum video page requests per second, across all workers. Zero is unlimited.
+	MetadataBudget      int           // Maximum videos whose metadata is fetched before showing the picker. The rest is fetched in the background. Zero is unlimited.
+	RetryAttempts       int           // Number of attempts for each HTTP request before giving up
+	RetryBackoff        time.Duration // Initial delay between retries, doubled after each attempt
+	RetryMaxBackoff     time.Duration // Upper bound for the delay between retries
+	HTTPTimeout         time.Duration // Timeout for each HTTP request, including reading the response
+	MaxResponseSize     int64         // Responses larger than this many bytes are rejected
+	AcceptLanguage      string        // Accept-Language header of requests, e.g. "de-DE,de;q=0.9". Empty sends none.
+	CookiesFile         string        // Netscape cookies.txt file, e.g. exported from a browser, whose cookies are sent with requests
+	YouTubeConsent      bool          // Sends the cookie that declines YouTube's consent prompt, which replaces video pages in the EU
+}
+
+// defaultConfig returns the compiled-in configuration.
+func defaultConfig() *Config {
+	return &Config{
+		CacheDuration:           30 * time.Minute,
+		PlaylistCacheDuration:   2 * time.Hour,
+		RSSCacheDuration:        1 * time.Hour,
+		CacheRetention:          90 * 24 * time.Hour,
+		PageCacheDuration:       15 * time.Minute,
+		HideShorts:              true,
+		ShortsThreshold:         120 * time.Second,
+		LogLevel:                "info",
+		EnableAuthorNamePadding: true,
+		HideWatched:             true,
+		Storage:                 storageJSON,
+
+		TitleSentenceCase:   true,
+		TitleStripHashtags:  true,
+		TitleClickbaitWords: []string{"shocking", "insane", "unbelievable", "you won't believe", "gone wrong", "must watch", "not clickbait"},
+
+		PinKey:         "ctrl-p",
+		LaterKey:       "ctrl-l",
+		OpenKey:        "ctrl-o",
+		DownloadKey:    "ctrl-d",
+		CopyKey:        "ctrl-y",
+		AudioKey:       "ctrl-a",
+		QueueKey:       "ctrl-q",
+		EnqueueKey:     "ctrl-e",
+		ActionsMenuKey: "ctrl-x",
+		FavoritesKey:   "ctrl-f",
+
+		PinnedMarker:       "[pinned]",
+		LaterMarker:        "[later]",
+		FavoriteMarker:     "★",
+		FavoritesFirst:     true,
+		ReplayMarker:       "↻",
+		WatchedMarker:      "✓",
+		EnablePreview:      true,
+		PreviewWindow:      "right,50%,wrap",
+		ShowCategories:     true,
+		ShowPlaylistNames:  true,
+		Columns:            []string{columnDate, columnDuration, columnChannel, columnTitle},
+		Theme:              themeDefault,
+		PickerBackend:      "auto",
+		DateStyle:          dateStyleAbsolute,
+		DateFormat:         "02 Jan",
+		SortOrder:          sortByPublished,
+		ThumbnailCacheSize: "50M",
+
+		PlayerCommand: "mpv {url}",
+		AudioOnlyArgs: "--no-video",
+
+		PreferLocalFiles: true,
+		ResumePlayback:   true,
+
+		PlaybackSources: []string{"local", "youtube"},
+
+		RetryFormatArgs: "--ytdl-format=best",
+
+		Backend:       backendYouTube,
+		PipedInstance: "https://pipedapi.kavin.rocks",
+		PipedFrontend: "https://piped.video",
+
+		BrowserFrontend: browserFrontendAuto,
+
+		SponsorBlockAPI:        "https://sponsor.ajay.app",
+		SponsorBlockCategories: []string{"sponsor", "selfpromo", "interaction"},
+
+		CheckAvailabilityBeforePlaying: true,
+
+		DownloadFormat:         "bestvideo[height<=1080]+bestaudio/best",
+		DownloadOutputTemplate: "%(uploader)s/%(upload_date)s %(title)s [%(id)s].%(ext)s",
+		AutoDownloadMaxAge:     3 * 24 * time.Hour,
+
+		FetchConcurrency:    10,
+		MetadataConcurrency: 10,
+		MetadataRateLimit:   5,
+		MetadataBudget:      50,
+		RetryAttempts:       3,
+		RetryBackoff:        1 * time.Second,
+		RetryMaxBackoff:     10 * time.Second,
+		HTTPTimeout:         30 * time.Second,
+		MaxResponseSize:     10 << 20,
+		AcceptLanguage:      "en-US,en;q=0.9",
+		YouTubeConsent:      true,
+	}
+}
+
+// options maps each key in the settings file to a function that applies its
+// value to the corresponding configuration field.
+func (c *Config) options() map[string]func(value string) error {
+	return map[string]func(value string) error{
+		"read_only":                  setBool(&c.ReadOnly),
+		"language":                   setString(&c.UILanguage),
+		"timezone":                   setString(&c.Timezone),
+		"log_level":                  setString(&c.LogLevel),
+		"log_file":                   setString(&c.LogFile),
+		"storage":                    setString(&c.Storage),
+		"player":                     setString(&c.PlayerCommand),
+		"cache_duration":             setDuration(&c.CacheDuration),
+		"playlist_cache_duration":    setDuration(&c.PlaylistCacheDuration),
+		"rss_cache_duration":         setDuration(&c.RSSCacheDuration),
+		"page_cache_duration":        setDuration(&c.PageCacheDuration),
+		"concurrency":                setInt(&c.FetchConcurrency),
+		"metadata_concurrency":       setInt(&c.MetadataConcurrency),
+		"metadata_rate_limit":        setInt(&c.MetadataRateLimit),
+		"metadata_budget":            setInt(&c.MetadataBudget),
+		"retry_attempts":             setInt(&c.RetryAttempts),
+		"retry_backoff":              setDuration(&c.RetryBackoff),
+		"retry_max_backoff":          setDuration(&c.RetryMaxBackoff),
+		"http_timeout":               setDuration(&c.HTTPTimeout),
+		"accept_language":            setString(&c.AcceptLanguage),
+		"cookies_file":               setString(&c.CookiesFile),
+		"youtube_consent":            setBool(&c.YouTubeConsent),
+		"cache_retention":            setDuration(&c.CacheRetention),
+		"cache_max_entries_per_feed": setInt(&c.CacheMaxEntriesPerFeed),
+		"actions_menu":               setStringList(&c.ActionsMenu),
+		"actions_menu_key":           setString(&c.ActionsMenuKey),
+		"pin_key":                    setString(&c.PinKey),
+		"later_key":                  setString(&c.LaterKey),
+		"open_key":                   setString(&c.OpenKey),
+		"download_key":               setString(&c.DownloadKey),
+		"copy_key":                   setString(&c.CopyKey),
+		"queue_key":                  setString(&c.QueueKey),
+		"enqueue_key":                setString(&c.EnqueueKey),
+		"audio_key":                  setString(&c.AudioKey),
+		"resume_playback":            setBool(&c.ResumePlayback),
+		"prefer_local_files":         setBool(&c.PreferLocalFiles),
+		"playback_sources":           setStringList(&c.PlaybackSources),
+		"audio_only":                 setBool(&c.AudioOnly),
+		"now_playing_file":           setString(&c.NowPlayingFile),
+		"retry_format_args":          setString(&c.RetryFormatArgs),
+		"invidious_instance":         setString(&c.InvidiousInstance),
+		"backend":                    setString(&c.Backend),
+		"piped_instance":             setString(&c.PipedInstance),
+		"piped_frontend":             setString(&c.PipedFrontend),
+		"browser_frontend":           setString(&c.BrowserFrontend),
+		"sponsorblock":               setBool(&c.SponsorBlock),
+		"sponsorblock_api":           setString(&c.SponsorBlockAPI),
+		"sponsorblock_categories":    setStringList(&c.SponsorBlockCategories),
+		"sponsorblock_script_opt":    setString(&c.SponsorBlockScriptOpt),
+		"check_availability":         setBool(&c.CheckAvailabilityBeforePlaying),
+		"audio_only_args":            setString(&c.AudioOnlyArgs),
+		"download_dir":               setString(&c.DownloadDir),
+		"download_format":            setString(&c.DownloadFormat),
+		"download_output_template":   setString(&c.DownloadOutputTemplate),
+		"download_args":              setString(&c.DownloadArgs),
+		"auto_download_max_age":      setDuration(&c.AutoDownloadMaxAge),
+		"download_retention":         setDuration(&c.DownloadRetention),
+		"download_max_size":          setString(&c.DownloadMaxSize),
+		"hide_shorts":                setBool(&c.HideShorts),
+		"shorts_threshold":           setDuration(&c.ShortsThreshold),
+		"hide_live":                  setBool(&c.HideLive),
+		"hide_upcoming":              setBool(&c.HideUpcoming),
+		"min_views":                  setInt(&c.MinViews),
+		"min_duration":               setDuration(&c.MinDuration),
+		"max_duration":               setDuration(&c.MaxDuration),
+		"max_age":                    setDuration(&c.MaxAge),
+		"stale_after":                setDuration(&c.StaleAfter),
+		"hide_watched":               setBool(&c.HideWatched),
+		"hide_duplicates":            setBool(&c.HideDuplicates),
+		"include_title":              appendString(&c.IncludeTitlePatterns),
+		"exclude_title":              appendString(&c.ExcludeTitlePatterns),
+		"exclude_keywords":           setStringList(&c.ExcludeKeywords),
+		"title_sentence_case":        setBool(&c.TitleSentenceCase),
+		"title_strip_hashtags":       setBool(&c.TitleStripHashtags),
+		"title_strip_emoji":          setBool(&c.TitleStripEmoji),
+		"title_strip_clickbait":      setBool(&c.TitleStripClickbait),
+		"title_clickbait_words":      setStringList(&c.TitleClickbaitWords),
+		"title_rewrite":              appendString(&c.TitleRewrites),
+		"replay_marker":              setString(&c.ReplayMarker),
+		"watched_marker":             setString(&c.WatchedMarker),
+		"later_marker":               setString(&c.LaterMarker),
+		"favorite_marker":            setString(&c.FavoriteMarker),
+		"favorites_first":            setBool(&c.FavoritesFirst),
+		"favorites_key":              setString(&c.FavoritesKey),
+		"show_categories":            setBool(&c.ShowCategories),
+		"show_playlist_names":        setBool(&c.ShowPlaylistNames),
+		"show_views":                 setBool(&c.ShowViews),
+		"columns":                    setStringList(&c.Columns),
+		"theme":                      setString(&c.Theme),
+		"colors":                     setStringList(&c.Colors),
+		"author_max_width":           setInt(&c.AuthorNameMaxWidth),
+		"sort":                       setString(&c.SortOrder),
+		"date_style":                 setString(&c.DateStyle),
+		"date_format":                setString(&c.DateFormat),
+		"date_locale":                setString(&c.DateLocale),
+		"picker":                     setString(&c.PickerBackend),
+		"loop":                       setBool(&c.LoopPicker),
+		"preview":                    setBool(&c.EnablePreview),
+		"preview_window":             setString(&c.PreviewWindow),
+		"preview_image_viewer":       setString(&c.PreviewImageViewer),
+		"thumbnail_cache_size":       setString(&c.ThumbnailCacheSize),
+	}
+}
+
+func setString(v *string) func(string) error {
+	return func(value string) error {
+		*v = value
+		return nil
+	}
+}
+
+func setBool(v *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*v = b
+		return nil
+	}
+}
+
+func setInt(v *int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*v = n
+		return nil
+	}
+}
+
+func setDuration(v *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		*v = d
+		return nil
+	}
+}
+
+// parseDuration parses a duration like time.ParseDuration, but also accepts
+// days and weeks, e.g. "7d" or "2w".
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration: %s", s)
+			}
+			return time.Duration(f * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// setStringList parses a comma-separated list of values.
+func setStringList(v *[]string) func(string) error {
+	return func(value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+		*v = list
+		return nil
+	}
+}
+
+// appendString appends the value to the list, so that the option can be
+// repeated to build up a list of values that may contain commas.
+func appendString(v *[]string) func(string) error {
+	return func(value string) error {
+		*v = append(*v, value)
+		return nil
+	}
+}
+
+func getSettingsFile() string {
+	fileName := filepath.Join(getConfigDir(), "yt-rss", "config")
+	return fileName
+}
+
+// loadSettings overrides the configuration with values from the
+// settings file, if it exists. Each line in the file is a "key = value" pair.
+// Values may be wrapped in double quotes. Lines starting with # are ignored.
+func (c *Config) loadSettings() error {
+	settingsFile := getSettingsFile()
+	f, err := os.Open(settingsFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lineErrors, err := c.applySettings(f)
+	if err != nil {
+		return err
+	}
+	if len(lineErrors) > 0 {
+		return fmt.Errorf("%s:%d: %s", settingsFile, lineErrors[0].Line, lineErrors[0].Err)
+	}
+	return nil
+}
+
+// settingsLineError is an invalid line in the settings file.
+type settingsLineError struct {
+	Line int
+	Err  error
+}
+
+// applySettings applies each line of the settings, and returns the errors of
+// the lines that are invalid, which are skipped.
+func (c *Config) applySettings(r io.Reader) ([]settingsLineError, error) {
+	var lineErrors []settingsLineError
+	lineNumber := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNumber++
+		key, value, ok, err := parseSettingsLine(scanner.Text())
+		if err == nil && ok {
+			set, known := c.options()[key]
+			if !known {
+				err = fmt.Errorf("unknown option %s", key)
+			} else if setErr := set(value); setErr != nil {
+				err = errors.Wrap(setErr, key)
+			}
+		}
+		if err != nil {
+			lineErrors = append(lineErrors, settingsLineError{Line: lineNumber, Err: err})
+		}
+	}
+	return lineErrors, scanner.Err()
+}
+
+// parseSettingsLine parses a "key = value" line of the settings file. ok is
+// false if the line is blank or a comment.
+func parseSettingsLine(line string) (key string, value string, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+	key, value, ok = strings.Cut(line, "=")
+	if !ok {
+		return "", "", false, errors.New("expected key = value")
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) {
+		value, err = strconv.Unquote(value)
+		if err != nil {
+			return "", "", false, fmt.Errorf("invalid quoted value for %s", key)
+		}
+	}
+	return key, value, true, nil
+}
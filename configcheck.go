@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// configProblem is a problem found by `yt-rss config validate` in the
+// settings file or the URLs file.
+type configProblem struct {
+	File    string
+	Line    int // 0 if the problem isn't on a particular line
+	Message string
+	Warning bool // Warnings don't stop yt-rss from running, e.g. duplicate feeds
+}
+
+func (p configProblem) String() string {
+	kind := "error"
+	if p.Warning {
+		kind = "warning"
+	}
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", p.File, kind, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, kind, p.Message)
+}
+
+// repeatableSettings are the settings that can be set more than once, to
+// build up a list.
+var repeatableSettings = map[string]bool{
+	"include_title": true,
+	"exclude_title": true,
+	"title_rewrite": true,
+}
+
+// knownSubscriptionOptions are the options that subscriptions in the URLs
+// file can have. See Subscription.
+var knownSubscriptionOptions = []string{"audio", "include", "exclude", "min-duration", "max-duration", "auto-download", "rewrite", "favorite"}
+
+// settingValueError is a setting whose value can be parsed, but isn't one
+// that yt-rss accepts.
+type settingValueError struct {
+	Key string
+	Err error
+}
+
+// checkValues returns the settings whose values yt-rss would reject when
+// they are used, e.g. an unknown backend, so that they are caught before
+// then.
+func (c *Config) checkValues() []settingValueError {
+	var invalid []settingValueError
+	check := func(key string, err error) {
+		if err != nil {
+			invalid = append(invalid, settingValueError{Key: key, Err: err})
+		}
+	}
+	oneOf := func(key, value string, available ...string) {
+		if !slices.Contains(available, value) {
+			check(key, fmt.Errorf("unknown value: %s (available: %s)", value, strings.Join(available, ", ")))
+		}
+	}
+
+	oneOf("log_level", c.LogLevel, logLevelNames...)
+	oneOf("backend", c.Backend, backends...)
+	oneOf("storage", c.Storage, storageJSON, storageSQLite)
+	oneOf("picker", c.PickerBackend, pickerBackends...)
+	oneOf("date_style", c.DateStyle, dateStyleAbsolute, dateStyleRelative, dateStyleBoth)
+	oneOf("browser_frontend", c.BrowserFrontend, browserFrontendAuto, browserFrontendYouTube, browserFrontendInvidious, browserFrontendPiped)
+	for _, v := range c.PlaybackSources {
+		oneOf("playback_sources", v, playbackSourceLocal, playbackSourceYouTube, playbackSourceInvidious, playbackSourceBrowser)
+	}
+	if c.InvidiousInstance == "" {
+		if c.Backend == backendInvidious {
+			check("backend", errors.New("the invidious backend needs invidious_instance"))
+		}
+		if c.BrowserFrontend == browserFrontendInvidious {
+			check("browser_frontend", errors.New("the invidious frontend needs invidious_instance"))
+		}
+		if slices.Contains(c.PlaybackSources, playbackSourceInvidious) {
+			check("playback_sources", errors.New("the invidious source needs invidious_instance"))
+		}
+	}
+	if c.RetryAttempts < 1 {
+		check("retry_attempts", fmt.Errorf("must be at least 1: %d", c.RetryAttempts))
+	}
+	for _, v := range c.Columns {
+		oneOf("columns", v, pickerColumnNames...)
+	}
+	_, _, err := parseSortOrder(c.SortOrder)
+	check("sort", err)
+	_, err = newColorTheme(c.Theme, c.Colors)
+	check("theme", err)
+	if c.Timezone != "" {
+		_, err := time.LoadLocation(c.Timezone)
+		check("timezone", err)
+	}
+	if c.DownloadMaxSize != "" {
+		_, err := parseSize(c.DownloadMaxSize)
+		check("download_max_size", err)
+	}
+	if c.ThumbnailCacheSize != "" {
+		_, err := parseSize(c.ThumbnailCacheSize)
+		check("thumbnail_cache_size", err)
+	}
+	if c.CookiesFile != "" {
+		check("cookies_file", checkCookiesFile(c.CookiesFile))
+	}
+	_, err = compilePatterns(c.IncludeTitlePatterns)
+	check("include_title", err)
+	_, err = compilePatterns(c.ExcludeTitlePatterns)
+	check("exclude_title", err)
+	for _, v := range c.TitleRewrites {
+		_, err = parseTitleRewrite(v)
+		check("title_rewrite", err)
+	}
+	return invalid
+}
+
+// validateSettings returns the problems in the settings file: lines that
+// can't be parsed, unknown options, values that yt-rss doesn't accept, and
+// options that are set more than once.
+func validateSettings(settingsFile string) ([]configProblem, error) {
+	b, err := os.ReadFile(settingsFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var problems []configProblem
+	c := defaultConfig()
+	lineErrors, err := c.applySettings(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range lineErrors {
+		problems = append(problems, configProblem{File: settingsFile, Line: v.Line, Message: v.Err.Error()})
+	}
+
+	lines := make(map[string]int) // Key to the line it was last set on
+	for i, line := range strings.Split(string(b), "\n") {
+		key, _, ok, err := parseSettingsLine(line)
+		if err != nil || !ok {
+			continue
+		}
+		if previous, ok := lines[key]; ok && !repeatableSettings[key] {
+			problems = append(problems, configProblem{
+				File:    settingsFile,
+				Line:    i + 1,
+				Message: fmt.Sprintf("%s is already set on line %d, which this overrides", key, previous),
+				Warning: true,
+			})
+		}
+		lines[key] = i + 1
+	}
+	for _, v := range c.checkValues() {
+		problems = append(problems, configProblem{File: settingsFile, Line: lines[v.Key], Message: errors.Wrap(v.Err, v.Key).Error()})
+	}
+	slices.SortStableFunc(problems, func(a, b configProblem) int {
+		return a.Line - b.Line
+	})
+	return problems, nil
+}
+
+// parseFeedURL parses a feed URL in the URLs file, which must be an absolute
+// HTTP or HTTPS URL.
+func parseFeedURL(feedURL string) (*url.URL, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("not a URL: %s", feedURL)
+	}
+	return u, nil
+}
+
+// checkFeedURL returns an error if the URL isn't a YouTube channel or
+// playlist feed. Other feeds are fetched as plain feeds, so they are only
+// warned about.
+func checkFeedURL(u *url.URL) (warning bool, err error) {
+	if strings.TrimPrefix(u.Hostname(), "www.") != "youtube.com" {
+		return true, fmt.Errorf("not a YouTube feed, it's fetched as a plain feed: %s", u)
+	}
+	if u.Path != "/feeds/videos.xml" {
+		return false, fmt.Errorf("not a feed URL: %s (add channels and playlists with `yt-rss subscribe <url>`)", u)
+	}
+	channelID, playlistID := u.Query().Get("channel_id"), u.Query().Get("playlist_id")
+	switch {
+	case channelID != "" && !channelIDRegex.MatchString(channelID):
+		return false, fmt.Errorf("invalid channel ID: %s", channelID)
+	case channelID == "" && playlistID == "":
+		return false, fmt.Errorf("feed URL has no channel_id or playlist_id: %s", u)
+	}
+	return false, nil
+}
+
+// feedURLKey returns the key that identifies the feed, so that the same
+// channel or playlist is found even if its URLs differ, e.g. by scheme.
+func feedURLKey(feedURL string) string {
+	if kind, id, err := backendFeedPath(feedURL); err == nil {
+		return kind + ":" + id
+	}
+	return feedURL
+}
+
+// validateURLsFile returns the problems in the URLs file: malformed feed
+// URLs, duplicate feeds, and unknown or invalid subscription options.
+func validateURLsFile(urlsFile string) ([]configProblem, error) {
+	b, err := os.ReadFile(urlsFile)
+	if os.IsNotExist(err) {
+		return []configProblem{{File: urlsFile, Message: "the file doesn't exist, subscribe to channels with `yt-rss subscribe <url>`", Warning: true}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var problems []configProblem
+	problem := func(line int, warning bool, err error) {
+		problems = append(problems, configProblem{File: urlsFile, Line: line, Message: err.Error(), Warning: warning})
+	}
+	seen := make(map[string]int) // Feed URL key to the line it's first on
+	for i, line := range strings.Split(string(b), "\n") {
+		lineNumber := i + 1
+		if category, ok := parseCategoryHeader(line); ok && category == "" {
+			problem(lineNumber, true, errors.New("empty category name"))
+		}
+		subscription, ok := parseSubscription(line)
+		if !ok {
+			continue
+		}
+		u, err := parseFeedURL(subscription.URL)
+		if err != nil {
+			// The rest of the line is unlikely to be options either
+			problem(lineNumber, false, err)
+			continue
+		}
+		if warning, err := checkFeedURL(u); err != nil {
+			problem(lineNumber, warning, err)
+		}
+		key := feedURLKey(subscription.URL)
+		if previous, ok := seen[key]; ok {
+			problem(lineNumber, true, fmt.Errorf("duplicate of the feed on line %d", previous))
+		} else {
+			seen[key] = lineNumber
+		}
+
+		for _, name := range sortedKeys(subscription.Options) {
+			value := subscription.Options[name]
+			switch name {
+			case "include", "exclude", "auto-download":
+				if _, err := regexp.Compile(value); err != nil {
+					problem(lineNumber, false, errors.Wrap(err, name))
+				}
+			case "rewrite":
+				if _, err := parseTitleRewrite(value); err != nil {
+					problem(lineNumber, false, errors.Wrap(err, name))
+				}
+			case "min-duration", "max-duration":
+				if _, err := parseDuration(value); err != nil {
+					problem(lineNumber, false, errors.Wrap(err, name))
+				}
+			default:
+				if !slices.Contains(knownSubscriptionOptions, name) {
+					problem(lineNumber, true, fmt.Errorf("unknown option %s (available: %s)", name, strings.Join(knownSubscriptionOptions, ", ")))
+				}
+			}
+		}
+	}
+	return problems, nil
+}
+
+// sortedKeys returns the keys of the map in order, so that problems are
+// reported in the same order every time.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// printConfigProblems prints the problems, and returns the number of errors
+// among them.
+func printConfigProblems(problems []configProblem) int {
+	errorCount := 0
+	for _, v := range problems {
+		fmt.Println(v)
+		if !v.Warning {
+			errorCount++
+		}
+	}
+	return errorCount
+}
+
+// openEditor opens the file in $VISUAL or $EDITOR, or vi if neither is set,
+// and waits for the editor to exit.
+func openEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// The editor may have arguments, e.g. "code --wait"
+	args, err := splitCommandLine(editor)
+	if err != nil || len(args) == 0 {
+		return fmt.Errorf("invalid editor: %s", editor)
+	}
+	cmd := exec.Command(args[0], append(args[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return errors.Wrap(cmd.Run(), "run editor")
+}
+
+// runConfig checks the settings file and the URLs file with `config
+// validate`, or opens one of them in the editor with `config edit` (the
+// settings file) or `config edit urls`. An edited file is validated when the
+// editor exits, and can be edited again if it has errors.
+func (a *App) runConfig(args []string) error {
+	usage := errors.New("usage: yt-rss config <validate|edit [urls]>")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "validate":
+		if len(args) != 1 {
+			return usage
+		}
+		problems, err := validateSettings(getSettingsFile())
+		if err != nil {
+			return err
+		}
+		urlsProblems, err := validateURLsFile(a.configFile)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, urlsProblems...)
+		errorCount := printConfigProblems(problems)
+		if errorCount > 0 {
+			return errors.New(a.printer.Sprintf("%d errors, %d warnings", errorCount, len(problems)-errorCount))
+		}
+		if len(problems) > 0 {
+			a.printer.Printf("No errors, %d warnings\n", len(problems))
+			return nil
+		}
+		a.printer.Printf("No problems found\n")
+		return nil
+	case "edit":
+		file := getSettingsFile()
+		validate := validateSettings
+		if len(args) == 2 && args[1] == "urls" {
+			file = a.configFile
+			validate = validateURLsFile
+		} else if len(args) != 1 {
+			return usage
+		}
+		if err := a.checkWritable(); err != nil {
+			return err
+		}
+		err := os.MkdirAll(filepath.Dir(file), 0755)
+		if err != nil {
+			return err
+		}
+		stdin := bufio.NewReader(os.Stdin)
+		for {
+			err = openEditor(file)
+			if err != nil {
+				return err
+			}
+			problems, err := validate(file)
+			if err != nil {
+				return err
+			}
+			if printConfigProblems(problems) == 0 {
+				return nil
+			}
+			if !a.confirm(stdin, a.printer.Sprintf("Edit again?")) {
+				return errors.New(a.printer.Sprintf("%s has errors", file))
+			}
+		}
+	default:
+		return usage
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckValuesPicker(t *testing.T) {
+	valid := []string{pickerAuto, pickerFZF, pickerTUI, pickerPlain, pickerRofi, pickerDmenu, pickerWofi, pickerFuzzel}
+	for _, picker := range valid {
+		c := defaultConfig()
+		lineErrors, err := c.applySettings(strings.NewReader("picker = " + picker + "\n"))
+		if err != nil || len(lineErrors) > 0 {
+			t.Fatalf("picker = %s: %v, %v", picker, lineErrors, err)
+		}
+		for _, v := range c.checkValues() {
+			if v.Key == "picker" {
+				t.Errorf("picker = %s: %s", picker, v.Err)
+			}
+		}
+	}
+
+	c := defaultConfig()
+	c.PickerBackend = "skim"
+	var rejected bool
+	for _, v := range c.checkValues() {
+		if v.Key == "picker" {
+			rejected = true
+		}
+	}
+	if !rejected {
+		t.Error("picker = skim: expected an error")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// consentCookies answer YouTube's cookie consent prompt by declining all
+// optional cookies, as its "Reject all" button does. Without them, visitors
+// from the EU get a consent page instead of the video page, which has no
+// duration or live status to read.
+var consentCookies = []*http.Cookie{
+	{Name: "SOCS", Value: "CAI", Path: "/", Domain: ".youtube.com"},
+}
+
+var youtubeURL = &url.URL{Scheme: "https", Host: "www.youtube.com", Path: "/"}
+
+// setupCookies gives the HTTP clients a cookie jar, with the consent cookies
+// if YouTubeConsent is set, and the cookies in CookiesFile. Cookies that are
+// set by responses are kept in the jar until yt-rss exits.
+func (a *App) setupCookies() error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	if a.YouTubeConsent {
+		jar.SetCookies(youtubeURL, consentCookies)
+	}
+	if a.CookiesFile != "" {
+		f, err := os.Open(a.CookiesFile)
+		if err != nil {
+			return errors.Wrap(err, "open cookies file")
+		}
+		defer f.Close()
+		cookies, err := parseCookiesFile(f)
+		if err != nil {
+			return errors.Wrapf(err, "cookies file %s", a.CookiesFile)
+		}
+		for _, v := range cookies {
+			jar.SetCookies(v.URL, []*http.Cookie{v.Cookie})
+		}
+	}
+	a.httpClient.Jar = jar
+	a.noRedirectClient.Jar = jar
+	return nil
+}
+
+// checkCookiesFile returns an error if the cookies file can't be read or
+// parsed.
+func checkCookiesFile(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = parseCookiesFile(f)
+	return err
+}
+
+// fileCookie is a cookie from a cookies file, with the URL it is set for.
+type fileCookie struct {
+	URL    *url.URL
+	Cookie *http.Cookie
+}
+
+// parseCookiesFile parses a cookies file in the Netscape format that browser
+// extensions export and yt-dlp's --cookies reads: one cookie per line, with
+// tab-separated domain, whether subdomains are included, path, whether the
+// cookie is secure, expiry as a Unix timestamp (0 for session cookies), name,
+// and value. Expired cookies are skipped.
+func parseCookiesFile(r io.Reader) ([]fileCookie, error) {
+	var cookies []fileCookie
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// HttpOnly cookies are marked with a prefix that looks like a
+		// comment
+		line, httpOnly := strings.CutPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab-separated fields, got %d", lineNumber, len(fields))
+		}
+		domain, includeSubdomains, path, secure, expiry, name, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+		expires, err := strconv.ParseInt(expiry, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry: %s", lineNumber, expiry)
+		}
+		cookie := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     path,
+			Secure:   strings.EqualFold(secure, "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(includeSubdomains, "TRUE") {
+			cookie.Domain = domain
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+			if cookie.Expires.Before(time.Now()) {
+				continue
+			}
+		}
+		u := &url.URL{Scheme: "http", Host: strings.TrimPrefix(domain, "."), Path: path}
+		if cookie.Secure {
+			u.Scheme = "https"
+		}
+		cookies = append(cookies, fileCookie{URL: u, Cookie: cookie})
+	}
+	return cookies, scanner.Err()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// waybarOutput is the JSON a waybar custom module reads from its exec command.
+type waybarOutput struct {
+	Text    string `json:"text"`
+	Tooltip string `json:"tooltip"`
+	Class   string `json:"class"` // "unwatched" if there are unwatched videos, otherwise "none", for styling
+}
+
+// countUnwatched returns the number of unwatched videos that would be shown
+// in the picker, in total and per category. Feeds without a category are
+// counted under an empty name.
+func (a *App) countUnwatched(entries []FeedEntry) (total int, byCategory map[string]int, err error) {
+	history, err := a.loadHistory()
+	if err != nil {
+		return 0, nil, err
+	}
+	filter, err := a.newEntryFilter()
+	if err != nil {
+		return 0, nil, err
+	}
+	if a.HideDuplicates {
+		entries = dedupTitles(entries)
+	}
+	byCategory = make(map[string]int)
+	for _, v := range a.filterEntries(filterWatched(entries, history), filter) {
+		total++
+		byCategory[filter.FeedCategories[v.ExtraMetadata.FeedURL]]++
+	}
+	return total, byCategory, nil
+}
+
+// runCount prints the number of unwatched videos, for status bars such as
+// polybar, waybar, and tmux, which run it every few seconds. It only reads
+// the cache, since status bars shouldn't hold up on the network, unless
+// --refresh is given.
+func (a *App) runCount(args []string) error {
+	fs := flag.NewFlagSet("count", flag.ExitOnError)
+	refresh := fs.Bool("refresh", false, "Refresh the feeds first, instead of only reading the cache")
+	byCategory := fs.Bool("by-category", false, "Print the count of each category")
+	asJSON := fs.Bool("json", false, "Print JSON for a waybar custom module, with the counts per category in the tooltip")
+	hideZero := fs.Bool("hide-zero", false, "Print nothing if there are no unwatched videos, so that the status bar hides the count")
+	fs.StringVar(&a.selectedCategory, "category", "", "Only count videos from feeds in this category")
+	fs.Parse(args)
+
+	// Status messages would fill the status bar's log, since it runs this
+	// every few seconds.
+	a.progressFormat = progressFormatNone
+	if a.logger.level == logLevelInfo {
+		a.logger.level = logLevelWarn
+	}
+	a.offline = !*refresh
+	a.forceRefresh = *refresh
+	entries, err := a.loadFeedEntries()
+	if err != nil {
+		return err
+	}
+	total, counts, err := a.countUnwatched(entries)
+	if err != nil {
+		return err
+	}
+
+	var categories []string
+	for category := range counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	categoryName := func(category string) string {
+		if category == "" {
+			return a.printer.Sprintf("Uncategorized")
+		}
+		return category
+	}
+
+	switch {
+	case *asJSON:
+		output := waybarOutput{Class: "none"}
+		if total > 0 || !*hideZero {
+			output.Text = fmt.Sprint(total)
+		}
+		if total > 0 {
+			output.Class = "unwatched"
+		}
+		var tooltip []string
+		for _, category := range categories {
+			tooltip = append(tooltip, fmt.Sprintf("%s: %d", categoryName(category), counts[category]))
+		}
+		output.Tooltip = strings.Join(tooltip, "\n")
+		return json.NewEncoder(os.Stdout).Encode(output)
+	case *byCategory:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, category := range categories {
+			fmt.Fprintf(w, "%s\t%d\n", categoryName(category), counts[category])
+		}
+		return w.Flush()
+	case total == 0 && *hideZero:
+		fmt.Println()
+		return nil
+	default:
+		fmt.Println(total)
+		return nil
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Commands accepted by the daemon
+const (
+	daemonCommandEntries = "entries" // Returns the latest feed entries
+	daemonCommandStatus  = "status"  // Returns the daemon's status
+)
+
+type daemonRequest struct {
+	Command string `json:"command"`
+}
+
+type daemonResponse struct {
+	Error   string        `json:"error,omitempty"`
+	Entries []FeedEntry   `json:"entries,omitempty"`
+	Status  *daemonStatus `json:"status,omitempty"`
+}
+
+type daemonStatus struct {
+	PID         int       `json:"pid"`
+	StartedAt   time.Time `json:"started_at"`
+	LastRefresh time.Time `json:"last_refresh"` // Zero until the first refresh has finished
+	NextRefresh time.Time `json:"next_refresh"`
+	Entries     int       `json:"entries"`
+	LastError   string    `json:"last_error,omitempty"` // Error of the last refresh, if it failed
+}
+
+// daemon keeps the feeds refreshed in the background, and serves the latest
+// entries over a unix socket, so that browsing doesn't wait for a refresh.
+type daemon struct {
+	app      *App
+	interval time.Duration
+
+	mu      sync.Mutex
+	entries []FeedEntry
+	status  daemonStatus
+}
+
+func getDaemonSocketPath() string {
+	return filepath.Join(getRuntimeDir(), "yt-rss.sock")
+}
+
+// runDaemon refreshes the feeds every interval until it is interrupted.
+func (a *App) runDaemon(args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	interval := fs.Duration("interval", a.CacheDuration, "How often to check the feeds. Feeds are only refreshed once their cache duration has passed.")
+	fs.Parse(args)
+	if *interval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+
+	socketPath := getDaemonSocketPath()
+	if conn, err := net.DialTimeout("unix", socketPath, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("the daemon is already running at %s", socketPath)
+	}
+	// Remove the socket of a daemon that didn't shut down cleanly
+	os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return errors.Wrap(err, "listen")
+	}
+	defer os.Remove(socketPath)
+
+	a.progressFormat = progressFormatNone
+	// Nothing is waiting on the daemon's refreshes, so it fetches all the
+	// metadata itself instead of deferring it.
+	a.MetadataBudget = 0
+	d := &daemon{
+		app:      a,
+		interval: *interval,
+		status:   daemonStatus{PID: os.Getpid(), StartedAt: time.Now()},
+	}
+	go d.serve(listener)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	log.Printf("Listening on %s, checking the feeds every %s", socketPath, *interval)
+	d.refresh()
+	for {
+		select {
+		case <-ticker.C:
+			d.refresh()
+		case <-signals:
+			log.Printf("Shutting down")
+			return listener.Close()
+		}
+	}
+}
+
+func (d *daemon) refresh() {
+	entries, err := d.app.refreshFeedEntries()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.NextRefresh = time.Now().Add(d.interval)
+	if err != nil {
+		log.Printf("Refresh failed: %s", err)
+		d.status.LastError = err.Error()
+		return
+	}
+	d.entries = entries
+	d.status.LastRefresh = time.Now()
+	d.status.Entries = len(entries)
+	d.status.LastError = ""
+}
+
+func (d *daemon) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			// The listener was closed
+			return
+		}
+		go d.handle(conn)
+	}
+}
+
+// handle answers a single request on the connection.
+func (d *daemon) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	var req daemonRequest
+	err := json.NewDecoder(conn).Decode(&req)
+	if err != nil {
+		return
+	}
+
+	var resp daemonResponse
+	d.mu.Lock()
+	switch req.Command {
+	case daemonCommandEntries:
+		if d.status.LastRefresh.IsZero() {
+			resp.Error = "the first refresh hasn't finished"
+		}
+		resp.Entries = d.entries
+	case daemonCommandStatus:
+		status := d.status
+		resp.Status = &status
+	default:
+		resp.Error = "unknown command: " + req.Command
+	}
+	d.mu.Unlock()
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// queryDaemon sends the command to the daemon and returns its response.
+func queryDaemon(command string) (*daemonResponse, error) {
+	conn, err := net.DialTimeout("unix", getDaemonSocketPath(), time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	err = json.NewEncoder(conn).Encode(daemonRequest{Command: command})
+	if err != nil {
+		return nil, err
+	}
+	var resp daemonResponse
+	err = json.NewDecoder(conn).Decode(&resp)
+	if err != nil {
+		return nil, errors.Wrap(err, "read daemon response")
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// getEntriesFromDaemon returns the latest entries from the daemon. ok is
+// false if the daemon isn't running, or hasn't refreshed the feeds yet.
+func getEntriesFromDaemon() (entries []FeedEntry, ok bool) {
+	resp, err := queryDaemon(daemonCommandEntries)
+	if err != nil {
+		return nil, false
+	}
+	return resp.Entries, true
+}
+
+// runStatus prints the status of the daemon.
+func (a *App) runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the status as JSON")
+	fs.Parse(args)
+
+	resp, err := queryDaemon(daemonCommandStatus)
+	if err != nil {
+		return errors.New(a.printer.Sprintf("the daemon isn't running"))
+	}
+	status := resp.Status
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+	a.printer.Printf("Daemon running (pid %s) since %s\n", fmt.Sprint(status.PID), a.localTime(status.StartedAt).Format("02 Jan 15:04"))
+	if status.LastRefresh.IsZero() {
+		a.printer.Printf("Refreshing the feeds for the first time\n")
+	} else {
+		a.printer.Printf("%d videos, last refreshed at %s, next check at %s\n", status.Entries, a.localTime(status.LastRefresh).Format("15:04"), a.localTime(status.NextRefresh).Format("15:04"))
+	}
+	if status.LastError != "" {
+		a.printer.Printf("Last refresh failed: %s\n", status.LastError)
+	}
+	return nil
+}
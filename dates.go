@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/language"
+)
+
+// setupTimezone sets the time zone to show dates in.
+func (a *App) setupTimezone() error {
+	if a.Timezone == "" {
+		a.location = time.Local
+		return nil
+	}
+	location, err := time.LoadLocation(a.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %s", a.Timezone)
+	}
+	a.location = location
+	return nil
+}
+
+// localTime returns the time in the time zone dates are shown in.
+func (a *App) localTime(t time.Time) time.Time {
+	return t.In(a.location)
+}
+
+// feedTimeLayouts are the layouts of timestamps accepted in feeds, tried in
+// order. YouTube's feeds use RFC 3339, e.g. "2024-01-02T15:04:05+00:00", but
+// other feeds and proxies leave out the colon in the offset or the seconds,
+// use a space instead of the "T", or use RSS's RFC 1123 dates. Fractional
+// seconds are accepted by all of them.
+var feedTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// feedTimeLayoutsUTC are the layouts of timestamps without an offset, which
+// are taken to be in UTC.
+var feedTimeLayoutsUTC = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseFeedTime parses a timestamp from a feed in any of feedTimeLayouts.
+func parseFeedTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, errors.New("missing date")
+	}
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range feedTimeLayoutsUTC {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %q", s)
+}
+
+// normalizeFeedTimes rewrites the publish and update dates of the feed's
+// entries in RFC 3339, so that they are parsed the same way everywhere.
+// Entries with an invalid publish date fall back to their update date, and are
+// reported rather than sorted as if they were published in year 1.
+func (a *App) normalizeFeedTimes(feed *Feed) {
+	for i := range feed.Entries {
+		entry := &feed.Entries[i]
+		updated, updatedErr := parseFeedTime(entry.Updated)
+		if updatedErr == nil {
+			entry.Updated = updated.Format(time.RFC3339)
+		}
+		published, err := parseFeedTime(entry.Published)
+		if err != nil && updatedErr == nil {
+			a.logger.Warnf("%s in %s has %s, using its update date\n", entry.YTVideoID, feed.URL, err)
+			published, err = updated, nil
+		}
+		if err != nil {
+			a.logger.Warnf("%s in %s has %s\n", entry.YTVideoID, feed.URL, err)
+			continue
+		}
+		entry.Published = published.Format(time.RFC3339)
+	}
+}
+
+// Styles of dates in the picker and `yt-rss list`, set with date_style.
+const (
+	dateStyleAbsolute = "absolute" // Formatted with date_format, e.g. "02 Jan"
+	dateStyleRelative = "relative" // e.g. "3d ago"
+	dateStyleBoth     = "both"     // e.g. "02 Jan (3d ago)"
+)
+
+// dateNames are the month and weekday names that time.Format writes, in the
+// languages dates can be localized to. Full names come before abbreviations,
+// so that "March" isn't replaced as "Mar" followed by "ch".
+var dateNames = map[string][]string{
+	"de": {
+		"January", "Januar", "February", "Februar", "March", "März", "April", "April", "June", "Juni", "July", "Juli", "October", "Oktober", "December", "Dezember",
+		"Monday", "Montag", "Tuesday", "Dienstag", "Wednesday", "Mittwoch", "Thursday", "Donnerstag", "Friday", "Freitag", "Saturday", "Samstag", "Sunday", "Sonntag",
+		"Mar", "Mär", "May", "Mai", "Oct", "Okt", "Dec", "Dez",
+		"Mon", "Mo", "Tue", "Di", "Wed", "Mi", "Thu", "Do", "Fri", "Fr", "Sat", "Sa", "Sun", "So",
+	},
+	"es": {
+		"January", "enero", "February", "febrero", "March", "marzo", "April", "abril", "May", "mayo", "June", "junio", "July", "julio", "August", "agosto", "September", "septiembre", "October", "octubre", "November", "noviembre", "December", "diciembre",
+		"Monday", "lunes", "Tuesday", "martes", "Wednesday", "miércoles", "Thursday", "jueves", "Friday", "viernes", "Saturday", "sábado", "Sunday", "domingo",
+		"Jan", "ene", "Feb", "feb", "Mar", "mar", "Apr", "abr", "Jun", "jun", "Jul", "jul", "Aug", "ago", "Sep", "sep", "Oct", "oct", "Nov", "nov", "Dec", "dic",
+		"Mon", "lun", "Tue", "mar", "Wed", "mié", "Thu", "jue", "Fri", "vie", "Sat", "sáb", "Sun", "dom",
+	},
+}
+
+// dateLocalizer returns a replacer that translates the month and weekday
+// names in formatted dates into DateLocale, or the language of messages if
+// it isn't set. It is nil if dates are left in English.
+func (a *App) dateLocalizer() *strings.Replacer {
+	locale := a.DateLocale
+	if locale == "" {
+		locale = a.language.String()
+	}
+	base, _ := language.Make(locale).Base()
+	names, ok := dateNames[base.String()]
+	if !ok {
+		return nil
+	}
+	return strings.NewReplacer(names...)
+}
+
+// formatDate formats the publish date of a video for the picker and `yt-rss
+// list`, in the style set with date_style, in the time zone set with timezone.
+// Dates from another year include the year, unless date_format already does,
+// so that e.g. last December isn't mistaken for this December.
+func (a *App) formatDate(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		// The date couldn't be parsed
+		return "?"
+	}
+	t = a.localTime(t)
+	now = a.localTime(now)
+	relative := a.formatRelativeTime(t, now)
+	if a.DateStyle == dateStyleRelative {
+		return relative
+	}
+
+	layout := a.DateFormat
+	if t.Year() != now.Year() && !strings.Contains(layout, "06") {
+		layout += " 2006"
+	}
+	absolute := t.Format(layout)
+	if localizer := a.dateLocalizer(); localizer != nil {
+		absolute = localizer.Replace(absolute)
+	}
+	if a.DateStyle == dateStyleBoth {
+		return fmt.Sprintf("%s (%s)", absolute, relative)
+	}
+	return absolute
+}
+
+// formatRelativeTime formats the time relative to now, in the largest unit
+// that fits, e.g. "5m ago", "3d ago", or "in 2h" for scheduled premieres.
+func (a *App) formatRelativeTime(t time.Time, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	const day = 24 * time.Hour
+	var amount string
+	switch {
+	case d < time.Minute:
+		return a.printer.Sprintf("just now")
+	case d < time.Hour:
+		amount = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < day:
+		amount = fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < 14*day:
+		amount = fmt.Sprintf("%dd", int(d/day))
+	case d < 60*day:
+		amount = fmt.Sprintf("%dw", int(d/(7*day)))
+	case d < 365*day:
+		amount = fmt.Sprintf("%dmo", int(d/(30*day)))
+	default:
+		amount = fmt.Sprintf("%dy", int(d/(365*day)))
+	}
+	if future {
+		return a.printer.Sprintf("in %s", amount)
+	}
+	return a.printer.Sprintf("%s ago", amount)
+}
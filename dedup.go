@@ -0,0 +1,109 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// duplicateTitleTagRegex matches tags that reuploads and cross-posts add to
+// the original title, e.g. "(Reupload)", "[4K]", or "| Full episode".
+var duplicateTitleTagRegex = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]|【[^】]*】|\|.*$`)
+
+const (
+	// Titles with fewer words than this, e.g. "Live" or "Q&A", are too
+	// generic to tell whether two videos are the same.
+	minDuplicateTitleWords = 3
+
+	// Durations of the same video may differ by a few seconds between
+	// uploads, e.g. with a different intro card.
+	maxDuplicateDurationDifference = 5 * time.Second
+)
+
+// entryKey returns the key that identifies the entry across feeds: its video
+// ID, or its entry ID for feeds without video IDs.
+func entryKey(entry FeedEntry) string {
+	if entry.YTVideoID != "" {
+		return entry.YTVideoID
+	}
+	return entry.ID
+}
+
+// keepEarliestPublished sets the entry's publish date to the duplicate's, if
+// the duplicate was published earlier, e.g. in the original channel's feed
+// rather than a playlist that reposted it later, or if the entry's date is
+// invalid, e.g. because it was cached before dates were normalized.
+func keepEarliestPublished(entry *FeedEntry, duplicate FeedEntry) {
+	published, err := duplicate.PublishedDate()
+	if err != nil {
+		return
+	}
+	if current, err := entry.PublishedDate(); err != nil || published.Before(current) {
+		entry.Published = duplicate.Published
+	}
+}
+
+// duplicateTitleKey returns the title without tags, hashtags, punctuation,
+// and case, so that the titles of reuploads match the original. It returns
+// an empty string if the title is too generic to compare.
+func duplicateTitleKey(title string) string {
+	title = duplicateTitleTagRegex.ReplaceAllString(title, " ")
+	title = hashtagRegex.ReplaceAllString(title, " ")
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) < minDuplicateTitleWords {
+		return ""
+	}
+	return strings.Join(words, " ")
+}
+
+// isNearDuplicate returns true if the entries look like the same video: their
+// titles match, and their durations are about the same, if they are known.
+func isNearDuplicate(a, b FeedEntry) bool {
+	da, db := a.ExtraMetadata.VideoDuration, b.ExtraMetadata.VideoDuration
+	if da == 0 || db == 0 {
+		return true
+	}
+	return max(da, db)-min(da, db) <= maxDuplicateDurationDifference
+}
+
+// dedupTitles hides reuploads and videos cross-posted to several channels,
+// keeping the earliest published of each. The entries keep their order.
+func dedupTitles(entries []FeedEntry) []FeedEntry {
+	indices := make([]int, len(entries))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return entries[indices[i]].GetPublishedDate().Before(entries[indices[j]].GetPublishedDate())
+	})
+
+	kept := make(map[string][]FeedEntry) // By title key
+	duplicate := make([]bool, len(entries))
+	for _, i := range indices {
+		key := duplicateTitleKey(entries[i].MediaGroup.Title)
+		if key == "" {
+			continue
+		}
+		for _, v := range kept[key] {
+			if isNearDuplicate(v, entries[i]) {
+				duplicate[i] = true
+				break
+			}
+		}
+		if !duplicate[i] {
+			kept[key] = append(kept[key], entries[i])
+		}
+	}
+
+	var deduped []FeedEntry
+	for i, v := range entries {
+		if !duplicate[i] {
+			deduped = append(deduped, v)
+		}
+	}
+	return deduped
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// descriptionURLRegex matches URLs in video descriptions. Trailing
+// punctuation is trimmed afterwards, with trimURLPunctuation.
+var descriptionURLRegex = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// trimURLPunctuation trims punctuation that ends the sentence around a URL
+// rather than the URL itself, e.g. the period in "see https://example.com.".
+// A closing parenthesis is kept if the URL has the opening one, as in
+// Wikipedia links.
+func trimURLPunctuation(u string) string {
+	for {
+		trimmed := strings.TrimRight(u, ".,;:!?'\"")
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = strings.TrimSuffix(trimmed, ")")
+		}
+		if trimmed == u {
+			return u
+		}
+		u = trimmed
+	}
+}
+
+// extractLinks returns the URLs in the description, in the order they first
+// appear.
+func extractLinks(description string) []string {
+	var links []string
+	seen := make(map[string]bool)
+	for _, v := range descriptionURLRegex.FindAllString(description, -1) {
+		v = trimURLPunctuation(v)
+		if _, err := url.Parse(v); err != nil || seen[v] {
+			continue
+		}
+		seen[v] = true
+		links = append(links, v)
+	}
+	return links
+}
+
+// hyperlink returns text as an OSC 8 terminal hyperlink to the URL, which
+// most terminals and fzf's preview pane make clickable. Terminals without
+// support show the text as is.
+func hyperlink(u, text string) string {
+	return "\x1b]8;;" + u + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// linkifyDescription makes the URLs in the description clickable.
+func linkifyDescription(description string) string {
+	return descriptionURLRegex.ReplaceAllStringFunc(description, func(match string) string {
+		u := trimURLPunctuation(match)
+		return hyperlink(u, u) + match[len(u):]
+	})
+}
+
+// printDescription prints the entry's description, followed by a numbered
+// list of the links in it, so that links in long descriptions are easy to
+// find. With hyperlinks, the links are made clickable.
+func printDescription(entry FeedEntry, hyperlinks bool) {
+	description := entry.MediaGroup.Description
+	if hyperlinks {
+		description = linkifyDescription(description)
+	}
+	fmt.Println(description)
+
+	links := extractLinks(entry.MediaGroup.Description)
+	if len(links) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println(color.New(color.Bold).Sprint("Links:"))
+	for i, v := range links {
+		if hyperlinks {
+			v = hyperlink(v, v)
+		}
+		fmt.Printf("%3d. %s\n", i+1, v)
+	}
+}
+
+// runShow prints the details and full description of a video, given by its
+// ID or URL, with the links in the description extracted. The video must be
+// in the cache or the watch-later list.
+func (a *App) runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	noLinks := fs.Bool("no-hyperlinks", false, "Print links as plain text, for terminals that show OSC 8 hyperlinks as garbage")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: yt-rss show [--no-hyperlinks] <video ID or URL>")
+	}
+	videoID := fs.Arg(0)
+	if id := parseVideoIDFromURL(videoID); id != "" {
+		videoID = id
+	}
+	entry, err := a.findEntry(videoID)
+	if err != nil {
+		return err
+	}
+	// Hyperlinks are only printed to terminals, not when the output is
+	// piped or colors are off.
+	return a.printEntry(entry, !*noLinks && !color.NoColor)
+}
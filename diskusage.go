@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// getDirSize returns the total size of the files in the directory, including
+// subdirectories. A missing directory has a size of zero.
+func getDirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// formatSize formats a size in bytes for humans, e.g. 1.5G.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// parseSize parses a size such as 500M or 50G. A plain number is in bytes.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	if i := strings.IndexAny(s, "KMGT"); i >= 0 && i == len(s)-1 {
+		multiplier = int64(1) << (10 * (strings.IndexByte("KMGT", s[i]) + 1))
+		s = s[:i]
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// pruneDownloads deletes downloads according to the retention settings.
+// Downloads watched longer than DownloadRetention ago are deleted. Then, if
+// the downloads exceed DownloadMaxSize, watched downloads are deleted, the
+// earliest watched first, until the size is under the limit. Unwatched
+// downloads are never deleted. If dryRun is true, the downloads that would be
+// deleted are only printed.
+func (a *App) pruneDownloads(dryRun bool) error {
+	if a.DownloadRetention == 0 && a.DownloadMaxSize == "" {
+		return nil
+	}
+	if !dryRun {
+		if err := a.checkWritable(); err != nil {
+			return err
+		}
+	}
+	downloads, err := a.loadDownloads()
+	if err != nil {
+		return err
+	}
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+
+	type candidate struct {
+		record    *DownloadRecord
+		size      int64
+		watchedAt time.Time
+	}
+	var totalSize int64
+	var candidates []candidate
+	for _, v := range downloads {
+		info, err := os.Stat(v.Path)
+		if err != nil {
+			// Already deleted outside of yt-rss
+			continue
+		}
+		totalSize += info.Size()
+		if watchedAt, ok := history.LastWatchedAt(v.VideoID); ok {
+			candidates = append(candidates, candidate{record: v, size: info.Size(), watchedAt: watchedAt})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].watchedAt.Before(candidates[j].watchedAt)
+	})
+
+	var maxSize int64 = -1
+	if a.DownloadMaxSize != "" {
+		maxSize, err = parseSize(a.DownloadMaxSize)
+		if err != nil {
+			return errors.Wrap(err, "download_max_size")
+		}
+	}
+
+	var deleted []*DownloadRecord
+	for _, v := range candidates {
+		expired := a.DownloadRetention > 0 && time.Since(v.watchedAt) > a.DownloadRetention
+		overLimit := maxSize >= 0 && totalSize > maxSize
+		if !expired && !overLimit {
+			continue
+		}
+		a.printer.Fprintf(os.Stderr, "Deleting %s (%s)\n", v.record.Path, formatSize(v.size))
+		if !dryRun {
+			err := os.Remove(v.record.Path)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		totalSize -= v.size
+		deleted = append(deleted, v.record)
+	}
+	if dryRun || len(deleted) == 0 {
+		return nil
+	}
+	for _, v := range deleted {
+		delete(downloads, v.VideoID)
+	}
+	return a.saveDownloads(downloads)
+}
+
+func (a *App) runPruneDownloads(args []string) error {
+	fs := flag.NewFlagSet("prune-downloads", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Only print the downloads that would be deleted")
+	fs.Parse(args)
+	return a.pruneDownloads(*dryRun)
+}
+
+// runStats prints statistics about yt-rss's data: the channels in the cache,
+// the watch history, and the downloads. With --heatmap, it shows a heatmap of
+// the watch history instead.
+func (a *App) runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	heatmap := fs.Bool("heatmap", false, "Show a heatmap of the videos watched per day over the past year")
+	order := fs.String("sort", channelStatsByUploads, "Order of the channels: uploads, length, watched (least watched first, to find channels to unsubscribe from), or name")
+	top := fs.Int("top", 10, "Number of most watched channels to show")
+	fs.Parse(args)
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	if *heatmap {
+		a.printHeatmap(os.Stdout, history, time.Now())
+		return nil
+	}
+
+	entries, err := a.getFromCache()
+	if err != nil {
+		return err
+	}
+	err = a.printChannelStats(os.Stdout, entries, history, *order, *top, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+
+	downloads, err := a.loadDownloads()
+	if err != nil {
+		return err
+	}
+	dirSize, err := getDirSize(a.getDownloadDir())
+	if err != nil {
+		return err
+	}
+	a.printer.Printf("Downloads: %d videos, %s in %s\n", len(downloads), formatSize(dirSize), a.getDownloadDir())
+	if a.DownloadMaxSize != "" {
+		a.printer.Printf("Download size limit: %s\n", a.DownloadMaxSize)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Health statuses of a feed, reported by `yt-rss doctor`.
+const (
+	feedHealthOK      = "ok"
+	feedHealthStale   = "stale"   // No uploads for longer than --stale-after
+	feedHealthRenamed = "renamed" // The channel or playlist has a new name since it was cached
+	feedHealthDead    = "dead"    // The channel or playlist no longer exists
+	feedHealthError   = "error"   // The feed couldn't be fetched or parsed
+)
+
+// feedHealth is the result of checking a feed.
+type feedHealth struct {
+	URL        string    `json:"url"`
+	Status     string    `json:"status"` // One of the feedHealth constants
+	HTTPStatus int       `json:"http_status,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	LastUpload time.Time `json:"last_upload"`
+	Detail     string    `json:"detail,omitempty"` // Why the feed isn't ok
+}
+
+// checkFeedHealth fetches the feed and checks that it is valid and recently
+// updated. cachedName is the name of the feed's channel or playlist in the
+// cache, to detect renames, or empty if it isn't known.
+func (a *App) checkFeedHealth(feedURL, cachedName string, staleAfter time.Duration) feedHealth {
+	health := feedHealth{URL: feedURL}
+	resp, err := a.httpGet(feedURL)
+	if err != nil {
+		health.Status, health.Detail = feedHealthError, err.Error()
+		return health
+	}
+	defer resp.Body.Close()
+	health.HTTPStatus = resp.StatusCode
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		// YouTube returns 404 for channels and playlists that were
+		// deleted, and for malformed channel IDs.
+		health.Status, health.Detail = feedHealthDead, "the channel or playlist no longer exists"
+		return health
+	case resp.StatusCode != http.StatusOK:
+		health.Status, health.Detail = feedHealthError, "unexpected status: "+resp.Status
+		return health
+	}
+
+	feed := &Feed{}
+	err = xml.NewDecoder(a.limitBody(resp)).Decode(feed)
+	if err != nil {
+		health.Status, health.Detail = feedHealthError, errors.Wrap(err, "invalid feed").Error()
+		return health
+	}
+	health.Title = feed.Title
+	for _, v := range feed.Entries {
+		if published := v.GetPublishedDate(); published.After(health.LastUpload) {
+			health.LastUpload = published
+		}
+	}
+
+	health.Status = feedHealthOK
+	switch {
+	case cachedName != "" && cachedName != feed.Title:
+		health.Status, health.Detail = feedHealthRenamed, fmt.Sprintf("was %q", cachedName)
+	case len(feed.Entries) == 0:
+		health.Status, health.Detail = feedHealthStale, "no videos"
+	case staleAfter > 0 && time.Since(health.LastUpload) > staleAfter:
+		health.Status, health.Detail = feedHealthStale, "last upload was "+a.formatRelativeTime(health.LastUpload, time.Now())
+	}
+	return health
+}
+
+// getCachedFeedNames returns the name of each feed's channel or playlist, as
+// of the oldest cached video from the feed, keyed by feed URL.
+func (a *App) getCachedFeedNames() (map[string]string, error) {
+	entries, err := a.getFromCache()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string)
+	for _, v := range entries {
+		// Entries are cached newest first, so later entries overwrite
+		// the names of newer ones.
+		name := v.Author.Name
+		if v.ExtraMetadata.PlaylistTitle != "" {
+			name = v.ExtraMetadata.PlaylistTitle
+		}
+		if name != "" {
+			names[v.ExtraMetadata.FeedURL] = name
+		}
+	}
+	return names, nil
+}
+
+// runDoctor checks every subscribed feed, and reports feeds that are dead,
+// renamed, stale, or failing, since errors during a refresh scroll by and
+// are easy to miss. It fails if any feed is dead or failing, so that it can
+// be run from scripts.
+func (a *App) runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	staleAfter := 180 * 24 * time.Hour
+	fs.Func("stale-after", "Report feeds without uploads for longer than this, e.g. 90d (default 180d). 0 disables the check.", setDuration(&staleAfter))
+	asJSON := fs.Bool("json", false, "Print the results as JSON")
+	all := fs.Bool("all", false, "Also list healthy feeds")
+	fs.StringVar(&a.progressFormat, "progress", progressFormatBar, "How to report progress: bar, json for JSON lines on stderr, or none")
+	fs.Parse(args)
+	if a.progressFormat != progressFormatBar && a.progressFormat != progressFormatJSON && a.progressFormat != progressFormatNone {
+		return fmt.Errorf("unknown progress format: %s", a.progressFormat)
+	}
+
+	feedURLs, err := a.getFeedURLs()
+	if err != nil {
+		return err
+	}
+	cachedNames, err := a.getCachedFeedNames()
+	if err != nil {
+		return err
+	}
+
+	results := make([]feedHealth, len(feedURLs))
+	progress := a.newProgressReporter("doctor", "Checking feeds", len(feedURLs))
+	ch := make(chan int)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < max(a.FetchConcurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				progress.Start(feedURLs[i])
+				results[i] = a.checkFeedHealth(feedURLs[i], cachedNames[feedURLs[i]], staleAfter)
+				progress.Finish(feedURLs[i], nil)
+			}
+		}()
+	}
+	for i := range feedURLs {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+	progress.Close()
+
+	counts := make(map[string]int)
+	for _, v := range results {
+		counts[v.Status]++
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(results)
+		if err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, v := range results {
+			if v.Status == feedHealthOK && !*all {
+				continue
+			}
+			name := v.Title
+			if name == "" {
+				name = v.URL
+			}
+			lastUpload := "-"
+			if !v.LastUpload.IsZero() {
+				lastUpload = a.formatRelativeTime(v.LastUpload, time.Now())
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Status, name, lastUpload, v.Detail)
+		}
+		err = w.Flush()
+		if err != nil {
+			return err
+		}
+		a.printer.Fprintf(os.Stderr, "%d feeds: %d ok, %d stale, %d renamed, %d dead, %d failed\n",
+			len(results), counts[feedHealthOK], counts[feedHealthStale], counts[feedHealthRenamed], counts[feedHealthDead], counts[feedHealthError])
+	}
+
+	if n := counts[feedHealthDead] + counts[feedHealthError]; n > 0 {
+		return errors.New(a.printer.Sprintf("%d feeds are dead or failing", n))
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func (a *App) getDownloadDir() string {
+	if a.DownloadDir != "" {
+		return a.DownloadDir
+	}
+	usr, _ := user.Current()
+	return filepath.Join(usr.HomeDir, "Videos", "yt-rss")
+}
+
+// DownloadRecord records a video downloaded to disk.
+type DownloadRecord struct {
+	VideoID      string    `json:"video_id"`
+	Title        string    `json:"title"`
+	Channel      string    `json:"channel"`
+	Path         string    `json:"path"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// Downloads are the downloaded videos, keyed by video ID.
+type Downloads map[string]*DownloadRecord
+
+func (a *App) getDownloadsFile() string {
+	return a.getDataFile(getStateDir(), "downloads.json")
+}
+
+func (a *App) loadDownloads() (Downloads, error) {
+	downloads := make(Downloads)
+	b, err := ioutil.ReadFile(a.getDownloadsFile())
+	if os.IsNotExist(err) {
+		return downloads, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &downloads)
+	if err != nil {
+		return nil, err
+	}
+	return downloads, nil
+}
+
+func (a *App) saveDownloads(downloads Downloads) error {
+	if a.ReadOnly {
+		return nil
+	}
+	b, err := json.Marshal(downloads)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.getDownloadsFile(), b, 0600)
+}
+
+// findDownloadedFile returns the path of the video's downloaded file. ok is
+// false if the video hasn't been downloaded, or if the file has since been
+// deleted.
+func (a *App) findDownloadedFile(videoID string) (path string, ok bool, err error) {
+	downloads, err := a.loadDownloads()
+	if err != nil {
+		return "", false, err
+	}
+	record, ok := downloads[videoID]
+	if !ok || record.Path == "" {
+		return "", false, nil
+	}
+	if _, err := os.Stat(record.Path); err != nil {
+		return "", false, nil
+	}
+	return record.Path, true, nil
+}
+
+// downloadEntry downloads the entry's video with yt-dlp, using the configured
+// format and output template, and records the download. yt-dlp's progress
+// output is shown as-is.
+func (a *App) downloadEntry(entry FeedEntry) error {
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	extraArgs, err := splitCommandLine(a.DownloadArgs)
+	if err != nil {
+		return errors.Wrap(err, "parse download arguments")
+	}
+
+	// Have yt-dlp write the final path of the file, so that the download
+	// can be found later.
+	pathFile, err := ioutil.TempFile("", "yt-rss-download-*")
+	if err != nil {
+		return err
+	}
+	pathFile.Close()
+	defer os.Remove(pathFile.Name())
+
+	args := []string{
+		"--format", a.DownloadFormat,
+		"--paths", a.getDownloadDir(),
+		"--output", a.DownloadOutputTemplate,
+		"--print-to-file", "after_move:filepath", pathFile.Name(),
+	}
+	args = append(args, extraArgs...)
+	args = append(args, entry.WatchURL())
+
+	a.printer.Fprintf(os.Stderr, "Downloading %s\n", entry.WatchURL())
+	err = runShellCommand("yt-dlp", args, nil, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(pathFile.Name())
+	if err != nil {
+		return err
+	}
+	downloads, err := a.loadDownloads()
+	if err != nil {
+		return err
+	}
+	downloads[entry.YTVideoID] = &DownloadRecord{
+		VideoID:      entry.YTVideoID,
+		Title:        entry.MediaGroup.Title,
+		Channel:      entry.Author.Name,
+		Path:         strings.TrimSpace(string(b)),
+		DownloadedAt: time.Now(),
+	}
+	return a.saveDownloads(downloads)
+}
+
+// runDownload downloads the videos with the given IDs. If no IDs are given,
+// the picker is opened to select videos to download.
+func (a *App) runDownload(args []string) error {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	a.addCacheFlags(fs)
+	fs.Parse(args)
+	args = fs.Args()
+
+	feedEntries, err := a.loadFeedEntries()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return a.selectAndRun(feedEntries, "download")
+	}
+
+	for _, videoID := range args {
+		entry, ok := findEntryByVideoID(feedEntries, videoID)
+		if !ok {
+			return fmt.Errorf("video not found: %s", videoID)
+		}
+		err := a.downloadEntry(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
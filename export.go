@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+// exporter writes feed entries in an export format. Exporters are listed by
+// name in exporters, and are used by `yt-rss export <name>` and the web UI,
+// so a new format only needs an exporter and an entry in exporters.
+type exporter interface {
+	// Description is shown in `yt-rss export --list`.
+	Description() string
+	// ContentType is the MIME type of the output, for serving it over HTTP.
+	ContentType() string
+	// Export writes the entries to w, in the order given.
+	Export(w io.Writer, entries []FeedEntry) error
+}
+
+// getExporter returns the exporter of the format with the name.
+func (a *App) getExporter(name string) (exporter, error) {
+	e, ok := a.exporters()[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format: %s (available: %s)", name, strings.Join(a.getExporterNames(), ", "))
+	}
+	return e, nil
+}
+
+func (a *App) getExporterNames() []string {
+	var names []string
+	for name := range a.exporters() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// combinedFeedFormats are the formats `yt-rss export feed` can write.
+var combinedFeedFormats = []string{"atom", "rss"}
+
+// getCombinedFeedEntries returns the entries of the combined feed of all
+// subscriptions, newest first. Feed readers keep track of what has been read,
+// so watched videos are included, but videos hidden by the title rules or the
+// Shorts setting are not.
+func (a *App) getCombinedFeedEntries(entries []FeedEntry) ([]FeedEntry, error) {
+	filter, err := a.newEntryFilter()
+	if err != nil {
+		return nil, err
+	}
+	return a.filterEntries(entries, filter), nil
+}
+
+// runExport writes the feed entries to stdout, or to a file with --output, in
+// one of the registered export formats. `yt-rss export feed` writes a
+// combined Atom or RSS feed of all subscriptions, for feed readers, and
+// `yt-rss export subscriptions` writes the subscriptions for NewPipe or
+// FreeTube.
+func (a *App) runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	list := fs.Bool("list", false, "List the export formats")
+	output := fs.String("output", "", "Write to this file instead of stdout")
+	all := fs.Bool("all", false, "Include entries that are hidden in the picker, e.g. watched videos and Shorts")
+	exportType := fs.String("type", "", "Format of `export feed`: "+strings.Join(combinedFeedFormats, " or ")+" (default atom), or of `export subscriptions`: "+strings.Join(subscriptionFormats, " or ")+" (default newpipe)")
+	limit := fs.Int("limit", 0, "Export at most this many videos, newest first. Zero is unlimited.")
+	fs.StringVar(&a.selectedCategory, "category", "", "Only export videos from feeds in this category")
+	a.addCacheFlags(fs)
+	a.addDurationFlags(fs)
+	fs.Parse(args)
+
+	if *list {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, name := range a.getExporterNames() {
+			fmt.Fprintf(w, "%s\t%s\n", name, a.exporters()[name].Description())
+		}
+		return w.Flush()
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: yt-rss export [--output <file>] [--all] [--limit <n>] <feed|subscriptions|%s>", strings.Join(a.getExporterNames(), "|"))
+	}
+	format := fs.Arg(0)
+	if format == "subscriptions" {
+		if *exportType == "" {
+			*exportType = subscriptionFormatNewPipe
+		}
+		return writeExport(*output, func(w io.Writer) error {
+			return a.exportSubscriptions(w, *exportType)
+		})
+	}
+	combinedFeed := format == "feed"
+	if combinedFeed {
+		format = *exportType
+		if format == "" {
+			format = "atom"
+		}
+		if !slices.Contains(combinedFeedFormats, format) {
+			return fmt.Errorf("unknown feed type: %s (available: %s)", format, strings.Join(combinedFeedFormats, ", "))
+		}
+	}
+	exporter, err := a.getExporter(format)
+	if err != nil {
+		return err
+	}
+
+	entries, err := a.loadFeedEntries()
+	if err != nil {
+		return err
+	}
+	if combinedFeed && !*all {
+		entries, err = a.getCombinedFeedEntries(entries)
+		if err != nil {
+			return err
+		}
+	} else if !*all {
+		history, err := a.loadHistory()
+		if err != nil {
+			return err
+		}
+		entries, err = a.getVisibleEntries(entries, history)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[:*limit]
+	}
+
+	return writeExport(*output, func(w io.Writer) error {
+		return exporter.Export(w, entries)
+	})
+}
+
+// writeExport runs export on the output file, or on stdout if it is empty.
+func writeExport(output string, export func(w io.Writer) error) error {
+	if output == "" {
+		return export(os.Stdout)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return errors.Wrap(err, "create output file")
+	}
+	err = export(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exporters returns the export formats by name.
+func (a *App) exporters() map[string]exporter {
+	return map[string]exporter{
+		"atom": atomExporter{},
+		"csv":  csvExporter{},
+		"html": htmlExporter{location: a.location},
+		"ics":  icsExporter{},
+		"json": jsonExporter{},
+		"m3u":  m3uExporter{},
+		"md":   markdownExporter{location: a.location},
+		"rss":  rssExporter{},
+	}
+}
+
+// feedEntryHTML returns the entry's thumbnail and description as HTML, for
+// feed readers to show as the entry's content.
+func feedEntryHTML(entry FeedEntry) string {
+	var b strings.Builder
+	if entry.MediaGroup.Thumbnail.URL != "" {
+		fmt.Fprintf(&b, `<a href="%s"><img src="%s" alt=""></a>`,
+			template.HTMLEscapeString(entry.WatchURL()),
+			template.HTMLEscapeString(entry.MediaGroup.Thumbnail.URL))
+	}
+	if entry.MediaGroup.Description != "" {
+		description := template.HTMLEscapeString(entry.MediaGroup.Description)
+		fmt.Fprintf(&b, "<p>%s</p>", strings.ReplaceAll(description, "\n", "<br>"))
+	}
+	return b.String()
+}
+
+// atomExporter writes an Atom feed of the entries.
+type atomExporter struct{}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Link      atomLink     `xml:"link"`
+	Author    string       `xml:"author>name"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Content   *atomContent `xml:"content,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+func (atomExporter) Description() string { return "Atom feed" }
+func (atomExporter) ContentType() string { return "application/atom+xml" }
+
+func (atomExporter) Export(w io.Writer, entries []FeedEntry) error {
+	feed := atomFeed{
+		ID:      "urn:yt-rss:export",
+		Title:   "yt-rss",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, v := range entries {
+		updated := v.Updated
+		if updated == "" {
+			updated = v.Published
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        v.ID,
+			Title:     v.MediaGroup.Title,
+			Link:      atomLink{Href: v.WatchURL(), Rel: "alternate"},
+			Author:    v.Author.Name,
+			Published: v.Published,
+			Updated:   updated,
+			Content:   &atomContent{Type: "html", Body: feedEntryHTML(v)},
+		})
+	}
+	io.WriteString(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err := encoder.Encode(feed)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// rssExporter writes an RSS 2.0 feed of the entries, for feed readers that
+// don't support Atom.
+type rssExporter struct{}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	GUID        rssGUID `xml:"guid"`
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Author      string  `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (rssExporter) Description() string { return "RSS 2.0 feed" }
+func (rssExporter) ContentType() string { return "application/rss+xml" }
+
+func (rssExporter) Export(w io.Writer, entries []FeedEntry) error {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         "yt-rss",
+			Link:          "https://www.youtube.com/feed/subscriptions",
+			Description:   "Videos from all subscriptions",
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, v := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			GUID:        rssGUID{Value: v.ID},
+			Title:       v.MediaGroup.Title,
+			Link:        v.WatchURL(),
+			Author:      v.Author.Name,
+			PubDate:     v.GetPublishedDate().UTC().Format(time.RFC1123Z),
+			Description: feedEntryHTML(v),
+		})
+	}
+	io.WriteString(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err := encoder.Encode(feed)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// csvExporter writes a row per entry, with a header row.
+type csvExporter struct{}
+
+func (csvExporter) Description() string { return "CSV, one row per video" }
+func (csvExporter) ContentType() string { return "text/csv" }
+
+func (csvExporter) Export(w io.Writer, entries []FeedEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"published", "channel", "title", "duration_seconds", "url", "video_id", "views", "likes"})
+	for _, v := range entries {
+		cw.Write([]string{
+			v.Published,
+			v.Author.Name,
+			v.MediaGroup.Title,
+			strconv.Itoa(int(v.ExtraMetadata.VideoDuration.Seconds())),
+			v.WatchURL(),
+			v.YTVideoID,
+			strconv.FormatInt(v.Views(), 10),
+			strconv.FormatInt(v.Likes(), 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// htmlExporter writes a standalone HTML page listing the entries with their
+// thumbnails, with the dates in location.
+type htmlExporter struct {
+	location *time.Location
+}
+
+var htmlExportTemplate = template.Must(template.New("html").Funcs(template.FuncMap{
+	"duration": formatDuration,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>yt-rss</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; }
+li { display: flex; gap: 1em; margin-bottom: 1em; }
+img { width: 160px; }
+.meta { color: #666; }
+</style>
+</head>
+<body>
+<ul style="list-style: none; padding: 0">
+{{- range .Entries}}
+<li>
+<a href="{{.WatchURL}}"><img src="{{.MediaGroup.Thumbnail.URL}}" alt="" loading="lazy"></a>
+<div>
+<a href="{{.WatchURL}}">{{.MediaGroup.Title}}</a>
+<div class="meta">{{.Author.Name}} · {{(.GetPublishedDate.In $.Location).Format "02 Jan 2006"}}{{if .ExtraMetadata.VideoDuration}} · {{duration .ExtraMetadata.VideoDuration}}{{end}}</div>
+</div>
+</li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
+func (htmlExporter) Description() string { return "HTML page with thumbnails" }
+func (htmlExporter) ContentType() string { return "text/html; charset=utf-8" }
+
+func (e htmlExporter) Export(w io.Writer, entries []FeedEntry) error {
+	return htmlExportTemplate.Execute(w, struct {
+		Entries  []FeedEntry
+		Location *time.Location
+	}{entries, e.location})
+}
+
+// icsExporter writes an iCalendar file with an event per entry, at the time
+// it was published and lasting as long as the video, so that videos can be
+// seen in a calendar.
+type icsExporter struct{}
+
+func (icsExporter) Description() string { return "iCalendar, one event per video" }
+func (icsExporter) ContentType() string { return "text/calendar; charset=utf-8" }
+
+func (icsExporter) Export(w io.Writer, entries []FeedEntry) error {
+	const layout = "20060102T150405Z"
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//yt-rss//export//EN",
+	}
+	now := time.Now().UTC().Format(layout)
+	for _, v := range entries {
+		start := v.GetPublishedDate().UTC()
+		duration := v.ExtraMetadata.VideoDuration
+		if duration <= 0 {
+			duration = time.Minute
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+v.YTVideoID+"@yt-rss",
+			"DTSTAMP:"+now,
+			"DTSTART:"+start.Format(layout),
+			"DTEND:"+start.Add(duration).Format(layout),
+			"SUMMARY:"+escapeICSText(v.Author.Name+": "+v.MediaGroup.Title),
+			"URL:"+v.WatchURL(),
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+	for _, line := range lines {
+		_, err := io.WriteString(w, foldICSLine(line)+"\r\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeICSText escapes the characters that are special in iCalendar text
+// values.
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits lines longer than 75 bytes, as iCalendar requires.
+// Continuation lines start with a space. Lines are only split between
+// characters, so that multi-byte characters stay intact.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
+
+// jsonExporter writes the entries as a JSON array, as `yt-rss list --json`
+// does.
+type jsonExporter struct{}
+
+func (jsonExporter) Description() string { return "JSON array of the cached entries" }
+func (jsonExporter) ContentType() string { return "application/json" }
+
+func (jsonExporter) Export(w io.Writer, entries []FeedEntry) error {
+	if entries == nil {
+		entries = []FeedEntry{} // Write [] rather than null
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// m3uExporter writes an extended M3U playlist, which mpv and most other
+// players can play with yt-dlp.
+type m3uExporter struct{}
+
+func (m3uExporter) Description() string { return "M3U playlist" }
+func (m3uExporter) ContentType() string { return "audio/x-mpegurl" }
+
+func (m3uExporter) Export(w io.Writer, entries []FeedEntry) error {
+	_, err := io.WriteString(w, "#EXTM3U\n")
+	if err != nil {
+		return err
+	}
+	for _, v := range entries {
+		// -1 is the duration of an entry of unknown length
+		seconds := -1
+		if v.ExtraMetadata.VideoDuration > 0 {
+			seconds = int(v.ExtraMetadata.VideoDuration.Seconds())
+		}
+		title := strings.ReplaceAll(v.Author.Name+" - "+v.MediaGroup.Title, "\n", " ")
+		_, err := fmt.Fprintf(w, "#EXTINF:%d,%s\n%s\n", seconds, title, v.WatchURL())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownExporter writes a Markdown list of links to the entries, with the
+// dates in location.
+type markdownExporter struct {
+	location *time.Location
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`)
+
+func (markdownExporter) Description() string { return "Markdown list of links" }
+func (markdownExporter) ContentType() string { return "text/markdown; charset=utf-8" }
+
+func (e markdownExporter) Export(w io.Writer, entries []FeedEntry) error {
+	for _, v := range entries {
+		line := fmt.Sprintf("- [%s](%s) — %s, %s",
+			markdownEscaper.Replace(v.MediaGroup.Title),
+			v.WatchURL(),
+			markdownEscaper.Replace(v.Author.Name),
+			v.GetPublishedDate().In(e.location).Format("02 Jan 2006"),
+		)
+		if v.ExtraMetadata.VideoDuration > 0 {
+			line += " (" + formatDuration(v.ExtraMetadata.VideoDuration) + ")"
+		}
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
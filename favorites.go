@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// getFavoriteFeeds returns the URLs of the feeds marked as favorites in the
+// URLs file, e.g.
+//
+//	https://www.youtube.com/feeds/videos.xml?channel_id=UC... favorite
+func (a *App) getFavoriteFeeds() (map[string]bool, error) {
+	subscriptions, err := a.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	favorites := make(map[string]bool)
+	for _, v := range subscriptions {
+		if v.HasOption("favorite") {
+			favorites[v.URL] = true
+		}
+	}
+	return favorites, nil
+}
+
+// sortFavoritesFirst moves entries from favorite channels above the others,
+// preserving the relative order of entries otherwise.
+func sortFavoritesFirst(entries []FeedEntry, favorites map[string]bool) []FeedEntry {
+	sorted := make([]FeedEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return favorites[sorted[i].ExtraMetadata.FeedURL] && !favorites[sorted[j].ExtraMetadata.FeedURL]
+	})
+	return sorted
+}
+
+// filterFavorites returns the entries from favorite channels.
+func filterFavorites(entries []FeedEntry, favorites map[string]bool) []FeedEntry {
+	var filtered []FeedEntry
+	for _, v := range entries {
+		if favorites[v.ExtraMetadata.FeedURL] {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// filterPickerLines returns the lines of the picker's content whose entries
+// are in entries, so that both views of the picker have the same column
+// widths.
+func filterPickerLines(fzfContent string, entries []FeedEntry) string {
+	ids := make(map[string]bool, len(entries))
+	for _, v := range entries {
+		ids[v.YTVideoID] = true
+	}
+	var lines []string
+	for _, line := range splitPickerLines(fzfContent) {
+		videoID, _, _ := strings.Cut(line, "\t")
+		if ids[videoID] {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// favoritesView holds the picker's lines for both views of the feeds, all
+// videos and those from favorite channels only, for FavoritesKey.
+type favoritesView struct {
+	All       string
+	Favorites string
+}
+
+// Views of the picker, written to the view file. See favoritesBinding.
+const (
+	viewAll       = "all"
+	viewFavorites = "favorites"
+)
+
+// favoritesBinding returns the fzf binding that switches the picker between
+// all videos and favorites only. fzf can only load lines from a command, so
+// both views are written to a temporary directory, and the binding reloads
+// from `yt-rss favorites-toggle`, which flips the view file and prints the
+// other view's lines. done removes the directory, and returns whether the
+// picker was left showing favorites only, so that it reopens in that view.
+func (a *App) favoritesBinding(view *favoritesView) (binding string, done func() (favoritesOnly bool), err error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "find executable")
+	}
+	dir, err := os.MkdirTemp("", "yt-rss-picker-*")
+	if err != nil {
+		return "", nil, err
+	}
+	current := viewAll
+	if a.favoritesOnly {
+		current = viewFavorites
+	}
+	files := map[string]string{
+		viewAll:       view.All,
+		viewFavorites: view.Favorites,
+		"view":        current,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", nil, err
+		}
+	}
+	binding = fmt.Sprintf("--bind=%s:reload(%s favorites-toggle %s)+first", a.FavoritesKey, shellQuote(executable), shellQuote(dir))
+	done = func() bool {
+		defer os.RemoveAll(dir)
+		b, _ := os.ReadFile(filepath.Join(dir, "view"))
+		return string(b) == viewFavorites
+	}
+	return binding, done, nil
+}
+
+// runFavoritesToggle switches the picker's view, and prints the lines of the
+// new view for fzf to reload. See favoritesBinding.
+func (a *App) runFavoritesToggle(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: yt-rss favorites-toggle <dir>")
+	}
+	dir := args[0]
+	b, err := os.ReadFile(filepath.Join(dir, "view"))
+	if err != nil {
+		return err
+	}
+	view := viewFavorites
+	if string(b) == viewFavorites {
+		view = viewAll
+	}
+	err = os.WriteFile(filepath.Join(dir, "view"), []byte(view), 0600)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(filepath.Join(dir, view))
+	if err != nil || len(content) == 0 {
+		return err
+	}
+	_, err = fmt.Println(string(content))
+	return err
+}
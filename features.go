@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// capability is an optional feature of yt-rss that depends on the system,
+// e.g. on an external program being installed.
+type capability struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Detail    string `json:"detail"`  // What was found, or why the capability is unavailable
+	Enables   string `json:"enables"` // What the capability is used for
+}
+
+// lookPath returns the path of the program, or a note that it wasn't found.
+func lookPath(program string) (string, bool) {
+	p, err := exec.LookPath(program)
+	if err != nil {
+		return program + " not found in PATH", false
+	}
+	return p, true
+}
+
+// getCapabilities detects which optional capabilities are available.
+func (a *App) getCapabilities() []capability {
+	var capabilities []capability
+
+	detail, ok := lookPath("fzf")
+	if ok && a.getPickerBackend() != pickerFZF {
+		detail += fmt.Sprintf(" (not used, picker = %s)", a.PickerBackend)
+	}
+	capabilities = append(capabilities, capability{"fzf", ok && a.getPickerBackend() == pickerFZF, detail, "The interactive picker. Without it, the built-in terminal UI is used."})
+	if backend := a.getPickerBackend(); isLauncherPicker(backend) {
+		detail, ok := lookPath(backend)
+		capabilities = append(capabilities, capability{backend, ok, detail, "The launcher picker, set with picker = " + backend})
+	}
+
+	if isDumbTerminal() {
+		capabilities = append(capabilities, capability{"colors", false, "the terminal doesn't support ANSI escape sequences", "Colors in the picker and preview"})
+	} else {
+		capabilities = append(capabilities, capability{"colors", true, "TERM=" + os.Getenv("TERM"), "Colors in the picker and preview"})
+	}
+
+	args, err := splitCommandLine(a.PlayerCommand)
+	if err != nil || len(args) == 0 {
+		capabilities = append(capabilities, capability{"player", false, "invalid player command", "Playing videos"})
+	} else {
+		if player, err := findPlayer(args[0]); err == nil {
+			capabilities = append(capabilities, capability{"player", true, player, "Playing videos"})
+		} else {
+			capabilities = append(capabilities, capability{"player", false, args[0] + " not found in PATH", "Playing videos"})
+		}
+	}
+	if a.isMPVPlayer() {
+		capabilities = append(capabilities, capability{"mpv", true, "the player is mpv", "Resuming playback, and queueing videos"})
+	} else {
+		capabilities = append(capabilities, capability{"mpv", false, "the player isn't mpv", "Resuming playback, and queueing videos"})
+	}
+
+	detail, ok = lookPath("yt-dlp")
+	capabilities = append(capabilities, capability{"yt-dlp", ok, detail, "Downloads and auto-download"})
+
+	if viewer, detail, ok := a.resolveImageViewer(); ok && viewer != a.PreviewImageViewer {
+		capabilities = append(capabilities, capability{"thumbnails", true, viewer + ": " + detail, "Thumbnails in the preview pane"})
+	} else {
+		capabilities = append(capabilities, capability{"thumbnails", ok, detail, "Thumbnails in the preview pane"})
+	}
+
+	if command, ok := findClipboardCommand(); ok {
+		capabilities = append(capabilities, capability{"clipboard", true, strings.Join(command, " "), "Copying video URLs"})
+	} else {
+		capabilities = append(capabilities, capability{"clipboard", false, "none of wl-copy, xclip, xsel, or pbcopy found", "Copying video URLs"})
+	}
+
+	if command, ok := findNotificationCommand(); ok {
+		capabilities = append(capabilities, capability{"notifications", true, command, "Desktop notifications from `yt-rss notify --output desktop`"})
+	} else {
+		capabilities = append(capabilities, capability{"notifications", false, command + " not found in PATH", "Desktop notifications from `yt-rss notify --output desktop`"})
+	}
+
+	browser := "xdg-open"
+	switch runtime.GOOS {
+	case "darwin":
+		browser = "open"
+	case "windows":
+		browser = "rundll32"
+	}
+	detail, ok = lookPath(browser)
+	capabilities = append(capabilities, capability{"browser", ok, detail, "Opening videos in the browser"})
+
+	return capabilities
+}
+
+// runFeatures reports which optional capabilities are available on this
+// system, and why the others aren't.
+func (a *App) runFeatures(args []string) error {
+	flags := flag.NewFlagSet("features", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Print the capabilities as JSON")
+	flags.Parse(args)
+
+	capabilities := a.getCapabilities()
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(capabilities)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, v := range capabilities {
+		mark := "✗"
+		if v.Available {
+			mark = "✓"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%s\n", mark, v.Name, v.Enables, v.Detail)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// filterRule decides which entries to show based on their titles. An entry
+// is hidden if its title matches any exclude pattern, or if there are include
+// patterns and its title matches none of them.
+type filterRule struct {
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+
+	// MinDuration and MaxDuration hide videos shorter or longer than
+	// them. Zero means no limit.
+	MinDuration time.Duration
+	MaxDuration time.Duration
+}
+
+func (r filterRule) allows(title string) bool {
+	for _, v := range r.Exclude {
+		if v.MatchString(title) {
+			return false
+		}
+	}
+	if len(r.Include) == 0 {
+		return true
+	}
+	for _, v := range r.Include {
+		if v.MatchString(title) {
+			return true
+		}
+	}
+	return false
+}
+
+// durationInRange returns false if the duration is outside of the range,
+// where zero means no limit. Videos with an unknown duration, e.g.
+// livestreams, are always in range.
+func durationInRange(d, minDuration, maxDuration time.Duration) bool {
+	if d == 0 {
+		return true
+	}
+	return (minDuration == 0 || d >= minDuration) && (maxDuration == 0 || d <= maxDuration)
+}
+
+// entryFilter holds the global title rules from the settings file, and the
+// per-channel rules from the URLs file. If Category is set, only entries
+// from feeds in that category are allowed.
+type entryFilter struct {
+	Global         filterRule
+	PerFeed        map[string]filterRule // Keyed by feed URL
+	Category       string
+	FeedCategories map[string]string // Feed URL to category
+	MutedChannels  map[string]bool   // Channel IDs of muted channels. See `yt-rss mute`.
+}
+
+// Allows returns true if the entry's title passes both the global rules and
+// the rules of the channel it belongs to, its duration is in range, it is in
+// the selected category, and its channel isn't muted. A channel's duration range replaces the global
+// one, so that e.g. a podcast channel can allow longer videos.
+func (f *entryFilter) Allows(entry FeedEntry) bool {
+	if f.Category != "" && f.FeedCategories[entry.ExtraMetadata.FeedURL] != f.Category {
+		return false
+	}
+	if f.MutedChannels[entry.ChannelID] {
+		return false
+	}
+	title := entry.MediaGroup.Title
+	if !f.Global.allows(title) {
+		return false
+	}
+	minDuration, maxDuration := f.Global.MinDuration, f.Global.MaxDuration
+	if rule, ok := f.PerFeed[entry.ExtraMetadata.FeedURL]; ok {
+		if !rule.allows(title) {
+			return false
+		}
+		if rule.MinDuration != 0 {
+			minDuration = rule.MinDuration
+		}
+		if rule.MaxDuration != 0 {
+			maxDuration = rule.MaxDuration
+		}
+	}
+	return durationInRange(entry.ExtraMetadata.VideoDuration, minDuration, maxDuration)
+}
+
+// newEntryFilter compiles the title rules. Global rules come from the
+// include_title, exclude_title, exclude_keywords, min_duration, and
+// max_duration settings. Per-channel rules come from the include=, exclude=,
+// min-duration=, and max-duration= options in the URLs file, e.g.
+//
+//	https://www.youtube.com/feeds/videos.xml?channel_id=UC... exclude="(?i)live ?stream" max-duration=3h
+func (a *App) newEntryFilter() (*entryFilter, error) {
+	filter := &entryFilter{
+		PerFeed:        make(map[string]filterRule),
+		Category:       a.selectedCategory,
+		FeedCategories: make(map[string]string),
+	}
+	filter.Global.MinDuration = a.MinDuration
+	filter.Global.MaxDuration = a.MaxDuration
+
+	var err error
+	filter.Global.Include, err = compilePatterns(a.IncludeTitlePatterns)
+	if err != nil {
+		return nil, errors.Wrap(err, "include_title")
+	}
+	filter.Global.Exclude, err = compilePatterns(a.ExcludeTitlePatterns)
+	if err != nil {
+		return nil, errors.Wrap(err, "exclude_title")
+	}
+	for _, keyword := range a.ExcludeKeywords {
+		// Keywords match anywhere in the title, ignoring case
+		filter.Global.Exclude = append(filter.Global.Exclude, regexp.MustCompile("(?i)"+regexp.QuoteMeta(keyword)))
+	}
+
+	subscriptions, err := a.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range subscriptions {
+		if v.Category != "" {
+			filter.FeedCategories[v.URL] = v.Category
+		}
+		var rule filterRule
+		if pattern, ok := v.Options["include"]; ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid include pattern for %s: %s", v.URL, err)
+			}
+			rule.Include = append(rule.Include, re)
+		}
+		if pattern, ok := v.Options["exclude"]; ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exclude pattern for %s: %s", v.URL, err)
+			}
+			rule.Exclude = append(rule.Exclude, re)
+		}
+		if value, ok := v.Options["min-duration"]; ok {
+			rule.MinDuration, err = parseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid min-duration for %s: %s", v.URL, err)
+			}
+		}
+		if value, ok := v.Options["max-duration"]; ok {
+			rule.MaxDuration, err = parseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid max-duration for %s: %s", v.URL, err)
+			}
+		}
+		if len(rule.Include) > 0 || len(rule.Exclude) > 0 || rule.MinDuration != 0 || rule.MaxDuration != 0 {
+			filter.PerFeed[v.URL] = rule
+		}
+	}
+
+	state, err := a.loadState()
+	if err != nil {
+		return nil, err
+	}
+	filter.MutedChannels = make(map[string]bool)
+	for channelID := range state.MutedChannels {
+		if state.IsMuted(channelID) {
+			filter.MutedChannels[channelID] = true
+		}
+	}
+	return filter, nil
+}
+
+// addDurationFlags adds the flags that override min_duration, max_duration,
+// and max_age for this run to the command's flag set.
+func (a *App) addDurationFlags(fs *flag.FlagSet) {
+	fs.Func("min-duration", "Hide videos shorter than this, e.g. 2m", setDuration(&a.MinDuration))
+	fs.Func("max-duration", "Hide videos longer than this, e.g. 2h", setDuration(&a.MaxDuration))
+	fs.Func("since", "Only show videos published within this window, e.g. 7d", setDuration(&a.MaxAge))
+	fs.Func("max-age", "Same as --since", setDuration(&a.MaxAge))
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, v := range patterns {
+		re, err := regexp.Compile(strings.TrimSpace(v))
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// heatmapLevels are the characters for each level of activity in the
+// heatmap, from none to the most.
+var heatmapLevels = []string{"·", "░", "▒", "▓", "█"}
+
+// heatmapWeeks is the number of weeks shown in the heatmap, i.e. a year.
+const heatmapWeeks = 53
+
+// countWatchedPerDay returns the number of videos watched or played on each
+// day, keyed by the start of the day in local time.
+func (a *App) countWatchedPerDay(history History) map[time.Time]int {
+	counts := make(map[time.Time]int)
+	for videoID := range history {
+		t, ok := history.LastWatchedAt(videoID)
+		if !ok {
+			continue
+		}
+		counts[startOfDay(a.localTime(t))]++
+	}
+	return counts
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// heatmapLevel maps a day's count to a level in heatmapLevels, relative to
+// the busiest day.
+func heatmapLevel(count, maxCount int) int {
+	if count == 0 || maxCount == 0 {
+		return 0
+	}
+	level := 1 + (count-1)*(len(heatmapLevels)-1)/maxCount
+	return min(level, len(heatmapLevels)-1)
+}
+
+// printHeatmap prints a GitHub-style heatmap of the videos watched per day
+// over the past year, ending today. Each column is a week, starting on
+// Sunday.
+func (a *App) printHeatmap(w io.Writer, history History, today time.Time) {
+	counts := a.countWatchedPerDay(history)
+	today = startOfDay(today)
+	// The first column starts on the Sunday heatmapWeeks-1 weeks ago
+	start := today.AddDate(0, 0, -int(today.Weekday())-(heatmapWeeks-1)*7)
+
+	total, maxCount := 0, 0
+	for day, count := range counts {
+		if day.Before(start) || day.After(today) {
+			continue
+		}
+		total += count
+		maxCount = max(maxCount, count)
+	}
+
+	// Month labels, above the first week of each month
+	var months strings.Builder
+	months.WriteString("    ")
+	for week := 0; week < heatmapWeeks; week++ {
+		day := start.AddDate(0, 0, week*7)
+		if day.Day() <= 7 && months.Len() <= 4+week {
+			months.WriteString(day.Format("Jan"))
+		} else if months.Len() <= 4+week {
+			months.WriteString(" ")
+		}
+	}
+	fmt.Fprintln(w, months.String())
+
+	green := color.New(color.FgGreen).SprintFunc()
+	for weekday := 0; weekday < 7; weekday++ {
+		label := "   "
+		if weekday%2 == 1 {
+			label = time.Weekday(weekday).String()[:3]
+		}
+		fmt.Fprintf(w, "%s ", label)
+		for week := 0; week < heatmapWeeks; week++ {
+			day := start.AddDate(0, 0, week*7+weekday)
+			if day.After(today) {
+				break
+			}
+			level := heatmapLevel(counts[day], maxCount)
+			if level == 0 {
+				fmt.Fprint(w, faint(heatmapLevels[0]))
+			} else {
+				fmt.Fprint(w, green(heatmapLevels[level]))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+	a.printer.Fprintf(w, "%d videos watched in the past year. Less %s More\n", total, strings.Join(heatmapLevels, ""))
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WatchRecord records that a video has been watched or played. Playing a
+// video doesn't necessarily mean it has been watched, so the two are tracked
+// separately.
+type WatchRecord struct {
+	VideoID   string    `json:"video_id"`
+	Title     string    `json:"title"`
+	Channel   string    `json:"channel"`
+	WatchedAt time.Time `json:"watched_at"` // Zero if the video has not been watched
+
+	PlayCount    int       `json:"play_count,omitempty"`
+	LastPlayedAt time.Time `json:"last_played_at"`
+
+	// Where playback stopped, if the video wasn't played to the end
+	ResumePosition time.Duration `json:"resume_position,omitempty"`
+}
+
+// History is the watch history, keyed by video ID. It is kept separate from
+// State because it can grow large, e.g. after importing years of history from
+// Google Takeout.
+type History map[string]*WatchRecord
+
+func (h History) IsWatched(videoID string) bool {
+	record, ok := h[videoID]
+	return ok && !record.WatchedAt.IsZero()
+}
+
+// PlayCount returns the number of times the video has been played.
+func (h History) PlayCount(videoID string) int {
+	if record, ok := h[videoID]; ok {
+		return record.PlayCount
+	}
+	return 0
+}
+
+// LastWatchedAt returns when the video was last watched or played. ok is
+// false if it has never been watched or played.
+func (h History) LastWatchedAt(videoID string) (t time.Time, ok bool) {
+	record, ok := h[videoID]
+	if !ok {
+		return time.Time{}, false
+	}
+	t = record.WatchedAt
+	if record.LastPlayedAt.After(t) {
+		t = record.LastPlayedAt
+	}
+	return t, !t.IsZero()
+}
+
+// getOrCreateRecord returns the entry's watch record, creating it if it
+// doesn't exist.
+func (h History) getOrCreateRecord(entry FeedEntry) *WatchRecord {
+	record, ok := h[entry.YTVideoID]
+	if !ok {
+		record = &WatchRecord{
+			VideoID: entry.YTVideoID,
+			Title:   entry.MediaGroup.Title,
+			Channel: entry.Author.Name,
+		}
+		h[entry.YTVideoID] = record
+	}
+	return record
+}
+
+func (a *App) getHistoryFile() string {
+	return a.getDataFile(getStateDir(), "history.json")
+}
+
+func (a *App) loadHistory() (History, error) {
+	useDatabase, err := a.useDatabase()
+	if err != nil {
+		return nil, err
+	}
+	if useDatabase {
+		return a.loadHistoryFromDatabase()
+	}
+	return a.loadHistoryFile()
+}
+
+func (a *App) loadHistoryFile() (History, error) {
+	history := make(History)
+	b, err := ioutil.ReadFile(a.getHistoryFile())
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &history)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (a *App) saveHistory(history History) error {
+	if a.ReadOnly {
+		return nil
+	}
+	useDatabase, err := a.useDatabase()
+	if err != nil {
+		return err
+	}
+	if useDatabase {
+		return a.saveHistoryToDatabase(history)
+	}
+	return a.saveHistoryFile(history)
+}
+
+func (a *App) saveHistoryFile(history History) error {
+	b, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.getHistoryFile(), b, 0600)
+}
+
+// saveHistoryWithUndo saves the history, recording the changes to the given
+// video IDs in the operation log so they can be reverted with `yt-rss undo`.
+// Only the changed records are logged, since the full history can be large.
+func (a *App) saveHistoryWithUndo(history History, changedVideoIDs []string, description string) error {
+	previousHistory, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	previousRecords := make(map[string]*WatchRecord)
+	for _, id := range changedVideoIDs {
+		previousRecords[id] = previousHistory[id] // nil if the record is new
+	}
+
+	err = a.recordOperation(Operation{
+		Timestamp:            time.Now(),
+		Description:          description,
+		PreviousWatchRecords: previousRecords,
+	})
+	if err != nil {
+		return errors.Wrap(err, "record operation")
+	}
+	return a.saveHistory(history)
+}
+
+// recordPlay increments the entry's play count.
+func (a *App) recordPlay(entry FeedEntry) error {
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	record := history.getOrCreateRecord(entry)
+	record.PlayCount++
+	record.LastPlayedAt = time.Now()
+	return a.saveHistory(history)
+}
+
+// filterWatched returns the entries that have not been watched.
+func filterWatched(entries []FeedEntry, history History) []FeedEntry {
+	var unwatched []FeedEntry
+	for _, v := range entries {
+		if !history.IsWatched(v.YTVideoID) {
+			unwatched = append(unwatched, v)
+		}
+	}
+	return unwatched
+}
+
+// entryFromWatchRecord returns an entry for a video in the history that is
+// no longer in the cache, with what the watch record knows about it.
+func (a *App) entryFromWatchRecord(record *WatchRecord) FeedEntry {
+	entry := FeedEntry{
+		ID:        "yt:video:" + record.VideoID,
+		YTVideoID: record.VideoID,
+	}
+	entry.MediaGroup.Title = record.Title
+	entry.MediaGroup.Content.URL = entry.WatchURL()
+	entry.Author.Name = record.Channel
+	entry.ExtraMetadata.NormalizedTitle = a.normalizeTitle(entry)
+	return entry
+}
+
+// historyEntries returns the videos that have been watched or played, most
+// recently watched first. Videos in the cache keep their metadata, e.g. their
+// duration.
+func (a *App) historyEntries(history History, cached []FeedEntry) []FeedEntry {
+	cachedByID := make(map[string]FeedEntry, len(cached))
+	for _, v := range cached {
+		cachedByID[v.YTVideoID] = v
+	}
+	var entries []FeedEntry
+	for videoID, record := range history {
+		if _, ok := history.LastWatchedAt(videoID); !ok {
+			continue
+		}
+		entry, ok := cachedByID[videoID]
+		if !ok {
+			entry = a.entryFromWatchRecord(record)
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, _ := history.LastWatchedAt(entries[i].YTVideoID)
+		tj, _ := history.LastWatchedAt(entries[j].YTVideoID)
+		if ti.Equal(tj) {
+			return entries[i].YTVideoID < entries[j].YTVideoID
+		}
+		return ti.After(tj)
+	})
+	return entries
+}
+
+// runHistory opens the videos that have been watched or played in the
+// picker, most recently watched first, to play them again or copy their
+// URLs. With --print, the history is printed instead, and `history export`
+// writes it as CSV or JSON.
+func (a *App) runHistory(args []string) error {
+	if len(args) > 0 && args[0] == "export" {
+		return a.runHistoryExport(args[1:])
+	}
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	printHistory := fs.Bool("print", false, "Print the history to stdout instead of opening the picker")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return errors.New("usage: yt-rss history [--print], or yt-rss history export [--format csv|json] [--output <file>]")
+	}
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	cached, err := a.getFromCache()
+	if err != nil {
+		return err
+	}
+	entries := a.historyEntries(history, cached)
+	if len(entries) == 0 {
+		a.printer.Fprintf(os.Stderr, "No watched videos\n")
+		return nil
+	}
+
+	if *printHistory {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, v := range entries {
+			watchedAt, _ := history.LastWatchedAt(v.YTVideoID)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				v.YTVideoID,
+				a.localTime(watchedAt).Format("02 Jan 2006 15:04"),
+				v.Author.Name,
+				v.ExtraMetadata.NormalizedTitle,
+			)
+		}
+		return w.Flush()
+	}
+	a.historyView = true
+	return a.selectAndRun(cached, "play")
+}
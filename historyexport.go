@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Formats of `yt-rss history export`
+const (
+	historyExportCSV  = "csv"
+	historyExportJSON = "json"
+)
+
+// historyExportRecord is a watched video, as written by `yt-rss history
+// export`.
+type historyExportRecord struct {
+	VideoID         string    `json:"video_id"`
+	Title           string    `json:"title"`
+	Channel         string    `json:"channel"`
+	WatchedAt       time.Time `json:"watched_at"`                 // When the video was last watched or played
+	DurationSeconds int       `json:"duration_seconds,omitempty"` // Only known for videos still in the cache
+	PlayCount       int       `json:"play_count"`
+	URL             string    `json:"url"`
+}
+
+// getHistoryExportRecords returns the watched and played videos, most
+// recently watched first.
+func (a *App) getHistoryExportRecords() ([]historyExportRecord, error) {
+	history, err := a.loadHistory()
+	if err != nil {
+		return nil, err
+	}
+	cached, err := a.getFromCache()
+	if err != nil {
+		return nil, err
+	}
+	records := []historyExportRecord{} // Written as [] rather than null
+	for _, v := range a.historyEntries(history, cached) {
+		watchedAt, _ := history.LastWatchedAt(v.YTVideoID)
+		records = append(records, historyExportRecord{
+			VideoID:         v.YTVideoID,
+			Title:           v.MediaGroup.Title,
+			Channel:         v.Author.Name,
+			WatchedAt:       watchedAt,
+			DurationSeconds: int(v.ExtraMetadata.VideoDuration.Seconds()),
+			PlayCount:       history.PlayCount(v.YTVideoID),
+			URL:             v.WatchURL(),
+		})
+	}
+	return records, nil
+}
+
+func writeHistoryCSV(w io.Writer, records []historyExportRecord) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"video_id", "title", "channel", "watched_at", "duration_seconds", "play_count", "url"})
+	for _, v := range records {
+		duration := ""
+		if v.DurationSeconds > 0 {
+			duration = strconv.Itoa(v.DurationSeconds)
+		}
+		cw.Write([]string{
+			v.VideoID,
+			v.Title,
+			v.Channel,
+			v.WatchedAt.Format(time.RFC3339),
+			duration,
+			strconv.Itoa(v.PlayCount),
+			v.URL,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeHistoryJSON(w io.Writer, records []historyExportRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// runHistoryExport writes the watch history to stdout, or to a file with
+// --output, as CSV or JSON, e.g. for personal analytics or to import into
+// another tracker.
+func (a *App) runHistoryExport(args []string) error {
+	fs := flag.NewFlagSet("history export", flag.ExitOnError)
+	format := fs.String("format", historyExportCSV, "Format to write: csv or json")
+	output := fs.String("output", "", "Write to this file instead of stdout")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return errors.New("usage: yt-rss history export [--format csv|json] [--output <file>]")
+	}
+	var write func(w io.Writer, records []historyExportRecord) error
+	switch *format {
+	case historyExportCSV:
+		write = writeHistoryCSV
+	case historyExportJSON:
+		write = writeHistoryJSON
+	default:
+		return fmt.Errorf("unknown format: %s (available: %s, %s)", *format, historyExportCSV, historyExportJSON)
+	}
+	records, err := a.getHistoryExportRecords()
+	if err != nil {
+		return err
+	}
+	return writeExport(*output, func(w io.Writer) error {
+		return write(w, records)
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// httpTransport is shared by all HTTP clients, so that connections to
+// YouTube are pooled and reused across feeds and metadata requests.
+var httpTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   20,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: 20 * time.Second,
+	ForceAttemptHTTP2:     true,
+}
+
+// newHTTPClient returns a client using the shared transport. Redirects are
+// followed unless followRedirects is false, in which case redirect responses
+// are returned as-is.
+func newHTTPClient(timeout time.Duration, followRedirects bool) *http.Client {
+	client := &http.Client{
+		Transport: httpTransport,
+		Timeout:   timeout,
+	}
+	if !followRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client
+}
+
+// limitBody returns a reader for the response body that fails once more than
+// MaxResponseSize bytes are read, so that a misbehaving server can't make
+// yt-rss read an unbounded response into memory.
+func (a *App) limitBody(resp *http.Response) io.Reader {
+	return http.MaxBytesReader(nil, resp.Body, a.MaxResponseSize)
+}
+
+// readBody reads the whole response body, up to MaxResponseSize bytes.
+func (a *App) readBody(resp *http.Response) ([]byte, error) {
+	b, err := io.ReadAll(a.limitBody(resp))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, errors.Errorf("response from %s is larger than %d bytes", resp.Request.URL, a.MaxResponseSize)
+	}
+	return b, err
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// translations is the message catalog, keyed by language and then by the
+// English format string.
+var translations = map[language.Tag]map[string]string{
+	language.German: {
+		"Using cached feeds\n":                           "Zwischengespeicherte Feeds werden verwendet\n",
+		"Using cached feeds (offline)\n":                 "Zwischengespeicherte Feeds werden verwendet (offline)\n",
+		"no cached feeds to use offline":                 "keine zwischengespeicherten Feeds für den Offline-Modus",
+		"--refresh and --offline can't be used together": "--refresh und --offline können nicht zusammen verwendet werden",
+		"unknown command: %s\n\n":                        "Unbekannter Befehl: %s\n\n",
+		"Usage: yt-rss [command] [flags]\n\nCommands:\n": "Verwendung: yt-rss [Befehl] [Optionen]\n\nBefehle:\n",
+		"\nGlobal flags:\n":                              "\nGlobale Optionen:\n",
+		"Skipping %s: video is %s (%s)\n":                "%s wird übersprungen: Video ist %s (%s)\n",
+		"Playing %s\n":                                   "Wiedergabe von %s\n",
+		"Playback from %s failed\n":                      "Wiedergabe über %s fehlgeschlagen\n",
+		"Opening %s in the browser\n":                    "%s wird im Browser geöffnet\n",
+		"Playback failed: %s > ":                         "Wiedergabe fehlgeschlagen: %s > ",
+		"Downloading %s\n":                               "%s wird heruntergeladen\n",
+		"failed to download %s: %s\n":                    "Herunterladen von %s fehlgeschlagen: %s\n",
+		"Deleting %s (%s)\n":                             "%s wird gelöscht (%s)\n",
+		"Downloads: %d videos, %s in %s\n":               "Downloads: %d Videos, %s in %s\n",
+		"Download size limit: %s\n":                      "Download-Größenlimit: %s\n",
+		"Imported metadata for %d videos, %d new downloads, %d marked as watched\n": "Metadaten für %d Videos importiert, %d neue Downloads, %d als gesehen markiert\n",
+		"Already subscribed to %s\n":                                 "%s ist bereits abonniert\n",
+		"Subscribed to %s\n":                                         "%s abonniert\n",
+		"Subscriptions are in sync\n":                                "Abonnements sind synchron\n",
+		"Only in account export (%d):\n":                             "Nur im Konto-Export (%d):\n",
+		"Only in local subscriptions (%d):\n":                        "Nur in lokalen Abonnements (%d):\n",
+		"unknown channel":                                            "unbekannter Kanal",
+		"Subscribe to %s locally?":                                   "%s lokal abonnieren?",
+		"Unsubscribe from %s locally?":                               "%s lokal deabonnieren?",
+		"Subscribed to %d channels, unsubscribed from %d channels\n": "%d Kanäle abonniert, %d Kanäle deabonniert\n",
+		"%s [y/N] ":        "%s [j/N] ",
+		"y":                "j",
+		"yes":              "ja",
+		"Undid %s (%s)\n":  "%s rückgängig gemacht (%s)\n",
+		"Moved %s to %s\n": "%s nach %s verschoben\n",
+		"Enter the numbers of the videos, optionally preceded by an action (%s). Leave empty to quit.\n": "Nummern der Videos eingeben, optional mit einer Aktion davor (%s). Leer lassen zum Beenden.\n",
+		"The cache is corrupted (%s), starting with an empty cache\n":                                    "Der Cache ist beschädigt (%s), es wird mit einem leeren Cache begonnen\n",
+		"Marked %d videos as watched, %d of them in the feed cache\n":                                    "%d Videos als gesehen markiert, davon %d im Feed-Cache\n",
+		"Replaying: yt-rss %s\n": "Wiederholung: yt-rss %s\n",
+		"No videos match %s\n":   "Keine Videos passen zu %s\n",
+		"%d new videos\n":        "%d neue Videos\n",
+		"%d new videos":          "%d neue Videos",
+		"and %d more":            "und %d weitere",
+		"%d videos watched in the past year. Less %s More\n":               "%d Videos im letzten Jahr gesehen. Weniger %s Mehr\n",
+		"Using feeds from the daemon\n":                                    "Feeds vom Daemon werden verwendet\n",
+		"the daemon isn't running":                                         "der Daemon läuft nicht",
+		"Daemon running (pid %s) since %s\n":                               "Daemon läuft (PID %s) seit %s\n",
+		"Refreshing the feeds for the first time\n":                        "Die Feeds werden zum ersten Mal aktualisiert\n",
+		"%d videos, last refreshed at %s, next check at %s\n":              "%d Videos, zuletzt aktualisiert um %s, nächste Prüfung um %s\n",
+		"Last refresh failed: %s\n":                                        "Letzte Aktualisierung fehlgeschlagen: %s\n",
+		"Fetching metadata for %d more videos in the background\n":         "Metadaten für %d weitere Videos werden im Hintergrund abgerufen\n",
+		"failed   %s: %s\n":                                                "fehlgeschlagen  %s: %s\n",
+		"skipped  %s (already subscribed)\n":                               "übersprungen    %s (bereits abonniert)\n",
+		"ok       %s (%s)\n":                                               "ok              %s (%s)\n",
+		"Validating channels":                                              "Kanäle werden geprüft",
+		"import aborted, no subscriptions were changed":                    "Import abgebrochen, keine Abonnements wurden geändert",
+		"%d channels can be imported, %d already subscribed, %d failed\n":  "%d Kanäle können importiert werden, %d bereits abonniert, %d fehlgeschlagen\n",
+		"Subscribed to %d channels, %d already subscribed, %d failed\n":    "%d Kanäle abonniert, %d bereits abonniert, %d fehlgeschlagen\n",
+		"Serving the web UI at http://%s\n":                                "Die Weboberfläche wird unter http://%s bereitgestellt\n",
+		"Imported %d cached videos and %d watch history records into %s\n": "%d zwischengespeicherte Videos und %d Einträge des Wiedergabeverlaufs wurden in %s importiert\n",
+		"just now":                      "gerade eben",
+		"in %s":                         "in %s",
+		"%s ago":                        "vor %s",
+		"Failed to fetch %s after %s\n": "Abrufen von %s nach %s fehlgeschlagen\n",
+		"Fetched %s in %s, %d videos\n": "%s in %s abgerufen, %d Videos\n",
+		"failed to get video duration for %s (%s): %s\n": "Videodauer für %s (%s) konnte nicht ermittelt werden: %s\n",
+		"failed to check if %s is a short (%s): %s\n":    "Prüfung, ob %s ein Short ist, fehlgeschlagen (%s): %s\n",
+		"Loading...": "Wird geladen...",
+		"%d feeds: %d ok, %d stale, %d renamed, %d dead, %d failed\n": "%d Feeds: %d ok, %d veraltet, %d umbenannt, %d tot, %d fehlgeschlagen\n",
+		"%d feeds are dead or failing":                                "%d Feeds sind tot oder fehlerhaft",
+		"Skipping %s, which isn't a channel\n":                        "%s wird übersprungen, da es kein Kanal ist\n",
+		"failed to get metadata for %s (%s) from %s: %s\n":            "Metadaten für %s (%s) konnten nicht von %s abgerufen werden: %s\n",
+		"failed to get sponsor segments for %s (%s): %s\n":            "Sponsor-Segmente für %s (%s) konnten nicht abgerufen werden: %s\n",
+		"Uncategorized":                                           "Ohne Kategorie",
+		"Added %d videos to watch later\n":                        "%d Videos zu „Später ansehen“ hinzugefügt\n",
+		"Removed %d videos from watch later\n":                    "%d Videos aus „Später ansehen“ entfernt\n",
+		"Nothing to watch later\n":                                "Nichts in „Später ansehen“\n",
+		"Added %d videos to the queue\n":                          "%d Videos zur Warteschlange hinzugefügt\n",
+		"Removed %d videos from the queue\n":                      "%d Videos aus der Warteschlange entfernt\n",
+		"The queue is empty\n":                                    "Die Warteschlange ist leer\n",
+		"Muted %s until %s\n":                                     "%s bis %s stummgeschaltet\n",
+		"Muted %s\n":                                              "%s stummgeschaltet\n",
+		"Unmuted %s\n":                                            "Stummschaltung von %s aufgehoben\n",
+		"No muted channels\n":                                     "Keine stummgeschalteten Kanäle\n",
+		"until unmuted":                                           "bis zur Aufhebung",
+		"until %s":                                                "bis %s",
+		"%s in %s has %s, using its update date\n":                "%s in %s hat %s, das Aktualisierungsdatum wird verwendet\n",
+		"%s in %s has %s\n":                                       "%s in %s hat %s\n",
+		"failed to save metadata checkpoint: %s\n":                "Zwischenstand der Metadaten konnte nicht gespeichert werden: %s\n",
+		"Saving metadata of %d videos\n":                          "Metadaten von %d Videos werden gespeichert\n",
+		"Fetching feeds":                                          "Feeds werden abgerufen",
+		"Adding metadata":                                         "Metadaten werden hinzugefügt",
+		"%s %d/%d, %d failed":                                     "%s %d/%d, %d fehlgeschlagen",
+		"%d feeds, %d failed, %d entries, %d new in %s\n":         "%d Feeds, %d fehlgeschlagen, %d Einträge, %d neu in %s\n",
+		"%d errors, %d warnings":                                  "%d Fehler, %d Warnungen",
+		"No errors, %d warnings\n":                                "Keine Fehler, %d Warnungen\n",
+		"No problems found\n":                                     "Keine Probleme gefunden\n",
+		"Edit again?":                                             "Erneut bearbeiten?",
+		"%s has errors":                                           "%s enthält Fehler",
+		"No watched videos\n":                                     "Keine angesehenen Videos\n",
+		"Cache: %d videos from %d channels, %d watched\n":         "Cache: %d Videos von %d Kanälen, %d angesehen\n",
+		"History: %d videos watched, %d plays\n":                  "Verlauf: %d Videos angesehen, %d Wiedergaben\n",
+		"Channel\tUploads/week\tAvg length\tWatched\tLast upload": "Kanal\tUploads/Woche\tØ Länge\tAngesehen\tLetzter Upload",
+		"Most watched channels:\n":                                "Meistgesehene Kanäle:\n",
+		"%d videos":                                               "%d Videos",
+		"Copied %d URLs to the clipboard\n":                       "%d URLs in die Zwischenablage kopiert\n",
+		"Interrupted after %d of %d feeds, saving what was fetched\n":                      "Abgebrochen nach %d von %d Feeds, das bisher Geladene wird gespeichert\n",
+		"Got the duration of %s in %s\n":                                                   "Dauer von %s in %s abgerufen\n",
+		"Got the duration of %s from the innertube API, since the watch page failed: %s\n": "Dauer von %s über die Innertube-API abgerufen, da die Videoseite fehlschlug: %s\n",
+		"Ignoring the invalid title_rewrite %s: %s\n":                                      "Ungültige title_rewrite %s wird ignoriert: %s\n",
+		"Ignoring the invalid rewrite rule for %s: %s\n":                                   "Ungültige rewrite-Regel für %s wird ignoriert: %s\n",
+		"no videos": "keine Videos",
+		"%d subscriptions haven't uploaded in %s\n":                                                   "%d Abos haben seit %s nichts hochgeladen\n",
+		"%d subscriptions haven't uploaded in %s, list them with `yt-rss subscriptions --stale %s`\n": "%d Abos haben seit %s nichts hochgeladen, anzeigen mit `yt-rss subscriptions --stale %s`\n",
+	},
+	language.Spanish: {
+		"Using cached feeds\n":                           "Usando los feeds en caché\n",
+		"Using cached feeds (offline)\n":                 "Usando los feeds en caché (sin conexión)\n",
+		"no cached feeds to use offline":                 "no hay feeds en caché para usar sin conexión",
+		"--refresh and --offline can't be used together": "--refresh y --offline no se pueden usar juntos",
+		"unknown command: %s\n\n":                        "comando desconocido: %s\n\n",
+		"Usage: yt-rss [command] [flags]\n\nCommands:\n": "Uso: yt-rss [comando] [opciones]\n\nComandos:\n",
+		"\nGlobal flags:\n":                              "\nOpciones globales:\n",
+		"Skipping %s: video is %s (%s)\n":                "Omitiendo %s: el video es %s (%s)\n",
+		"Playing %s\n":                                   "Reproduciendo %s\n",
+		"Playback from %s failed\n":                      "Falló la reproducción desde %s\n",
+		"Opening %s in the browser\n":                    "Abriendo %s en el navegador\n",
+		"Playback failed: %s > ":                         "Falló la reproducción: %s > ",
+		"Downloading %s\n":                               "Descargando %s\n",
+		"failed to download %s: %s\n":                    "no se pudo descargar %s: %s\n",
+		"Deleting %s (%s)\n":                             "Eliminando %s (%s)\n",
+		"Downloads: %d videos, %s in %s\n":               "Descargas: %d videos, %s en %s\n",
+		"Download size limit: %s\n":                      "Límite de tamaño de descargas: %s\n",
+		"Imported metadata for %d videos, %d new downloads, %d marked as watched\n": "Metadatos importados de %d videos, %d descargas nuevas, %d marcados como vistos\n",
+		"Already subscribed to %s\n":                                 "Ya estás suscrito a %s\n",
+		"Subscribed to %s\n":                                         "Suscrito a %s\n",
+		"Subscriptions are in sync\n":                                "Las suscripciones están sincronizadas\n",
+		"Only in account export (%d):\n":                             "Solo en la exportación de la cuenta (%d):\n",
+		"Only in local subscriptions (%d):\n":                        "Solo en las suscripciones locales (%d):\n",
+		"unknown channel":                                            "canal desconocido",
+		"Subscribe to %s locally?":                                   "¿Suscribirse a %s localmente?",
+		"Unsubscribe from %s locally?":                               "¿Cancelar la suscripción a %s localmente?",
+		"Subscribed to %d channels, unsubscribed from %d channels\n": "Suscrito a %d canales, cancelada la suscripción a %d canales\n",
+		"%s [y/N] ":        "%s [s/N] ",
+		"y":                "s",
+		"yes":              "sí",
+		"Undid %s (%s)\n":  "Se deshizo %s (%s)\n",
+		"Moved %s to %s\n": "Se movió %s a %s\n",
+		"Enter the numbers of the videos, optionally preceded by an action (%s). Leave empty to quit.\n": "Introduce los números de los videos, opcionalmente precedidos de una acción (%s). Déjalo vacío para salir.\n",
+		"The cache is corrupted (%s), starting with an empty cache\n":                                    "La caché está dañada (%s), se empieza con una caché vacía\n",
+		"Marked %d videos as watched, %d of them in the feed cache\n":                                    "%d videos marcados como vistos, %d de ellos en la caché de feeds\n",
+		"Replaying: yt-rss %s\n": "Reproduciendo de nuevo: yt-rss %s\n",
+		"No videos match %s\n":   "Ningún video coincide con %s\n",
+		"%d new videos\n":        "%d videos nuevos\n",
+		"%d new videos":          "%d videos nuevos",
+		"and %d more":            "y %d más",
+		"%d videos watched in the past year. Less %s More\n":               "%d videos vistos en el último año. Menos %s Más\n",
+		"Using feeds from the daemon\n":                                    "Usando los feeds del daemon\n",
+		"the daemon isn't running":                                         "el daemon no está en ejecución",
+		"Daemon running (pid %s) since %s\n":                               "Daemon en ejecución (pid %s) desde %s\n",
+		"Refreshing the feeds for the first time\n":                        "Actualizando los feeds por primera vez\n",
+		"%d videos, last refreshed at %s, next check at %s\n":              "%d videos, última actualización a las %s, próxima comprobación a las %s\n",
+		"Last refresh failed: %s\n":                                        "Falló la última actualización: %s\n",
+		"Fetching metadata for %d more videos in the background\n":         "Obteniendo los metadatos de %d vídeos más en segundo plano\n",
+		"failed   %s: %s\n":                                                "error    %s: %s\n",
+		"skipped  %s (already subscribed)\n":                               "omitido  %s (ya suscrito)\n",
+		"ok       %s (%s)\n":                                               "ok       %s (%s)\n",
+		"Validating channels":                                              "Validando canales",
+		"import aborted, no subscriptions were changed":                    "importación cancelada, no se cambió ninguna suscripción",
+		"%d channels can be imported, %d already subscribed, %d failed\n":  "Se pueden importar %d canales, %d ya suscritos, %d con errores\n",
+		"Subscribed to %d channels, %d already subscribed, %d failed\n":    "Suscrito a %d canales, %d ya suscritos, %d con errores\n",
+		"Serving the web UI at http://%s\n":                                "Sirviendo la interfaz web en http://%s\n",
+		"Imported %d cached videos and %d watch history records into %s\n": "Se importaron %d vídeos en caché y %d registros del historial en %s\n",
+		"just now":                      "ahora mismo",
+		"in %s":                         "en %s",
+		"%s ago":                        "hace %s",
+		"Failed to fetch %s after %s\n": "Error al obtener %s tras %s\n",
+		"Fetched %s in %s, %d videos\n": "%s obtenido en %s, %d vídeos\n",
+		"failed to get video duration for %s (%s): %s\n": "no se pudo obtener la duración del vídeo %s (%s): %s\n",
+		"failed to check if %s is a short (%s): %s\n":    "no se pudo comprobar si %s es un Short (%s): %s\n",
+		"Loading...": "Cargando...",
+		"%d feeds: %d ok, %d stale, %d renamed, %d dead, %d failed\n": "%d feeds: %d bien, %d inactivos, %d renombrados, %d eliminados, %d con errores\n",
+		"%d feeds are dead or failing":                                "%d feeds están eliminados o fallan",
+		"Skipping %s, which isn't a channel\n":                        "Se omite %s, que no es un canal\n",
+		"failed to get metadata for %s (%s) from %s: %s\n":            "no se pudieron obtener los metadatos de %s (%s) de %s: %s\n",
+		"failed to get sponsor segments for %s (%s): %s\n":            "no se pudieron obtener los segmentos de patrocinio de %s (%s): %s\n",
+		"Uncategorized":                                           "Sin categoría",
+		"Added %d videos to watch later\n":                        "%d vídeos añadidos a «Ver más tarde»\n",
+		"Removed %d videos from watch later\n":                    "%d vídeos eliminados de «Ver más tarde»\n",
+		"Nothing to watch later\n":                                "Nada en «Ver más tarde»\n",
+		"Added %d videos to the queue\n":                          "%d vídeos añadidos a la cola\n",
+		"Removed %d videos from the queue\n":                      "%d vídeos eliminados de la cola\n",
+		"The queue is empty\n":                                    "La cola está vacía\n",
+		"Muted %s until %s\n":                                     "%s silenciado hasta %s\n",
+		"Muted %s\n":                                              "%s silenciado\n",
+		"Unmuted %s\n":                                            "%s ya no está silenciado\n",
+		"No muted channels\n":                                     "No hay canales silenciados\n",
+		"until unmuted":                                           "hasta que se reactive",
+		"until %s":                                                "hasta %s",
+		"%s in %s has %s, using its update date\n":                "%s en %s tiene %s, se usa su fecha de actualización\n",
+		"%s in %s has %s\n":                                       "%s en %s tiene %s\n",
+		"failed to save metadata checkpoint: %s\n":                "no se pudo guardar el progreso de los metadatos: %s\n",
+		"Saving metadata of %d videos\n":                          "Guardando los metadatos de %d vídeos\n",
+		"Fetching feeds":                                          "Obteniendo feeds",
+		"Adding metadata":                                         "Añadiendo metadatos",
+		"%s %d/%d, %d failed":                                     "%s %d/%d, %d fallidos",
+		"%d feeds, %d failed, %d entries, %d new in %s\n":         "%d feeds, %d fallidos, %d entradas, %d nuevas en %s\n",
+		"%d errors, %d warnings":                                  "%d errores, %d advertencias",
+		"No errors, %d warnings\n":                                "Sin errores, %d advertencias\n",
+		"No problems found\n":                                     "No se encontraron problemas\n",
+		"Edit again?":                                             "¿Editar de nuevo?",
+		"%s has errors":                                           "%s tiene errores",
+		"No watched videos\n":                                     "No hay vídeos vistos\n",
+		"Cache: %d videos from %d channels, %d watched\n":         "Caché: %d vídeos de %d canales, %d vistos\n",
+		"History: %d videos watched, %d plays\n":                  "Historial: %d vídeos vistos, %d reproducciones\n",
+		"Channel\tUploads/week\tAvg length\tWatched\tLast upload": "Canal\tSubidas/semana\tDuración media\tVistos\tÚltima subida",
+		"Most watched channels:\n":                                "Canales más vistos:\n",
+		"%d videos":                                               "%d vídeos",
+		"Copied %d URLs to the clipboard\n":                       "%d URL copiadas al portapapeles\n",
+		"Interrupted after %d of %d feeds, saving what was fetched\n":                      "Interrumpido tras %d de %d feeds, guardando lo descargado\n",
+		"Got the duration of %s in %s\n":                                                   "Duración de %s obtenida en %s\n",
+		"Got the duration of %s from the innertube API, since the watch page failed: %s\n": "Duración de %s obtenida de la API innertube, porque la página del vídeo falló: %s\n",
+		"Ignoring the invalid title_rewrite %s: %s\n":                                      "Se ignora el title_rewrite no válido %s: %s\n",
+		"Ignoring the invalid rewrite rule for %s: %s\n":                                   "Se ignora la regla rewrite no válida de %s: %s\n",
+		"no videos": "sin vídeos",
+		"%d subscriptions haven't uploaded in %s\n":                                                   "%d suscripciones no han subido nada en %s\n",
+		"%d subscriptions haven't uploaded in %s, list them with `yt-rss subscriptions --stale %s`\n": "%d suscripciones no han subido nada en %s, lístalas con `yt-rss subscriptions --stale %s`\n",
+	},
+}
+
+// setupLanguage selects the language of user-facing messages. The language
+// is taken from UILanguage (the --lang flag or the language setting), then
+// from the locale environment variables. Unsupported languages fall back to
+// English.
+func (a *App) setupLanguage() error {
+	for tag, messages := range translations {
+		for key, msg := range messages {
+			err := message.SetString(tag, key, msg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	lang := a.UILanguage
+	if lang == "" {
+		lang = detectLocale()
+	}
+	supported := []language.Tag{language.English}
+	for tag := range translations {
+		supported = append(supported, tag)
+	}
+	tag, _ := language.MatchStrings(language.NewMatcher(supported), lang)
+	base, _ := tag.Base()
+	a.language = language.Make(base.String())
+	a.printer = message.NewPrinter(a.language)
+	a.logger.printer = a.printer
+	return nil
+}
+
+// detectLocale returns the language of the locale set in the environment,
+// e.g. "de-DE" for LANG=de_DE.UTF-8.
+func detectLocale() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		// Strip the codeset and modifier, e.g. de_DE.UTF-8@euro
+		v, _, _ = strings.Cut(v, ".")
+		v, _, _ = strings.Cut(v, "@")
+		if v == "C" || v == "POSIX" {
+			return "en"
+		}
+		return strings.ReplaceAll(v, "_", "-")
+	}
+	return ""
+}
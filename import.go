@@ -0,0 +1,177 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func (a *App) runImport(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: yt-rss import <watch-history|info-json|subscriptions|takeout|newpipe|freetube> <file or directory>")
+	}
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "watch-history":
+		if len(args) != 2 {
+			return errors.New("usage: yt-rss import watch-history <watch-history.json|takeout.zip>")
+		}
+		return a.importWatchHistory(args[1])
+	case "info-json":
+		return a.importInfoJSON(args[1:])
+	case "subscriptions":
+		return a.importSubscriptions(args[1:])
+	case "takeout":
+		return a.importTakeout(args[1:])
+	case "newpipe":
+		return a.importNewPipe(args[1:])
+	case "freetube":
+		return a.importFreeTube(args[1:])
+	default:
+		return fmt.Errorf("unknown import type: %s", args[0])
+	}
+}
+
+// takeoutWatchEvent is an item in the watch-history.json file exported by
+// Google Takeout.
+type takeoutWatchEvent struct {
+	Title     string `json:"title"`
+	TitleURL  string `json:"titleUrl"`
+	Subtitles []struct {
+		Name string `json:"name"`
+	} `json:"subtitles"`
+	Time time.Time `json:"time"`
+}
+
+// importWatchHistory marks every video in a Google Takeout watch history as
+// watched, so that videos already seen on YouTube aren't shown again.
+func (a *App) importWatchHistory(fileName string) error {
+	events, err := readTakeoutWatchHistory(fileName)
+	if err != nil {
+		return err
+	}
+
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	var changed []string
+	for _, v := range events {
+		videoID := parseVideoIDFromURL(v.TitleURL)
+		if videoID == "" || history.IsWatched(videoID) {
+			// Not a video (e.g. a removed video or a post), or
+			// already marked as watched.
+			continue
+		}
+		record, ok := history[videoID]
+		if !ok {
+			record = &WatchRecord{
+				VideoID: videoID,
+				Title:   strings.TrimPrefix(v.Title, "Watched "),
+			}
+			if len(v.Subtitles) > 0 {
+				record.Channel = v.Subtitles[0].Name
+			}
+			history[videoID] = record
+		}
+		record.WatchedAt = v.Time
+		changed = append(changed, videoID)
+	}
+
+	err = a.saveHistoryWithUndo(history, changed, fmt.Sprintf("import %d videos from watch history", len(changed)))
+	if err != nil {
+		return err
+	}
+
+	// Videos that aren't in the cache yet are still marked, so that they
+	// are hidden once their feed is fetched.
+	cache, err := a.loadCache()
+	if err != nil {
+		return err
+	}
+	cached := make(map[string]bool)
+	for _, v := range cache.FeedEntries {
+		cached[v.YTVideoID] = true
+	}
+	var inCache int
+	for _, v := range changed {
+		if cached[v] {
+			inCache++
+		}
+	}
+	a.printer.Fprintf(os.Stderr, "Marked %d videos as watched, %d of them in the feed cache\n", len(changed), inCache)
+	return nil
+}
+
+// readTakeoutWatchHistory parses Google Takeout's watch-history.json. Like
+// subscriptions.csv, it can also be read straight from the Takeout archive.
+// Takeout exports the history as HTML unless JSON is chosen in the export's
+// options, and only JSON is supported.
+func readTakeoutWatchHistory(fileName string) ([]takeoutWatchEvent, error) {
+	var b []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".html":
+		return nil, errors.New("the watch history must be exported as JSON: choose JSON as the history's format in Takeout's options")
+	case ".zip":
+		b, err = readTakeoutArchiveFile(fileName, "watch-history.json")
+	default:
+		b, err = ioutil.ReadFile(fileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var events []takeoutWatchEvent
+	err = json.Unmarshal(b, &events)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse watch history")
+	}
+	return events, nil
+}
+
+// readTakeoutArchiveFile returns the contents of the named file in a Takeout
+// .zip archive. See readTakeoutArchiveSubscriptions.
+func readTakeoutArchiveFile(fileName string, name string) ([]byte, error) {
+	archive, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, errors.Wrap(err, "open takeout archive")
+	}
+	defer archive.Close()
+	for _, f := range archive.File {
+		if path.Base(f.Name) != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "open %s", f.Name)
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+	return nil, fmt.Errorf("no %s in %s", name, fileName)
+}
+
+// parseVideoIDFromURL extracts the video ID from a YouTube watch URL, e.g.
+// https://www.youtube.com/watch?v=dQw4w9WgXcQ. An empty string is returned if
+// the URL is not a watch URL.
+func parseVideoIDFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if u.Path != "/watch" {
+		return ""
+	}
+	return u.Query().Get("v")
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// importedSubscription is a channel in a bulk import, and the result of
+// validating it.
+type importedSubscription struct {
+	Input    string // The URL as given in the import file
+	Options  string // Options to add after the feed URL, e.g. "audio"
+	Category string // Category to add the feed to, unless one is given with --category
+	Title    string
+	FeedURL  string
+	Err      error
+}
+
+// readSubscriptionList reads the channels to import. Files ending in .csv or
+// .zip are read as Google Takeout's subscriptions.csv, or the archive with it. Other files have a URL per line,
+// in any format `yt-rss subscribe` accepts, optionally followed by options as
+// in the URLs file. Blank lines and comments are ignored.
+func readSubscriptionList(fileName string) ([]*importedSubscription, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv", ".zip":
+		return readTakeoutSubscriptionList(fileName)
+	}
+
+	var subscriptions []*importedSubscription
+
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		input, options, _ := strings.Cut(line, " ")
+		subscriptions = append(subscriptions, &importedSubscription{
+			Input:   input,
+			Options: strings.TrimSpace(options),
+		})
+	}
+	return subscriptions, nil
+}
+
+// readTakeoutSubscriptionList reads the channels to import from Google
+// Takeout's subscriptions.csv, or the Takeout archive with it. Channels are
+// converted to feed URLs by their IDs, falling back to their URLs.
+func readTakeoutSubscriptionList(fileName string) ([]*importedSubscription, error) {
+	exported, err := readTakeoutSubscriptions(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var subscriptions []*importedSubscription
+	for _, v := range exported {
+		input := v.URL
+		if channelIDRegex.MatchString(v.ChannelID) {
+			input = channelFeedURL(v.ChannelID)
+		}
+		subscriptions = append(subscriptions, &importedSubscription{Input: input, Title: v.Title})
+	}
+	return subscriptions, nil
+}
+
+// validateImportedSubscription resolves the subscription's feed URL, and
+// fetches the feed to check that it exists.
+func (a *App) validateImportedSubscription(subscription *importedSubscription) {
+	feedURL, err := a.resolveFeedURL(subscription.Input)
+	if err != nil {
+		subscription.Err = err
+		return
+	}
+	subscription.FeedURL = feedURL
+	feed, err := a.getFeed(feedURL)
+	if err != nil {
+		subscription.Err = err
+		return
+	}
+	if subscription.Title == "" {
+		subscription.Title = feed.Title
+	}
+}
+
+// importSubscriptions subscribes to every channel in a file. Each channel is
+// validated by fetching its feed first, and channels that fail are reported
+// and left out. The URLs file is only written once every channel has been
+// checked, so an import that is interrupted or fails midway leaves the
+// subscriptions as they were. A completed import can be reverted with
+// `yt-rss undo`.
+func (a *App) importSubscriptions(args []string) error {
+	return a.runSubscriptionImport("subscriptions", "<file>", args, readSubscriptionList)
+}
+
+// importTakeout subscribes to every channel in Google Takeout's
+// subscriptions.csv, whatever the file is named, or in the Takeout archive
+// with it, like importSubscriptions.
+func (a *App) importTakeout(args []string) error {
+	return a.runSubscriptionImport("takeout", "<subscriptions.csv or takeout.zip>", args, readTakeoutSubscriptionList)
+}
+
+// importNewPipe subscribes to every YouTube channel in NewPipe's
+// subscriptions export, like importSubscriptions.
+func (a *App) importNewPipe(args []string) error {
+	return a.runSubscriptionImport("newpipe", "<subscriptions.json>", args, readNewPipeSubscriptionList)
+}
+
+// importFreeTube subscribes to every channel in FreeTube's subscriptions
+// export, like importSubscriptions.
+func (a *App) importFreeTube(args []string) error {
+	return a.runSubscriptionImport("freetube", "<subscriptions.db>", args, readFreeTubeSubscriptionList)
+}
+
+// runSubscriptionImport runs the `import <name>` command, importing the
+// channels read from the file by read.
+func (a *App) runSubscriptionImport(name, fileArg string, args []string, read func(fileName string) ([]*importedSubscription, error)) error {
+	fs := flag.NewFlagSet("import "+name, flag.ExitOnError)
+	category := fs.String("category", "", "Add the imported feeds to this category")
+	dryRun := fs.Bool("dry-run", false, "Only validate the channels, without subscribing to them")
+	fs.StringVar(&a.progressFormat, "progress", progressFormatBar, "How to report progress: bar, json for JSON lines on stderr, or none")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: yt-rss import %s [--category <name>] [--dry-run] %s", name, fileArg)
+	}
+	if a.progressFormat != progressFormatBar && a.progressFormat != progressFormatJSON && a.progressFormat != progressFormatNone {
+		return fmt.Errorf("unknown progress format: %s", a.progressFormat)
+	}
+
+	subscriptions, err := read(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	existing, err := a.getSubscriptions()
+	if err != nil {
+		return err
+	}
+	subscribed := make(map[string]bool)
+	for _, v := range existing {
+		subscribed[v.URL] = true
+	}
+
+	aborted := a.validateImportedSubscriptions(subscriptions)
+	if aborted {
+		return errors.New(a.printer.Sprintf("import aborted, no subscriptions were changed"))
+	}
+
+	var added []*importedSubscription
+	failed := 0
+	for _, v := range subscriptions {
+		switch {
+		case v.Err != nil:
+			failed++
+			a.printer.Printf("failed   %s: %s\n", v.Input, v.Err)
+		case subscribed[v.FeedURL]:
+			a.printer.Printf("skipped  %s (already subscribed)\n", v.Title)
+		default:
+			subscribed[v.FeedURL] = true
+			added = append(added, v)
+			a.printer.Printf("ok       %s (%s)\n", v.Title, v.FeedURL)
+		}
+	}
+
+	if len(added) > 0 && !*dryRun {
+		lines, err := a.readURLsFile()
+		if err != nil {
+			return err
+		}
+		for _, v := range added {
+			line := v.FeedURL
+			if v.Options != "" {
+				line += " " + v.Options
+			}
+			feedCategory := *category
+			if feedCategory == "" {
+				feedCategory = v.Category
+			}
+			lines = insertSubscription(lines, line, feedCategory)
+		}
+		err = a.writeURLsFileWithUndo(lines, fmt.Sprintf("import %d subscriptions", len(added)))
+		if err != nil {
+			return err
+		}
+	}
+
+	if *dryRun {
+		a.printer.Fprintf(os.Stderr, "%d channels can be imported, %d already subscribed, %d failed\n", len(added), len(subscriptions)-len(added)-failed, failed)
+	} else {
+		a.printer.Fprintf(os.Stderr, "Subscribed to %d channels, %d already subscribed, %d failed\n", len(added), len(subscriptions)-len(added)-failed, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d channels couldn't be imported", failed, len(subscriptions))
+	}
+	return nil
+}
+
+// validateImportedSubscriptions validates the subscriptions concurrently,
+// reporting the progress of each. It returns true if the import was
+// interrupted, in which case the remaining subscriptions aren't validated.
+func (a *App) validateImportedSubscriptions(subscriptions []*importedSubscription) (aborted bool) {
+	concurrency := max(a.FetchConcurrency, 1)
+	progress := a.newProgressReporter("import", a.printer.Sprintf("Validating channels"), len(subscriptions))
+
+	ch := make(chan *importedSubscription)
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range ch {
+				progress.Start(v.Input)
+				a.validateImportedSubscription(v)
+				progress.Finish(v.Input, v.Err)
+			}
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		defer close(ch)
+		for _, v := range subscriptions {
+			select {
+			case ch <- v:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		progress.Close()
+		return false
+	case <-signals:
+		// Requests in flight are abandoned rather than waited for,
+		// since they can take up to the HTTP timeout.
+		close(stop)
+		fmt.Fprintln(os.Stderr)
+		return true
+	}
+}
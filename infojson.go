@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ytdlpInfo is the subset of the .info.json files written by yt-dlp's
+// --write-info-json that yt-rss uses.
+type ytdlpInfo struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Uploader    string  `json:"uploader"`
+	Duration    float64 `json:"duration"` // Seconds
+	Description string  `json:"description"`
+}
+
+// VideoMetadata is metadata about a video that is known without fetching its
+// watch page, e.g. from yt-dlp's info JSON files. It is applied to entries
+// when they are fetched, so their pages don't need to be fetched.
+type VideoMetadata struct {
+	Duration    time.Duration `json:"duration"`
+	Description string        `json:"description,omitempty"`
+}
+
+// applyKnownMetadata fills in the entries' durations and descriptions from
+// the known metadata, where they are missing.
+func applyKnownMetadata(entries []FeedEntry, known map[string]VideoMetadata) {
+	for i := range entries {
+		metadata, ok := known[entries[i].YTVideoID]
+		if !ok {
+			continue
+		}
+		if entries[i].ExtraMetadata.VideoDuration == 0 {
+			entries[i].ExtraMetadata.VideoDuration = metadata.Duration
+		}
+		if entries[i].MediaGroup.Description == "" {
+			entries[i].MediaGroup.Description = metadata.Description
+		}
+	}
+}
+
+// importInfoJSON imports the metadata in the .info.json files under dir,
+// written by yt-dlp's --write-info-json. Durations and descriptions are
+// stored in the cache, and videos whose media file is next to the info file
+// are recorded as downloaded. If markWatched is true, the videos are also
+// marked as watched.
+func (a *App) importInfoJSON(args []string) error {
+	flags := flag.NewFlagSet("import info-json", flag.ExitOnError)
+	markWatched := flags.Bool("watched", false, "Also mark the imported videos as watched")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: yt-rss import info-json [--watched] <directory>")
+	}
+
+	cache, err := a.loadCache()
+	if err != nil {
+		return err
+	}
+	downloads, err := a.loadDownloads()
+	if err != nil {
+		return err
+	}
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	if cache.KnownMetadata == nil {
+		cache.KnownMetadata = make(map[string]VideoMetadata)
+	}
+
+	var imported, downloaded int
+	var watched []string
+	err = filepath.WalkDir(flags.Arg(0), func(fileName string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(fileName, ".info.json") {
+			return nil
+		}
+		b, err := os.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		var info ytdlpInfo
+		err = json.Unmarshal(b, &info)
+		if err != nil || info.ID == "" {
+			// Playlist or malformed info files
+			return nil
+		}
+		imported++
+		cache.KnownMetadata[info.ID] = VideoMetadata{
+			Duration:    time.Duration(info.Duration * float64(time.Second)),
+			Description: info.Description,
+		}
+
+		if mediaFile, ok := findMediaFile(strings.TrimSuffix(fileName, ".info.json")); ok {
+			if _, ok := downloads[info.ID]; !ok {
+				downloads[info.ID] = &DownloadRecord{
+					VideoID:      info.ID,
+					Title:        info.Title,
+					Channel:      info.Uploader,
+					Path:         mediaFile,
+					DownloadedAt: time.Now(),
+				}
+				downloaded++
+			}
+		}
+
+		if *markWatched && !history.IsWatched(info.ID) {
+			entry := FeedEntry{YTVideoID: info.ID}
+			entry.MediaGroup.Title = info.Title
+			entry.Author.Name = info.Uploader
+			history.getOrCreateRecord(entry).WatchedAt = time.Now()
+			watched = append(watched, info.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	applyKnownMetadata(cache.FeedEntries, cache.KnownMetadata)
+	err = a.writeToCache(cache)
+	if err != nil {
+		return err
+	}
+	err = a.saveDownloads(downloads)
+	if err != nil {
+		return err
+	}
+	if len(watched) > 0 {
+		err = a.saveHistoryWithUndo(history, watched, fmt.Sprintf("import %d videos from yt-dlp info files", len(watched)))
+		if err != nil {
+			return err
+		}
+	}
+	a.printer.Fprintf(os.Stderr, "Imported metadata for %d videos, %d new downloads, %d marked as watched\n", imported, downloaded, len(watched))
+	return nil
+}
+
+// findMediaFile returns the media file with the given path and any extension
+// other than the info file's, e.g. "video.mkv" for "video".
+func findMediaFile(base string) (string, bool) {
+	matches, _ := filepath.Glob(globEscape(base) + ".*")
+	for _, v := range matches {
+		ext := filepath.Ext(v)
+		if strings.HasSuffix(v, ".info.json") || ext == ".part" || ext == ".vtt" || ext == ".srt" || ext == ".jpg" || ext == ".webp" || ext == ".png" {
+			continue
+		}
+		if abs, err := filepath.Abs(v); err == nil {
+			return abs, true
+		}
+	}
+	return "", false
+}
+
+// globEscape escapes the glob metacharacters in s, since yt-dlp's file names
+// often contain brackets, e.g. "title [id].info.json".
+func globEscape(s string) string {
+	replacer := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`, `\`, `\\`)
+	return replacer.Replace(s)
+}
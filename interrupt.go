@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// errInterrupted is returned by a refresh that was interrupted with SIGINT or
+// SIGTERM, after what was fetched before the signal was saved. yt-rss then
+// exits with interruptedExitCode instead of printing it as an error.
+var errInterrupted = errors.New("interrupted")
+
+// interruptedExitCode is the conventional exit status of a program that was
+// interrupted: 128 + SIGINT.
+const interruptedExitCode = 130
+
+// cancelOnInterrupt sets a.ctx to a context that is cancelled by SIGINT or
+// SIGTERM, which aborts the requests in flight, so that the caller can save
+// what was fetched before the signal instead of losing it. A second signal
+// kills yt-rss right away, as usual. The returned function restores the
+// previous a.ctx once the caller is done.
+func (a *App) cancelOnInterrupt() (restore func()) {
+	previous := a.ctx
+	ctx, stop := signal.NotifyContext(previous, os.Interrupt, syscall.SIGTERM)
+	a.ctx = ctx
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return func() {
+		stop()
+		a.ctx = previous
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// recordLastUploads updates the time of the latest upload of each feed with
+// the entries. The times are kept in the cache even once the entries are
+// pruned, so that channels that stopped uploading can still be found.
+func (c *Cache) recordLastUploads(entries []FeedEntry) {
+	if c.FeedLastUpload == nil {
+		c.FeedLastUpload = make(map[string]time.Time)
+	}
+	for _, v := range entries {
+		published := v.GetPublishedDate()
+		feedURL := v.ExtraMetadata.FeedURL
+		if feedURL != "" && published.After(c.FeedLastUpload[feedURL]) {
+			c.FeedLastUpload[feedURL] = published
+		}
+	}
+}
+
+// feedUpload is a subscribed feed, with the time of its latest upload.
+type feedUpload struct {
+	URL        string
+	Name       string
+	LastUpload time.Time // Zero if the feed has been fetched, but had no videos
+}
+
+// getStaleFeeds returns the subscribed feeds that haven't had an upload
+// within staleAfter, the longest-inactive first. Feeds that haven't been
+// fetched yet are left out, since nothing is known about them. If staleAfter
+// is zero, every fetched feed is returned.
+func (a *App) getStaleFeeds(staleAfter time.Duration, now time.Time) ([]feedUpload, error) {
+	feedURLs, err := a.getFeedURLs()
+	if err != nil {
+		return nil, err
+	}
+	cache, err := a.loadCache()
+	if err != nil {
+		return nil, err
+	}
+	cache.recordLastUploads(cache.FeedEntries)
+	names, err := a.getCachedFeedNames()
+	if err != nil {
+		return nil, err
+	}
+	var stale []feedUpload
+	for _, v := range feedURLs {
+		lastUpload, ok := cache.FeedLastUpload[v]
+		if _, fetched := cache.FeedFetchedAt[v]; !ok && !fetched {
+			continue
+		}
+		if staleAfter > 0 && now.Sub(lastUpload) <= staleAfter {
+			continue
+		}
+		stale = append(stale, feedUpload{URL: v, Name: names[v], LastUpload: lastUpload})
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].LastUpload.Before(stale[j].LastUpload)
+	})
+	return stale, nil
+}
+
+// warnStaleFeeds logs how many subscriptions haven't uploaded within
+// StaleAfter, if it's set, after a refresh.
+func (a *App) warnStaleFeeds() {
+	if a.StaleAfter <= 0 {
+		return
+	}
+	stale, err := a.getStaleFeeds(a.StaleAfter, time.Now())
+	if err != nil || len(stale) == 0 {
+		return
+	}
+	a.logger.Warnf("%d subscriptions haven't uploaded in %s, list them with `yt-rss subscriptions --stale %s`\n", len(stale), formatDays(a.StaleAfter), formatDays(a.StaleAfter))
+}
+
+// formatDays formats a duration of whole days as e.g. "90d", and other
+// durations like time.Duration.
+func formatDays(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
+
+// runSubscriptions lists the subscribed feeds with their latest upload, from
+// the cache. With --stale, only the feeds that haven't uploaded within that
+// window are listed, to find dead subscriptions to prune.
+func (a *App) runSubscriptions(args []string) error {
+	fs := flag.NewFlagSet("subscriptions", flag.ExitOnError)
+	var staleAfter time.Duration
+	fs.Func("stale", "Only list subscriptions without uploads for longer than this, e.g. 90d", setDuration(&staleAfter))
+	fs.Parse(args)
+
+	now := time.Now()
+	feeds, err := a.getStaleFeeds(staleAfter, now)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, v := range feeds {
+		lastUpload := a.printer.Sprintf("no videos")
+		if !v.LastUpload.IsZero() {
+			lastUpload = a.formatRelativeTime(v.LastUpload, now)
+		}
+		name := v.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", lastUpload, name, v.URL)
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	if staleAfter > 0 {
+		a.printer.Fprintf(os.Stderr, "%d subscriptions haven't uploaded in %s\n", len(feeds), formatDays(staleAfter))
+	}
+	return nil
+}
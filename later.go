@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// watchLaterItem is an entry saved to the watch-later list. The entry is
+// copied into the state, so that it stays in the list after it has expired
+// from the cache.
+type watchLaterItem struct {
+	Entry   FeedEntry `json:"entry"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// InWatchLater returns true if the video is in the watch-later list.
+func (s *State) InWatchLater(videoID string) bool {
+	for _, v := range s.WatchLater {
+		if v.Entry.YTVideoID == videoID {
+			return true
+		}
+	}
+	return false
+}
+
+// AddToWatchLater adds the entry to the end of the watch-later list, unless it
+// is already in it. It returns false if it was.
+func (s *State) AddToWatchLater(entry FeedEntry) bool {
+	if s.InWatchLater(entry.YTVideoID) {
+		return false
+	}
+	s.WatchLater = append(s.WatchLater, watchLaterItem{Entry: entry, AddedAt: time.Now()})
+	return true
+}
+
+// RemoveFromWatchLater removes the video from the watch-later list. It
+// returns false if it wasn't in the list.
+func (s *State) RemoveFromWatchLater(videoID string) bool {
+	var kept []watchLaterItem
+	for _, v := range s.WatchLater {
+		if v.Entry.YTVideoID != videoID {
+			kept = append(kept, v)
+		}
+	}
+	removed := len(kept) != len(s.WatchLater)
+	s.WatchLater = kept
+	return removed
+}
+
+// WatchLaterEntries returns the entries in the watch-later list, in the order
+// they were added.
+func (s *State) WatchLaterEntries() []FeedEntry {
+	var entries []FeedEntry
+	for _, v := range s.WatchLater {
+		entries = append(entries, v.Entry)
+	}
+	return entries
+}
+
+// laterAction adds the entries to the watch-later list, or removes them if
+// they are all in it already, like pinAction.
+func (a *App) laterAction(entries []FeedEntry, state *State) (bool, error) {
+	allSaved := true
+	for _, entry := range entries {
+		allSaved = allSaved && state.InWatchLater(entry.YTVideoID)
+	}
+	for _, entry := range entries {
+		if allSaved {
+			state.RemoveFromWatchLater(entry.YTVideoID)
+		} else {
+			state.AddToWatchLater(entry)
+		}
+	}
+	if allSaved {
+		return true, a.saveStateWithUndo(state, fmt.Sprintf("remove %d entries from watch later", len(entries)))
+	}
+	return true, a.saveStateWithUndo(state, fmt.Sprintf("add %d entries to watch later", len(entries)))
+}
+
+// findWatchLaterEntry returns the entry in the watch-later list, for videos
+// that are no longer in the cache.
+func (a *App) findWatchLaterEntry(videoID string) (FeedEntry, bool, error) {
+	state, err := a.loadState()
+	if err != nil {
+		return FeedEntry{}, false, err
+	}
+	for _, v := range state.WatchLater {
+		if v.Entry.YTVideoID == videoID {
+			return v.Entry, true, nil
+		}
+	}
+	return FeedEntry{}, false, nil
+}
+
+// runLater manages the watch-later list: `later add <video>...` saves videos
+// from the cache, `later remove <video>...` removes them, `later list` prints
+// the list, and `later play` opens it in the picker, or plays the given
+// videos. Videos are given by ID or watch URL.
+func (a *App) runLater(args []string) error {
+	usage := errors.New("usage: yt-rss later <add|remove|list|play> [video ID or URL...]")
+	if len(args) < 1 {
+		return usage
+	}
+	videoIDs := make([]string, 0, len(args)-1)
+	for _, v := range args[1:] {
+		if id := parseVideoIDFromURL(v); id != "" {
+			v = id
+		}
+		videoIDs = append(videoIDs, v)
+	}
+	state, err := a.loadState()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		if len(videoIDs) == 0 {
+			return usage
+		}
+		if err := a.checkWritable(); err != nil {
+			return err
+		}
+		entries, err := a.getFromCache()
+		if err != nil {
+			return err
+		}
+		added := 0
+		for _, videoID := range videoIDs {
+			entry, ok := findEntryByVideoID(entries, videoID)
+			if !ok {
+				return fmt.Errorf("video not found in cache: %s", videoID)
+			}
+			if state.AddToWatchLater(entry) {
+				added++
+			}
+		}
+		a.printer.Fprintf(os.Stderr, "Added %d videos to watch later\n", added)
+		return a.saveStateWithUndo(state, fmt.Sprintf("add %d entries to watch later", added))
+	case "remove":
+		if len(videoIDs) == 0 {
+			return usage
+		}
+		if err := a.checkWritable(); err != nil {
+			return err
+		}
+		removed := 0
+		for _, videoID := range videoIDs {
+			if state.RemoveFromWatchLater(videoID) {
+				removed++
+			}
+		}
+		a.printer.Fprintf(os.Stderr, "Removed %d videos from watch later\n", removed)
+		return a.saveStateWithUndo(state, fmt.Sprintf("remove %d entries from watch later", removed))
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, v := range state.WatchLater {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				v.Entry.YTVideoID,
+				a.formatRelativeTime(v.AddedAt, time.Now()),
+				a.formatEntryDuration(v.Entry),
+				v.Entry.Author.Name,
+				v.Entry.ExtraMetadata.NormalizedTitle,
+			)
+		}
+		return w.Flush()
+	case "play":
+		if len(videoIDs) > 0 {
+			var entries []FeedEntry
+			for _, videoID := range videoIDs {
+				entry, ok := findEntryByVideoID(state.WatchLaterEntries(), videoID)
+				if !ok {
+					return fmt.Errorf("video not in watch later: %s", videoID)
+				}
+				entries = append(entries, entry)
+			}
+			return a.playEntries(entries, state, a.AudioOnly)
+		}
+		if len(state.WatchLater) == 0 {
+			a.printer.Fprintf(os.Stderr, "Nothing to watch later\n")
+			return nil
+		}
+		a.watchLaterView = true
+		return a.selectAndRun(nil, "play")
+	default:
+		return usage
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Launcher picker backends, for browsing outside of a terminal. They read the
+// lines on stdin and print the selected line, like `fzf` without colors.
+const (
+	pickerRofi   = "rofi"
+	pickerDmenu  = "dmenu"
+	pickerWofi   = "wofi"
+	pickerFuzzel = "fuzzel"
+)
+
+var launcherPickers = []string{pickerRofi, pickerDmenu, pickerWofi, pickerFuzzel}
+
+func isLauncherPicker(backend string) bool {
+	return slices.Contains(launcherPickers, backend)
+}
+
+// rofiCustomKeyExitCode is the exit code of rofi when the first of its
+// custom keys is pressed. The Nth custom key exits with this plus N-1.
+const rofiCustomKeyExitCode = 10
+
+// launcherArgs returns the arguments to run the launcher as a menu with the
+// prompt. Only rofi supports selecting multiple lines; it prints the indices
+// of the selected lines instead of the lines themselves.
+func launcherArgs(backend, prompt string, multi bool) []string {
+	switch backend {
+	case pickerRofi:
+		args := []string{"-dmenu", "-i", "-p", prompt, "-format", "i"}
+		if multi {
+			args = append(args, "-multi-select")
+		}
+		return args
+	case pickerDmenu:
+		return []string{"-i", "-l", "20", "-p", prompt}
+	case pickerWofi:
+		return []string{"--dmenu", "--insensitive", "--prompt", prompt}
+	default:
+		return []string{"--dmenu", "--prompt", prompt + " "}
+	}
+}
+
+// runLauncher shows the options in the launcher, and returns the indices of
+// the selected options. exitCode is the launcher's exit code, which rofi uses
+// to report custom keys. No indices are returned if the launcher was
+// dismissed.
+func runLauncher(backend string, args []string, options []string) (indices []int, exitCode int, err error) {
+	// Launchers can't show colors
+	display := make([]string, len(options))
+	for i, v := range options {
+		display[i] = ansiEscapeRegex.ReplaceAllString(v, "")
+	}
+	b := &bytes.Buffer{}
+	err = runShellCommand(backend, args, strings.NewReader(strings.Join(display, "\n")), b)
+	if err != nil {
+		e, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, 0, errors.Wrapf(err, "run %s", backend)
+		}
+		exitCode = e.ExitCode()
+		if exitCode < rofiCustomKeyExitCode {
+			// Dismissed
+			return nil, exitCode, nil
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(b.String(), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if backend == pickerRofi {
+			i, err := strconv.Atoi(line)
+			if err != nil || i < 0 || i >= len(options) {
+				return nil, 0, fmt.Errorf("unexpected output from rofi: %s", line)
+			}
+			indices = append(indices, i)
+			continue
+		}
+		// The other launchers print the line itself. Input that
+		// doesn't match a line, e.g. a typed query, is ignored.
+		for i, v := range display {
+			if v == line {
+				indices = append(indices, i)
+				break
+			}
+		}
+	}
+	return indices, exitCode, nil
+}
+
+// runLauncherPicker is the launcher alternative to fzf in runPicker. Enter
+// runs the enter action. With rofi, the actions menu and the actions bound to
+// keys are on rofi's custom keys, alt+1, alt+2, and so on, since rofi's other
+// keys are already bound. The other launchers can't bind keys, so only the
+// enter action can be used.
+func (a *App) runLauncherPicker(fzfContent string, actions []pickerAction, query string) (newQuery string, key string, selections []string, err error) {
+	backend := a.getPickerBackend()
+	var displays []string
+	lines := splitPickerLines(fzfContent)
+	for _, line := range lines {
+		display := pickerLineDisplay(line)
+		displays = append(displays, display)
+	}
+
+	args := launcherArgs(backend, "yt-rss", true)
+	var customKeys []string // fzf keys of rofi's custom keys, in order
+	if backend == pickerRofi {
+		customKeys = append(customKeys, a.ActionsMenuKey)
+		help := []string{"enter: " + actionNameForKey(actions, "enter"), "alt+1: actions"}
+		for _, v := range actions {
+			if v.Key == "" || v.Key == "enter" {
+				continue
+			}
+			customKeys = append(customKeys, v.Key)
+			help = append(help, fmt.Sprintf("alt+%d: %s", len(customKeys), v.Name))
+		}
+		args = append(args, "-mesg", strings.Join(help, ", "))
+		if query != "" {
+			args = append(args, "-filter", query)
+		}
+	}
+
+	indices, exitCode, err := runLauncher(backend, args, displays)
+	if err != nil || len(indices) == 0 {
+		return query, "", nil, err
+	}
+	if n := exitCode - rofiCustomKeyExitCode; n >= 0 && n < len(customKeys) {
+		key = customKeys[n]
+	}
+	for _, i := range indices {
+		selections = append(selections, lines[i])
+	}
+	return query, key, selections, nil
+}
+
+// runLauncherMenu is the launcher alternative to fzf in runMenu.
+func (a *App) runLauncherMenu(prompt string, options []string) (selection string, ok bool, err error) {
+	backend := a.getPickerBackend()
+	indices, _, err := runLauncher(backend, launcherArgs(backend, strings.TrimSuffix(prompt, " > "), false), options)
+	if err != nil || len(indices) == 0 {
+		return "", false, err
+	}
+	return options[indices[0]], true, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// listTemplateFuncs are the functions available to --format templates, in
+// addition to the text/template builtins.
+var listTemplateFuncs = template.FuncMap{
+	"duration": formatDuration,
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+}
+
+// runList prints the feed entries to stdout instead of opening the picker,
+// for use in scripts. By default, entries are printed as aligned columns.
+// --format takes a Go template that is executed for each entry, e.g.
+// '{{.Author.Name}}: {{.MediaGroup.Title}}'.
+func (a *App) runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print entries as a JSON array")
+	all := fs.Bool("all", false, "Include entries that are hidden in the picker, e.g. watched videos and Shorts")
+	format := fs.String("format", "", "Go template to print each entry with, e.g. '{{.Author.Name}}: {{.MediaGroup.Title}} ({{duration .ExtraMetadata.VideoDuration}})'")
+	fs.StringVar(&a.selectedCategory, "category", "", "Only list videos from feeds in this category")
+	fs.BoolVar(&a.favoritesOnly, "favorites", false, "Only list videos from favorite channels")
+	a.addCacheFlags(fs)
+	a.addShuffleFlags(fs)
+	a.addSortFlags(fs)
+	a.addDurationFlags(fs)
+	fs.Parse(args)
+
+	var tmpl *template.Template
+	if *format != "" {
+		var err error
+		tmpl, err = template.New("format").Funcs(listTemplateFuncs).Parse(*format)
+		if err != nil {
+			return errors.Wrap(err, "parse format")
+		}
+	}
+
+	entries, err := a.loadFeedEntries()
+	if err != nil {
+		return err
+	}
+	entries = a.applyShuffle(fs, entries)
+	entries, err = a.applySort(fs, entries)
+	if err != nil {
+		return err
+	}
+	if !*all {
+		history, err := a.loadHistory()
+		if err != nil {
+			return err
+		}
+		entries, err = a.getVisibleEntries(entries, history)
+		if err != nil {
+			return err
+		}
+	}
+	if a.favoritesOnly {
+		favorites, err := a.getFavoriteFeeds()
+		if err != nil {
+			return err
+		}
+		entries = filterFavorites(entries, favorites)
+	}
+
+	if *asJSON {
+		if entries == nil {
+			entries = []FeedEntry{} // Print [] rather than null
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if tmpl != nil {
+		for _, v := range entries {
+			err := tmpl.Execute(os.Stdout, v)
+			if err != nil {
+				return errors.Wrap(err, "execute format")
+			}
+			fmt.Println()
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	now := time.Now()
+	for _, v := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			a.formatDate(v.GetPublishedDate(), now),
+			a.formatEntryDuration(v),
+			v.Author.Name,
+			v.ExtraMetadata.NormalizedTitle,
+		)
+	}
+	return w.Flush()
+}
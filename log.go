@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/message"
+)
+
+// Log levels, from least to most verbose. Messages are written to stderr if
+// their level is at most the level set with log_level, -v, or -q.
+const (
+	logLevelError = iota
+	logLevelWarn
+	logLevelInfo  // Status messages, e.g. whether cached feeds are used
+	logLevelDebug // Timings of each feed and video page fetched
+)
+
+var logLevelNames = []string{"error", "warn", "info", "debug"}
+
+// leveledLogger writes diagnostic messages to stderr, and to the log file if
+// one is set. Messages are formatted with the printer, so that they are
+// translated like other messages.
+type leveledLogger struct {
+	mu      sync.Mutex
+	level   int
+	file    io.Writer // Receives messages of all levels, with timestamps. Nil if there's no log file.
+	printer *message.Printer
+
+	// holding is set while the refresh progress is displayed. Messages
+	// for stderr are held back until Release, so that they aren't drawn
+	// over the progress. The log file still gets them right away.
+	holding bool
+	held    []string
+}
+
+func (l *leveledLogger) logf(level int, format string, args ...any) {
+	if level > l.level && l.file == nil {
+		return
+	}
+	msg := strings.TrimSuffix(l.printer.Sprintf(format, args...), "\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level <= l.level {
+		if l.holding {
+			l.held = append(l.held, msg)
+		} else {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+	}
+	if l.file != nil {
+		fmt.Fprintf(l.file, "%s %-5s %s\n", time.Now().Format(time.RFC3339), strings.ToUpper(logLevelNames[level]), msg)
+	}
+}
+
+// Hold holds back messages for stderr until Release.
+func (l *leveledLogger) Hold() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.holding = true
+}
+
+// Release writes the messages held back since Hold to stderr, and stops
+// holding them.
+func (l *leveledLogger) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, msg := range l.held {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	l.holding = false
+	l.held = nil
+}
+
+func (l *leveledLogger) Errorf(format string, args ...any) { l.logf(logLevelError, format, args...) }
+func (l *leveledLogger) Warnf(format string, args ...any)  { l.logf(logLevelWarn, format, args...) }
+func (l *leveledLogger) Infof(format string, args ...any)  { l.logf(logLevelInfo, format, args...) }
+func (l *leveledLogger) Debugf(format string, args ...any) { l.logf(logLevelDebug, format, args...) }
+
+// setupLogging sets the log level from LogLevel, and opens LogFile. The log
+// file is appended to, and receives all messages regardless of the level, so
+// that failures can be diagnosed after the fact.
+func (a *App) setupLogging() error {
+	level := -1
+	for i, name := range logLevelNames {
+		if name == a.LogLevel {
+			level = i
+		}
+	}
+	if level < 0 {
+		return fmt.Errorf("unknown log level: %s (available: %s)", a.LogLevel, strings.Join(logLevelNames, ", "))
+	}
+	a.logger.level = level
+
+	if a.LogFile != "" {
+		f, err := os.OpenFile(a.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return errors.Wrap(err, "open log file")
+		}
+		// The file is left open until yt-rss exits.
+		a.logger.file = f
+	}
+	return nil
+}
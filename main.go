@@ -1,45 +1,34 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/xml"
+	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
-	"net/http"
+	"maps"
 	"os"
 	"os/exec"
-	"os/user"
-	"path"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
-	"unicode"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
 	"github.com/pkg/errors"
-	"github.com/schollz/progressbar/v3"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
-var configFile = getConfigFile()
 var hashtagRegex = regexp.MustCompile(`\B(\#[\w_-]+\b)`) // non-word boundary, hashtag, word boundary
 
-// Configuration
-var (
-	cacheDuration           = 30 * time.Minute
-	shortsThreshold         = 120 * time.Second // Duration to consider a video a YouTube Short
-	enableAuthorNamePadding = true              // Enables padding of author names to align the FZF output
-)
-
 type FeedEntry struct {
 	ID        string `xml:"id" json:"id"`
 	YTVideoID string `xml:"videoId" json:"yt_video_id"`
+	ChannelID string `xml:"channelId" json:"channel_id"`
 	Published string `xml:"published" json:"published"`
 	Updated   string `xml:"updated" json:"updated"`
 	Author    struct {
@@ -50,45 +39,114 @@ type FeedEntry struct {
 		Content struct {
 			URL string `xml:"url,attr" json:"url"`
 		} `xml:"content" json:"content"`
+		Thumbnail struct {
+			URL string `xml:"url,attr" json:"url"`
+		} `xml:"thumbnail" json:"thumbnail"`
+		Description string `xml:"description" json:"description"`
+		Community   struct {
+			StarRating struct {
+				Count int64 `xml:"count,attr" json:"count"` // The number of likes, since dislikes were made private
+			} `xml:"starRating" json:"star_rating"`
+			Statistics struct {
+				Views int64 `xml:"views,attr" json:"views"`
+			} `xml:"statistics" json:"statistics"`
+		} `xml:"community" json:"community"`
 	} `xml:"group" json:"media_group"`
 
 	// ExtraMetadata contains metadata not part of YouTube's RSS feed.
 	ExtraMetadata struct {
-		VideoDuration   time.Duration `json:"video_duration"`
-		NormalizedTitle string        `json:"normalized_title"`
+		VideoDuration   time.Duration     `json:"video_duration"`
+		NormalizedTitle string            `json:"normalized_title"`
+		FeedURL         string            `json:"feed_url"`                 // URL of the feed the entry was fetched from
+		IsShort         *bool             `json:"is_short"`                 // Nil if not checked yet
+		LiveStatus      string            `json:"live_status"`              // Empty for regular videos, otherwise one of the liveStatus constants
+		PlaylistTitle   string            `json:"playlist_title,omitempty"` // Title of the playlist, if the entry was fetched from a playlist feed
+		SponsorBlock    *sponsorBlockData `json:"sponsorblock,omitempty"`   // Nil if not fetched yet
 	} `json:"extra_metadata"`
 }
 
+// WatchURL returns the URL of the video's watch page on YouTube.
+func (e FeedEntry) WatchURL() string {
+	return "https://www.youtube.com/watch?v=" + e.YTVideoID
+}
+
+// Views returns the view count from the feed. It is zero for entries cached
+// before view counts were added, until their feed is refreshed.
+func (e FeedEntry) Views() int64 {
+	return e.MediaGroup.Community.Statistics.Views
+}
+
+// Likes returns the like count from the feed.
+func (e FeedEntry) Likes() int64 {
+	return e.MediaGroup.Community.StarRating.Count
+}
+
+// PublishedDate returns the entry's publish date, or an error if it couldn't
+// be parsed.
+func (e FeedEntry) PublishedDate() (time.Time, error) {
+	return parseFeedTime(e.Published)
+}
+
+// GetPublishedDate returns the entry's publish date, or the zero time if it
+// couldn't be parsed, which sorts last. Invalid dates are reported when the
+// feed is fetched, and in the preview pane.
 func (e FeedEntry) GetPublishedDate() time.Time {
-	t, _ := time.Parse(time.RFC3339, e.Published)
+	t, _ := e.PublishedDate()
 	return t
 }
 
+func findEntryByVideoID(entries []FeedEntry, videoID string) (FeedEntry, bool) {
+	for _, v := range entries {
+		if v.YTVideoID == videoID {
+			return v, true
+		}
+	}
+	return FeedEntry{}, false
+}
+
 type Feed struct {
-	XMLName xml.Name    `xml:"feed"`
+	XMLName    xml.Name `xml:"feed"`
+	Title      string   `xml:"title"`
+	PlaylistID string   `xml:"playlistId"` // Only set for playlist feeds
+	Author     struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
 	Entries []FeedEntry `xml:"entry"`
+	URL     string      `xml:"-"`
 }
 
-func getFeeds(feedURLs []string) ([]Feed, error) {
+// getFeeds fetches the feeds concurrently. onFeed, if not nil, is called
+// with each feed as soon as it has been fetched, from several goroutines.
+func (a *App) getFeeds(feedURLs []string, onFeed func(feed Feed)) ([]Feed, error) {
 	var feeds []Feed
-	concurrency := 10
+	var feedsMutex sync.Mutex
+	concurrency := max(a.FetchConcurrency, 1)
 
-	progressBar := progressbar.Default(
-		int64(len(feedURLs)),
-		"Fetching feeds",
-	)
+	progress := a.newProgressReporter("feeds", a.printer.Sprintf("Fetching feeds"), len(feedURLs))
 
-	worker := func(wg *sync.WaitGroup, ch <-chan string, errCh chan<- error, progressBar *progressbar.ProgressBar) {
+	worker := func(wg *sync.WaitGroup, ch <-chan string, errCh chan<- error, progress *progressReporter) {
 		defer wg.Done()
 		for feedURL := range ch {
-			feed, err := getFeed(feedURL)
+			progress.Start(feedURL)
+			start := time.Now()
+			feed, err := a.getFeed(feedURL)
+			progress.Finish(feedURL, err)
+			if err != nil && a.ctx.Err() != nil {
+				// Aborted by an interrupt, rather than failed
+				continue
+			}
 			if err != nil {
-				progressBar.Add(1)
-				errCh <- err
-				return
+				a.logger.Debugf("Failed to fetch %s after %s\n", feedURL, time.Since(start).Round(time.Millisecond))
+				errCh <- errors.Wrapf(err, "feed %s", feedURL)
+				continue
 			}
+			a.logger.Debugf("Fetched %s in %s, %d videos\n", feedURL, time.Since(start).Round(time.Millisecond), len(feed.Entries))
+			feedsMutex.Lock()
 			feeds = append(feeds, *feed)
-			progressBar.Add(1)
+			feedsMutex.Unlock()
+			if onFeed != nil {
+				onFeed(*feed)
+			}
 		}
 	}
 
@@ -99,215 +157,337 @@ func getFeeds(feedURLs []string) ([]Feed, error) {
 	// start workers
 	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
-		go worker(wg, ch, errCh, progressBar)
+		go worker(wg, ch, errCh, progress)
 	}
 
-	// Queue feed URLs
+	// Queue feed URLs, until interrupted
+queue:
 	for _, feedURL := range feedURLs {
-		ch <- feedURL
+		select {
+		case ch <- feedURL:
+		case <-a.ctx.Done():
+			break queue
+		}
 	}
 	close(ch)
 
 	// Wait for workers to finish
 	wg.Wait()
+	close(errCh)
+	progress.Close()
 
 	// Print errors, if any
-	if len(errCh) > 0 {
-		for err := range errCh {
-			fmt.Fprintln(os.Stderr, err)
-		}
+	for err := range errCh {
+		a.logger.Warnf("%s\n", err)
 	}
 
 	return feeds, nil
 }
 
-func getFeed(feedURL string) (*Feed, error) {
-	resp, err := http.Get(feedURL)
+func (a *App) getFeed(feedURL string) (*Feed, error) {
+	var feed *Feed
+	var err error
+	if _, _, pathErr := backendFeedPath(feedURL); a.Backend != backendYouTube && pathErr == nil {
+		feed, err = a.getBackendFeed(feedURL)
+	} else {
+		feed, err = a.fetchFeed(feedURL)
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "fetch feed")
+		return nil, err
+	}
+	feed.URL = feedURL
+	a.normalizeFeedTimes(feed)
+	for i := range feed.Entries {
+		feed.Entries[i].ExtraMetadata.FeedURL = feedURL
+		if feed.PlaylistID != "" {
+			// A playlist feed's author is the owner of the
+			// playlist, and each entry has the channel that
+			// uploaded the video.
+			feed.Entries[i].ExtraMetadata.PlaylistTitle = feed.Title
+		}
+		if feed.Entries[i].Author.Name == "" {
+			feed.Entries[i].Author.Name = feed.Author.Name
+		}
 	}
-	defer resp.Body.Close()
 
-	b, err := ioutil.ReadAll(resp.Body)
+	return feed, nil
+}
+
+func (a *App) fetchFeed(feedURL string) (*Feed, error) {
+	resp, err := a.httpGet(feedURL)
 	if err != nil {
-		return nil, errors.Wrap(err, "read response body")
+		return nil, errors.Wrap(err, "fetch feed")
 	}
+	defer resp.Body.Close()
 
 	feed := &Feed{}
-	err = xml.Unmarshal(b, feed)
+	err = xml.NewDecoder(a.limitBody(resp)).Decode(feed)
 	if err != nil {
-		return nil, errors.Wrap(err, "unmarshal response body")
+		return nil, errors.Wrap(err, "decode feed")
 	}
-
 	return feed, nil
 }
 
-func getFeedEntries(feeds []Feed, cachedFeedEntries []FeedEntry) []FeedEntry {
-	// Create a lookup for entries we've seen before, so we can avoid
-	// postprocessing them again later.
-	cachedEntryLookup := make(map[string]FeedEntry)
-	for _, v := range cachedFeedEntries {
-		cachedEntryLookup[v.ID] = v
+func (a *App) addMetadata(entry *FeedEntry) {
+	if a.Backend == backendYouTube {
+		a.addYouTubeMetadata(entry)
+	} else {
+		err := a.addBackendMetadata(entry)
+		if err != nil {
+			a.logger.Warnf("failed to get metadata for %s (%s) from %s: %s\n", entry.YTVideoID, entry.Author.Name, a.Backend, err)
+		}
 	}
+	a.addSponsorSegments(entry)
 
-	// Concat cached and new feed entries. Prioritize cached entries if
-	// there are duplicates, because the cached entries have additional
-	// metadata fetched already. Fetching metadata can be expensive, so we
-	// should avoid doing it where unnecessary.
-	var entries []FeedEntry
-	seen := make(map[string]bool)
-	for _, v := range cachedFeedEntries {
-		if _, ok := seen[v.ID]; !ok {
-			// Append if entry has not been added before
-			seen[v.ID] = true
-			entries = append(entries, v)
-		}
+	// Normalize titles
+	if entry.ExtraMetadata.NormalizedTitle == "" {
+		entry.ExtraMetadata.NormalizedTitle = a.normalizeTitle(*entry)
 	}
-	for _, feed := range feeds {
-		for _, v := range feed.Entries {
-			if _, ok := seen[v.ID]; !ok {
-				// Append if entry has not been added before
-				seen[v.ID] = true
-				entries = append(entries, v)
-			}
+}
+
+// addYouTubeMetadata adds the duration, live status, and whether the video is
+// a Short, from YouTube's pages.
+func (a *App) addYouTubeMetadata(entry *FeedEntry) {
+	// Add video duration and live status. Livestreams and premieres
+	// have no duration until they end, and their status changes over
+	// time, so they are checked again on every refresh.
+	if entry.ExtraMetadata.VideoDuration == 0 || entry.ExtraMetadata.LiveStatus != "" {
+		start := time.Now()
+		duration, liveStatus, err := a.getVideoDuration(*entry)
+		if err != nil {
+			a.logger.Warnf("failed to get video duration for %s (%s): %s\n", entry.MediaGroup.Content.URL, entry.Author.Name, err)
+		} else {
+			a.logger.Debugf("Got the duration of %s in %s\n", entry.YTVideoID, time.Since(start).Round(time.Millisecond))
+			entry.ExtraMetadata.LiveStatus = liveStatus
+			entry.ExtraMetadata.VideoDuration = duration
 		}
 	}
 
-	sort.SliceStable(entries, func(i, j int) bool {
-		return entries[i].GetPublishedDate().After(entries[j].GetPublishedDate())
-	})
-
-	entries = bulkAddMetadata(entries)
-
-	return entries
+	// Check if the video is a YouTube Short
+	if entry.ExtraMetadata.IsShort == nil {
+		isShort, err := a.checkIsShort(entry.YTVideoID)
+		if err != nil {
+			a.logger.Warnf("failed to check if %s is a short (%s): %s\n", entry.YTVideoID, entry.Author.Name, err)
+		} else {
+			entry.ExtraMetadata.IsShort = &isShort
+		}
+	}
 }
 
-func bulkAddMetadata(entries []FeedEntry) []FeedEntry {
-	concurrency := 10
-	progressBar := progressbar.Default(
-		int64(len(entries)),
-		"Adding metadata",
-	)
-
-	// Worker to add metadata to each entry.
-	// Note that the slice index is being passed instead of a pointer to
-	// each struct in the slice. Would prefer to do the latter, but I can't
-	// get it to work. This method is less ideal, but it works for now.
-	worker := func(wg *sync.WaitGroup, ch <-chan int, errCh chan<- error, progressBar *progressbar.ProgressBar) {
-		defer wg.Done()
-		for i := range ch {
-			addMetadata(&entries[i])
-			progressBar.Add(1)
+func (a *App) shouldFilterOutEntry(entry FeedEntry, filter *entryFilter) bool {
+	// Filter out YouTube Shorts. If the entry hasn't been checked for
+	// whether it's a Short, guess based on its duration.
+	if a.HideShorts {
+		if entry.ExtraMetadata.IsShort != nil {
+			if *entry.ExtraMetadata.IsShort {
+				return true
+			}
+		} else if entry.ExtraMetadata.VideoDuration > 0 && entry.ExtraMetadata.VideoDuration < a.ShortsThreshold {
+			return true
 		}
 	}
-
-	ch := make(chan int, concurrency)
-	errCh := make(chan error, len(entries))
-	wg := &sync.WaitGroup{}
-
-	// Start workers
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go worker(wg, ch, errCh, progressBar)
+	// Filter out livestreams and premieres
+	switch entry.ExtraMetadata.LiveStatus {
+	case liveStatusLive:
+		if a.HideLive {
+			return true
+		}
+	case liveStatusUpcoming, liveStatusPremiere:
+		if a.HideUpcoming {
+			return true
+		}
 	}
-
-	// Queue feed entries
-	for i := range entries {
-		i := i
-		ch <- i
+	// Filter out unpopular videos
+	if a.MinViews > 0 && entry.Views() < int64(a.MinViews) {
+		return true
 	}
-	close(ch)
-
-	// Wait for workers to finish
-	wg.Wait()
+	// Filter out old videos. Videos with an unknown publish date are kept.
+	if published := entry.GetPublishedDate(); a.MaxAge > 0 && !published.IsZero() && time.Since(published) > a.MaxAge {
+		return true
+	}
+	// Filter out entries hidden by title rules
+	if !filter.Allows(entry) {
+		return true
+	}
+	return false
+}
 
-	// Print errors, if any
-	if len(errCh) > 0 {
-		for err := range errCh {
-			fmt.Fprintln(os.Stderr, err)
+func (a *App) filterEntries(entries []FeedEntry, filter *entryFilter) []FeedEntry {
+	var filtered []FeedEntry
+	for _, v := range entries {
+		if !a.shouldFilterOutEntry(v, filter) {
+			filtered = append(filtered, v)
 		}
 	}
-	return entries
+	return filtered
 }
 
-func normalizeTitle(title string) string {
-	// Sentence case
-	caser := cases.Lower(language.English)
-	title = caser.String(title)
-	r := []rune(title)
-	r[0] = unicode.ToUpper(r[0])
-	title = string(r)
-
-	// Remove hashtags
-	title = hashtagRegex.ReplaceAllString(title, "")
-
-	return title
+// getVisibleEntries returns the entries that should be shown to the user,
+// i.e. excluding near-duplicates (if HideDuplicates is set), watched entries
+// (if HideWatched is set), and entries hidden by shouldFilterOutEntry.
+func (a *App) getVisibleEntries(entries []FeedEntry, history History) ([]FeedEntry, error) {
+	// Duplicates are hidden before watched entries, so that reuploads of
+	// watched videos stay hidden.
+	if a.HideDuplicates {
+		entries = dedupTitles(entries)
+	}
+	if a.HideWatched {
+		entries = filterWatched(entries, history)
+	}
+	filter, err := a.newEntryFilter()
+	if err != nil {
+		return nil, err
+	}
+	return a.filterEntries(entries, filter), nil
 }
 
-func addMetadata(entry *FeedEntry) {
-	// Add video duration
-	if entry.ExtraMetadata.VideoDuration == 0 {
-		duration, err := getVideoDuration(entry.MediaGroup.Content.URL)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get video duration for %s\n", entry.MediaGroup.Content.URL)
-			return
-		}
-		entry.ExtraMetadata.VideoDuration = duration
+// formatDuration formats a video duration as MM:SS, or H:MM:SS if it is an
+// hour or longer.
+func formatDuration(d time.Duration) string {
+	if d >= time.Hour {
+		return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
 	}
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
 
-	// Normalize titles
-	if entry.ExtraMetadata.NormalizedTitle == "" {
-		entry.ExtraMetadata.NormalizedTitle = normalizeTitle(entry.MediaGroup.Title)
+// formatCount formats a view or like count compactly, e.g. 1.2K or 3.4M.
+func formatCount(n int64) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprintf("%d", n)
+	case n < 1000000:
+		return fmt.Sprintf("%.1fK", float64(n)/1e3)
+	case n < 1000000000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	default:
+		return fmt.Sprintf("%.1fB", float64(n)/1e9)
 	}
-
-	return
 }
 
-func shouldFilterOutEntry(entry FeedEntry) bool {
-	// Filter out short videos
-	if entry.ExtraMetadata.VideoDuration > 0 && entry.ExtraMetadata.VideoDuration < shortsThreshold {
-		return true
+var faint = color.New(color.Faint).SprintFunc()
+
+// authorNameEllipsis ends author names truncated to AuthorNameMaxWidth.
+const authorNameEllipsis = "…"
+
+// formatAuthorName truncates the author name to AuthorNameMaxWidth, if set,
+// for the picker.
+func (a *App) formatAuthorName(name string) string {
+	if a.AuthorNameMaxWidth > 0 {
+		name = runewidth.Truncate(name, a.AuthorNameMaxWidth, authorNameEllipsis)
 	}
-	return false
+	// Tabs would split the picker's columns
+	return strings.ReplaceAll(name, "\t", " ")
 }
 
-func findLongestAuthorNameLength(entries []FeedEntry) int {
-	var maxLength int
+// findLongestAuthorNameWidth returns the display width of the longest author
+// name, in terminal cells. CJK characters and most emoji take two cells, so
+// names are measured by their width rather than their length.
+func (a *App) findLongestAuthorNameWidth(entries []FeedEntry) int {
+	var maxWidth int
 	for _, v := range entries {
-		if len(v.Author.Name) > maxLength {
-			maxLength = len(v.Author.Name)
-		}
+		maxWidth = max(maxWidth, runewidth.StringWidth(a.formatAuthorName(v.Author.Name)))
 	}
-	return maxLength
+	return maxWidth
 }
 
-// buildFZFContent builds the content to show in a fzf instance. This function
-// also returns a map, mapping each line in the fzf content to the
-// corresponding feed entry. The map allows the feed entry to be looked up
-// based on the selected line.
-func buildFZFContent(entries []FeedEntry) (fzfContent string, feedEntryLookup map[string]FeedEntry, err error) {
-	authorNameFormatString := "%s"
-	if enableAuthorNamePadding {
-		maxAuthorNameLength := findLongestAuthorNameLength(entries)
-		authorNameFormatString = fmt.Sprintf("%%-%ds", maxAuthorNameLength) // e.g. "%-16s"
+// buildFZFContent builds the content to show in a fzf instance. Each line is
+// prefixed with the entry's video ID and a tab; the ID is hidden from view in
+// fzf, but is used by the preview pane and to find the selected entries. The
+// columns follow, in the order of the columns setting, separated by
+// columnSeparator. This function also returns a map, mapping each video ID to
+// the corresponding feed entry.
+func (a *App) buildFZFContent(entries []FeedEntry, state *State, history History) (fzfContent string, feedEntryLookup map[string]FeedEntry, err error) {
+	var authorNameWidth int
+	if a.EnableAuthorNamePadding {
+		authorNameWidth = a.findLongestAuthorNameWidth(entries)
 	}
+	feedCategories, err := a.getFeedCategories()
+	if err != nil {
+		return "", nil, err
+	}
+	favorites, err := a.getFavoriteFeeds()
+	if err != nil {
+		return "", nil, err
+	}
+	// Dates and durations vary in width, e.g. when they include the year
+	// or hours, so pad them to keep the columns aligned. Widths are in
+	// terminal cells, since localized dates can include wide characters.
+	now := time.Now()
+	formattedDates := make([]string, len(entries))
+	var dateWidth, durationWidth int
+	for i, v := range entries {
+		var date time.Time
+		if a.historyView {
+			// The history is listed by when the videos were watched
+			date, _ = history.LastWatchedAt(v.YTVideoID)
+		} else {
+			// Shown as a placeholder if the date can't be parsed
+			date = v.GetPublishedDate()
+		}
+		formattedDates[i] = a.formatDate(date, now)
+		dateWidth = max(dateWidth, runewidth.StringWidth(formattedDates[i]))
+		durationWidth = max(durationWidth, runewidth.StringWidth(a.formatEntryDuration(v)))
+	}
+	columns := a.pickerColumns()
+	watchedColumn := slices.Contains(columns, columnWatched)
+	watchedMarkerWidth := runewidth.StringWidth(a.WatchedMarker)
 	feedEntryLookup = make(map[string]FeedEntry)
 	for i, v := range entries {
-		if shouldFilterOutEntry(v) {
-			continue
+		formattedDate := runewidth.FillRight(formattedDates[i], dateWidth)
+		duration := runewidth.FillLeft(a.formatEntryDuration(v), durationWidth)
+		authorName := runewidth.FillRight(a.formatAuthorName(v.Author.Name), authorNameWidth)
+		title := v.ExtraMetadata.NormalizedTitle
+		coloredTitle := a.theme["title"].Sprint(title)
+		if v.ExtraMetadata.LiveStatus != "" {
+			coloredTitle = a.theme["live"].Sprintf("[%s]", strings.ToUpper(v.ExtraMetadata.LiveStatus)) + " " + coloredTitle
 		}
-		parsedDate, err := time.Parse(time.RFC3339, v.Published)
-		if err != nil {
-			return "", nil, err
+		watched := history.IsWatched(v.YTVideoID) && !a.historyView
+		if watched && !watchedColumn {
+			coloredTitle = a.theme["watched"].Sprint(a.WatchedMarker) + " " + coloredTitle
+		}
+		if playCount := history.PlayCount(v.YTVideoID); playCount > 0 {
+			coloredTitle = a.theme["watched"].Sprintf("%s%d", a.ReplayMarker, playCount) + " " + coloredTitle
+		}
+		if state.InWatchLater(v.YTVideoID) && !a.watchLaterView {
+			coloredTitle = a.theme["later"].Sprint(a.LaterMarker) + " " + coloredTitle
+		}
+		if favorites[v.ExtraMetadata.FeedURL] && a.FavoriteMarker != "" {
+			coloredTitle = a.theme["favorite"].Sprint(a.FavoriteMarker) + " " + coloredTitle
+		}
+		if state.IsPinned(v.ID) {
+			coloredTitle = a.theme["pinned"].Sprint(a.PinnedMarker) + " " + coloredTitle
+		}
+		if playlist := v.ExtraMetadata.PlaylistTitle; playlist != "" && a.ShowPlaylistNames {
+			coloredTitle = a.theme["category"].Sprintf("%s ›", playlist) + " " + coloredTitle
+		}
+		if category := feedCategories[v.ExtraMetadata.FeedURL]; category != "" && a.ShowCategories && a.selectedCategory == "" {
+			coloredTitle = a.theme["category"].Sprintf("[%s]", category) + " " + coloredTitle
+		}
+		watchedMarker := strings.Repeat(" ", watchedMarkerWidth)
+		if watched {
+			watchedMarker = a.theme["watched"].Sprint(a.WatchedMarker)
 		}
-		formattedDate := parsedDate.Format("02 Jan")
-		duration := fmt.Sprintf("%02d:%02d", int(v.ExtraMetadata.VideoDuration.Minutes()), int(v.ExtraMetadata.VideoDuration.Seconds())%60)
-		authorName := fmt.Sprintf(authorNameFormatString, v.Author.Name)
-		title := v.ExtraMetadata.NormalizedTitle
-		line := fmt.Sprintf("%s | %s | %s | %s", color.YellowString(formattedDate), color.BlueString(duration), color.GreenString(authorName), title)
-		rawLine := fmt.Sprintf("%s | %s | %s | %s", formattedDate, duration, authorName, title)
 
-		feedEntryLookup[rawLine] = v
+		cells := make([]string, len(columns))
+		for j, column := range columns {
+			switch column {
+			case columnDate:
+				cells[j] = a.theme["date"].Sprint(formattedDate)
+			case columnDuration:
+				cells[j] = a.theme["duration"].Sprint(duration)
+			case columnViews:
+				cells[j] = a.theme["views"].Sprintf("%6s", formatCount(v.Views()))
+			case columnChannel:
+				cells[j] = a.theme["channel"].Sprint(authorName)
+			case columnWatched:
+				cells[j] = watchedMarker
+			case columnTitle:
+				cells[j] = coloredTitle
+			}
+		}
+		line := v.YTVideoID + "\t" + strings.Join(cells, columnSeparator)
+
+		feedEntryLookup[v.YTVideoID] = v
 
 		fzfContent += line
 		if i < len(entries)-1 {
@@ -317,17 +497,93 @@ func buildFZFContent(entries []FeedEntry) (fzfContent string, feedEntryLookup ma
 	return fzfContent, feedEntryLookup, nil
 }
 
-func selectAndPlay(entries []FeedEntry) error {
-	// Get fzf content
-	fzfContent, feedEntryLookup, err := buildFZFContent(entries)
-	if err != nil {
-		return err
+// sortPinnedFirst moves pinned entries to the top of the list, preserving the
+// relative order of entries otherwise.
+func sortPinnedFirst(entries []FeedEntry, state *State) []FeedEntry {
+	sorted := make([]FeedEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return state.IsPinned(sorted[i].ID) && !state.IsPinned(sorted[j].ID)
+	})
+	return sorted
+}
+
+// runPicker opens fzf with the given lines, starting with the query and the
+// cursor at pos (1-based, or 0 for the first line). It returns the final
+// query, the key that was pressed, and the selected lines.
+func (a *App) runPicker(fzfContent string, actions []pickerAction, query string, pos int) (newQuery string, key string, selections []string, err error) {
+	if a.replaying {
+		printReplayedPicker(fzfContent)
+		return query, "", nil, nil
+	}
+	switch a.getPickerBackend() {
+	case pickerPlain:
+		key, selections, err = a.runPlainPicker(fzfContent, actions)
+		return "", key, selections, err
+	case pickerTUI:
+		return a.runTUIPicker(fzfContent, actions, query, pos)
+	case pickerRofi, pickerDmenu, pickerWofi, pickerFuzzel:
+		return a.runLauncherPicker(fzfContent, actions, query)
+	}
+	expect := []string{a.ActionsMenuKey}
+	header := []string{a.ActionsMenuKey + ": actions"}
+	if a.favoritesView != nil && a.FavoritesKey != "" {
+		header = append(header, a.FavoritesKey+": favorites")
+	}
+	for _, v := range actions {
+		if v.Key == "" {
+			continue
+		}
+		if v.Key == "enter" {
+			header = append([]string{v.Key + ": " + v.Name}, header...)
+			continue
+		}
+		expect = append(expect, v.Key)
+		header = append(header, v.Key+": "+v.Name)
 	}
 
-	// Select in fzf
 	r := strings.NewReader(fzfContent)
 	b := &bytes.Buffer{}
-	err = runShellCommand("fzf", []string{"--ansi", "--tiebreak=index"}, r, b)
+	args := []string{
+		"--ansi",
+		"--multi",
+		"--tiebreak=index",
+		"--delimiter=\t",
+		"--with-nth=2..",
+		"--tabstop=1",
+		"--expect=" + strings.Join(expect, ","),
+		"--header=" + strings.Join(header, ", "),
+		"--print-query",
+		"--query=" + query,
+	}
+	if color.NoColor {
+		args = append(args, "--no-color")
+	}
+	if fields := searchedFields(a.pickerColumns()); fields != "" {
+		// Only search the titles and channels, not e.g. the dates.
+		// With --with-nth, the fields are counted from the first
+		// column shown.
+		args = append(args, "--nth="+fields)
+	}
+	if pos > 1 {
+		args = append(args, fmt.Sprintf("--bind=load:pos(%d)", pos))
+	}
+	if a.favoritesView != nil && a.FavoritesKey != "" {
+		binding, done, err := a.favoritesBinding(a.favoritesView)
+		if err != nil {
+			return "", "", nil, err
+		}
+		defer func() { a.favoritesOnly = done() }()
+		args = append(args, binding)
+	}
+	if a.EnablePreview {
+		previewCommand, err := a.getPreviewCommand()
+		if err != nil {
+			return "", "", nil, err
+		}
+		args = append(args, "--preview="+previewCommand, "--preview-window="+a.PreviewWindow)
+	}
+	err = runShellCommand("fzf", args, r, b)
 	if err != nil {
 		if e, ok := err.(*exec.ExitError); ok {
 			// Exit code 2 indicates an unexpected error. Other
@@ -335,28 +591,160 @@ func selectAndPlay(entries []FeedEntry) error {
 			// user-invoked ctrl-C; both of which can be gracefully
 			// ignored.
 			if e.ExitCode() == 2 {
-				return err
+				return "", "", nil, err
 			}
-			return nil
+			return "", "", nil, nil
 		}
+		return "", "", nil, err
+	}
+
+	// With --print-query and --expect, the first line of the output is
+	// the query, the second is the key that was pressed, and the
+	// remaining lines are the selections.
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	if len(lines) < 3 {
+		return "", "", nil, nil
+	}
+	return lines[0], lines[1], lines[2:], nil
+}
+
+// entryPosition returns the index of the entry in entries, or -1 if it isn't
+// there.
+func entryPosition(entries []FeedEntry, entry FeedEntry) int {
+	for i, v := range entries {
+		if v.ID == entry.ID {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectAndRun opens the picker, and runs the chosen action on the selected
+// entries. enterAction is the name of the action bound to enter; the action
+// that would otherwise be bound to enter remains available in the actions
+// menu.
+func (a *App) selectAndRun(allEntries []FeedEntry, enterAction string) error {
+	state, err := a.loadState()
+	if err != nil {
 		return err
 	}
+	actions := a.getPickerActions()
+	for i := range actions {
+		if actions[i].Name == enterAction {
+			actions[i].Key = "enter"
+		} else if actions[i].Key == "enter" {
+			actions[i].Key = ""
+		}
+	}
+
+	var query string
+	var pos int
+	for {
+		// Reload the history each time, since playing entries marks them
+		// as watched.
+		history, err := a.loadHistory()
+		if err != nil {
+			return err
+		}
+		var entries []FeedEntry
+		var favorites map[string]bool // Only set for the feeds, which can be limited to favorites
+		if a.watchLaterView {
+			// Every entry in the list is shown, even if it is
+			// watched or filtered out. The list is rebuilt each
+			// time, since entries leave it when they are played.
+			entries = state.WatchLaterEntries()
+			if len(entries) == 0 {
+				return nil
+			}
+		} else if a.historyView {
+			// allEntries are the cached entries, for the
+			// metadata of the watched videos still in the cache
+			entries = a.historyEntries(history, allEntries)
+		} else {
+			entries, err = a.getVisibleEntries(allEntries, history)
+			if err != nil {
+				return err
+			}
+			favorites, err = a.getFavoriteFeeds()
+			if err != nil {
+				return err
+			}
+			if a.FavoritesFirst {
+				entries = sortFavoritesFirst(entries, favorites)
+			}
+			entries = sortPinnedFirst(entries, state)
+		}
+
+		// Get fzf content
+		fzfContent, feedEntryLookup, err := a.buildFZFContent(entries, state, history)
+		if err != nil {
+			return err
+		}
+		a.favoritesView = nil
+		if len(favorites) > 0 {
+			// Both views are built, so that fzf can switch between
+			// them. See favoritesBinding.
+			a.favoritesView = &favoritesView{All: fzfContent, Favorites: filterPickerLines(fzfContent, filterFavorites(entries, favorites))}
+			if a.favoritesOnly {
+				fzfContent = a.favoritesView.Favorites
+			}
+		}
+
+		// Select in fzf
+		var key string
+		var selections []string
+		query, key, selections, err = a.runPicker(fzfContent, actions, query, pos)
+		if err != nil {
+			return err
+		}
+		if len(selections) == 0 {
+			return nil
+		}
+		var feedEntries []FeedEntry
+		for _, selection := range selections {
+			videoID, _, _ := strings.Cut(selection, "\t")
+			feedEntry, ok := feedEntryLookup[videoID]
+			if !ok {
+				return errors.New("url not found for selection")
+			}
+			feedEntries = append(feedEntries, feedEntry)
+		}
+		a.logSessionEvent(SessionEvent{Type: sessionEventSelection, Key: key, VideoIDs: getVideoIDs(feedEntries)})
 
-	// Parse selection, play in mpv
-	selection := strings.Trim(b.String(), "\n")
-	if selection != "" {
-		if feedEntry, ok := feedEntryLookup[selection]; ok {
-			url := feedEntry.MediaGroup.Content.URL
-			fmt.Fprintf(os.Stderr, "Playing %s\n", url)
-			err := runShellCommand("mpv", []string{url}, nil, os.Stdout)
+		// Find the action to perform
+		var action pickerAction
+		var ok bool
+		if key == a.ActionsMenuKey {
+			action, ok, err = a.selectActionFromMenu(actions, feedEntries)
 			if err != nil {
 				return err
 			}
+			if !ok {
+				// Menu dismissed, go back to the picker
+				continue
+			}
 		} else {
-			return errors.New("url not found for selection")
+			action, ok = findActionByKey(actions, key)
+			if !ok {
+				return fmt.Errorf("no action bound to %s", key)
+			}
 		}
+
+		a.logSessionEvent(SessionEvent{Type: sessionEventAction, Action: action.Name, VideoIDs: getVideoIDs(feedEntries)})
+		reopen, err := action.Run(feedEntries, state)
+		if err != nil {
+			return err
+		}
+		if !reopen && !a.LoopPicker {
+			return nil
+		}
+		// Reopen with the cursor where the first selected entry was.
+		// If it is now hidden, the cursor lands on the entry after it.
+		if a.favoritesView != nil && a.favoritesOnly {
+			entries = filterFavorites(entries, favorites)
+		}
+		pos = entryPosition(entries, feedEntries[0]) + 1
 	}
-	return nil
 }
 
 func runShellCommand(command string, args []string, r io.Reader, w io.Writer) error {
@@ -367,71 +755,346 @@ func runShellCommand(command string, args []string, r io.Reader, w io.Writer) er
 	return cmd.Run()
 }
 
-func getConfigDir() string {
-	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
-	if xdgConfigHome == "" {
-		usr, _ := user.Current()
-		homeDir := usr.HomeDir
-		xdgConfigHome = path.Join(homeDir, ".config/")
-	}
-	return xdgConfigHome
-}
-
 func getConfigFile() string {
-	fileName := path.Join(getConfigDir(), "yt-rss/urls")
+	fileName := filepath.Join(getConfigDir(), "yt-rss", "urls")
 	// TODO: create dir and file if it does not exist
 	return fileName
 }
 
-func getFeedURLs() ([]string, error) {
-	f, err := os.Open(configFile)
+func (a *App) getFeedURLs() ([]string, error) {
+	subscriptions, err := a.getSubscriptions()
 	if err != nil {
 		return nil, err
 	}
-
 	var feedURLs []string
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "#") {
-			feedURLs = append(feedURLs, line)
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	for _, v := range subscriptions {
+		feedURLs = append(feedURLs, v.URL)
 	}
 	return feedURLs, nil
 }
 
 func main() {
-	feedEntries, isStale, err := getFromCache()
+	config := defaultConfig()
+	settingsErr := config.loadSettings()
+	a := newApp(config)
+	args, err := a.parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	name, rest := getCommandName(args)
+	if name != "replay" {
+		a.logSessionEvent(SessionEvent{Type: sessionEventCommand, Args: args})
+	}
+	err = a.setupLanguage()
 	if err != nil {
 		log.Fatal(err)
 	}
-	if isStale {
-		feedURLs, err := getFeedURLs()
+	if name == "config" {
+		// The config command checks the settings itself, and runs
+		// before they are applied, so that it can fix them.
+		err = a.runConfig(rest)
 		if err != nil {
 			log.Fatal(err)
 		}
+		return
+	}
+	if settingsErr != nil {
+		log.Fatal(settingsErr)
+	}
+	err = a.setupLogging()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !slices.Contains(backends, a.Backend) {
+		log.Fatalf("unknown backend: %s (available: %s)", a.Backend, strings.Join(backends, ", "))
+	}
+	// There's no default Invidious instance, so that no requests go to a
+	// third party the user didn't choose
+	if a.InvidiousInstance == "" && a.Backend == backendInvidious {
+		log.Fatal("the invidious backend needs invidious_instance")
+	}
+	if a.InvidiousInstance == "" && a.BrowserFrontend == browserFrontendInvidious {
+		log.Fatal("the invidious browser_frontend needs invidious_instance")
+	}
+	err = a.setupTimezone()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = a.setupCookies()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = a.setupDirs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = a.setupTheme()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if isDumbTerminal() {
+		color.NoColor = true
+	}
+
+	err = a.runCommandLine(args)
+	if errors.Is(err, errInterrupted) {
+		os.Exit(interruptedExitCode)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// getCommandName splits the command name from its args. If no command is
+// given, browse is used.
+func getCommandName(args []string) (name string, rest []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "browse", args
+}
 
-		feeds, err := getFeeds(feedURLs)
+// runCommandLine runs the command in args, after the global flags have been
+// parsed.
+func (a *App) runCommandLine(args []string) error {
+	name, args := getCommandName(args)
+	cmd, ok := a.findCommand(name)
+	if !ok {
+		a.printer.Fprintf(os.Stderr, "unknown command: %s\n\n", name)
+		a.printUsage()
+		os.Exit(2)
+	}
+	return cmd.Run(args)
+}
+
+func (a *App) runBrowse(args []string) error {
+	fs := flag.NewFlagSet("browse", flag.ExitOnError)
+	fs.BoolVar(&a.AudioOnly, "audio", a.AudioOnly, "Play audio only")
+	stream := fs.Bool("stream", false, "Stream videos even if they have been downloaded")
+	sources := fs.String("sources", "", "Comma-separated playback sources to try in order, e.g. local,youtube,browser")
+	fs.BoolVar(&a.LoopPicker, "loop", a.LoopPicker, "Return to the picker after playback ends")
+	fs.StringVar(&a.selectedCategory, "category", "", "Only show videos from feeds in this category")
+	fs.BoolVar(&a.favoritesOnly, "favorites", false, "Only show videos from favorite channels, until switched with favorites_key")
+	lucky := fs.Bool("lucky", false, "Play a random video without opening the picker")
+	browser := fs.Bool("browser", false, "Open the selected videos in the browser instead of playing them")
+	tui := fs.Bool("tui", false, "Use the built-in terminal UI instead of fzf")
+	thumbnails := fs.Bool("thumbnails", false, "Show thumbnails in the preview pane, with preview_image_viewer or the best viewer for the terminal")
+	a.addCacheFlags(fs)
+	a.addShuffleFlags(fs)
+	a.addSortFlags(fs)
+	a.addDurationFlags(fs)
+	fs.Parse(args)
+	if *tui {
+		a.PickerBackend = pickerTUI
+	}
+	if *thumbnails {
+		a.EnablePreview = true
+		if a.PreviewImageViewer == "" {
+			a.PreviewImageViewer = imageViewerAuto
+		}
+	}
+	if *stream {
+		a.PreferLocalFiles = false
+	}
+	if *sources != "" {
+		err := setStringList(&a.PlaybackSources)(*sources)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
+	}
+
+	feedEntries, err := a.loadFeedEntries()
+	if err != nil {
+		return err
+	}
+	if *lucky {
+		a.shuffle = true
+	}
+	feedEntries = a.applyShuffle(fs, feedEntries)
+	feedEntries, err = a.applySort(fs, feedEntries)
+	if err != nil {
+		return err
+	}
+	if *browser {
+		// Videos that are played open in the browser too, e.g. with --lucky
+		a.PlaybackSources = []string{playbackSourceBrowser}
+	}
+	if *lucky {
+		return a.playLucky(feedEntries)
+	}
+	if *browser {
+		return a.selectAndRun(feedEntries, "open")
+	}
+	return a.selectAndRun(feedEntries, "play")
+}
 
-		feedEntries = getFeedEntries(feeds, feedEntries)
+// playLucky plays the first visible entry.
+func (a *App) playLucky(entries []FeedEntry) error {
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	entries, err = a.getVisibleEntries(entries, history)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errors.New("no videos to play")
+	}
+	state, err := a.loadState()
+	if err != nil {
+		return err
+	}
+	return a.playEntries(entries[:1], state, a.AudioOnly)
+}
 
-		err = writeToCache(feedEntries)
-		if err != nil {
-			log.Fatal(err)
+// addCacheFlags adds the flags that control whether and how the feeds are
+// refreshed to the command's flag set.
+func (a *App) addCacheFlags(fs *flag.FlagSet) {
+	fs.StringVar(&a.progressFormat, "progress", progressFormatBar, "How to report refresh progress: bar, json for JSON lines on stderr, or none")
+	fs.BoolVar(&a.forceRefresh, "refresh", false, "Refresh the feeds even if the cache is fresh")
+	fs.BoolVar(&a.offline, "offline", false, "Use the cached feeds without going online, even if the cache is stale")
+	fs.BoolVar(&a.offline, "cached", false, "Alias for --offline")
+}
+
+// loadFeedEntries returns the latest feed entries from the daemon, if it is
+// running, or from the cache otherwise. See refreshFeedEntries.
+func (a *App) loadFeedEntries() ([]FeedEntry, error) {
+	if !a.forceRefresh {
+		if entries, ok := getEntriesFromDaemon(); ok {
+			a.logger.Infof("Using feeds from the daemon\n")
+			return entries, nil
 		}
-	} else {
-		fmt.Fprintf(os.Stderr, "Using cached feeds\n")
 	}
+	return a.refreshFeedEntries()
+}
 
-	err = selectAndPlay(feedEntries)
+// refreshFeedEntries returns the feed entries from the cache, refreshing the
+// feeds first if the cache is stale. --refresh and --offline override the
+// staleness check.
+func (a *App) refreshFeedEntries() ([]FeedEntry, error) {
+	if a.forceRefresh && a.offline {
+		return nil, errors.New(a.printer.Sprintf("--refresh and --offline can't be used together"))
+	}
+	if a.progressFormat != progressFormatBar && a.progressFormat != progressFormatJSON && a.progressFormat != progressFormatNone {
+		return nil, fmt.Errorf("unknown progress format: %s", a.progressFormat)
+	}
+	cache, err := a.loadCache()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if a.offline {
+		if cache.FeedEntries == nil {
+			return nil, errors.New(a.printer.Sprintf("no cached feeds to use offline"))
+		}
+		a.logger.Infof("Using cached feeds (offline)\n")
+		return cache.FeedEntries, nil
 	}
 
+	feedURLs, err := a.getFeedURLs()
+	if err != nil {
+		return nil, err
+	}
+	if !a.forceRefresh {
+		// Each type of feed has its own cache duration, so only
+		// refresh the feeds that are stale.
+		feedURLs = cache.staleFeeds(feedURLs, a.feedCacheDuration)
+	}
+	if len(feedURLs) == 0 {
+		a.logger.Infof("Using cached feeds\n")
+		return cache.FeedEntries, nil
+	}
+
+	state, err := a.loadState()
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := func(entries []FeedEntry, done []int) error {
+		// The feeds are left marked as not fetched, so that an
+		// interrupted refresh is picked up by the next run, which
+		// keeps the cached entries' metadata and fetches the rest.
+		// Entries aren't pruned, since not every feed may have
+		// arrived yet.
+		sortNewestFirst(entries)
+		checkpointCache := *cache
+		checkpointCache.FeedEntries = entries
+		checkpointCache.FeedFetchedAt = maps.Clone(cache.FeedFetchedAt)
+		if checkpointCache.FeedFetchedAt == nil {
+			checkpointCache.FeedFetchedAt = make(map[string]time.Time)
+		}
+		for _, feedURL := range feedURLs {
+			checkpointCache.FeedFetchedAt[feedURL] = time.Time{}
+		}
+		a.logger.Debugf("Saving metadata of %d videos\n", len(done))
+		return a.writeToCache(&checkpointCache)
+	}
+	if a.progressFormat == progressFormatBar {
+		// Errors are printed after the summary, rather than over the
+		// progress.
+		a.logger.Hold()
+		a.progressDisplay = newProgressDisplay(a.printer)
+	}
+	// An interrupt aborts the refresh, and what was fetched so far is
+	// saved below.
+	restore := a.cancelOnInterrupt()
+	defer restore()
+	start := time.Now()
+	// Metadata is fetched for each feed's entries as soon as the feed
+	// arrives, while the other feeds are still being fetched.
+	pipeline := a.newRefreshPipeline(cache.FeedEntries, cache.KnownMetadata, checkpoint)
+	feeds, err := a.getFeeds(feedURLs, pipeline.AddFeed)
+	if err != nil {
+		return nil, err
+	}
+	feedEntries, deferred := pipeline.Finish()
+	if a.progressDisplay != nil {
+		a.progressDisplay.Close()
+		a.progressDisplay = nil
+	}
+	interrupted := a.ctx.Err() != nil
+	if interrupted {
+		// Entries aren't pruned, since not every feed arrived. Only
+		// the feeds that arrived are marked as fetched below, so the
+		// next run fetches the rest, and the metadata that wasn't
+		// fetched.
+		cache.FeedEntries = feedEntries
+		a.logger.Infof("Interrupted after %d of %d feeds, saving what was fetched\n", len(feeds), len(feedURLs))
+	} else {
+		cached := make(map[string]bool, len(cache.FeedEntries))
+		for _, v := range cache.FeedEntries {
+			cached[entryKey(v)] = true
+		}
+		cache.FeedEntries = a.pruneFeedEntries(feedEntries, feeds, state)
+		newEntries := 0
+		for _, v := range cache.FeedEntries {
+			if !cached[entryKey(v)] {
+				newEntries++
+			}
+		}
+		a.logger.Infof("%d feeds, %d failed, %d entries, %d new in %s\n", len(feedURLs), len(feedURLs)-len(feeds), len(cache.FeedEntries), newEntries, time.Since(start).Round(time.Second))
+	}
+	a.logger.Release()
+
+	if cache.FeedFetchedAt == nil {
+		cache.FeedFetchedAt = make(map[string]time.Time)
+	}
+	for _, feed := range feeds {
+		cache.FeedFetchedAt[feed.URL] = time.Now()
+		cache.recordLastUploads(feed.Entries)
+	}
+	cache.recordLastUploads(cache.FeedEntries)
+	err = a.writeToCache(cache)
+	if err != nil {
+		return nil, err
+	}
+	if interrupted {
+		return nil, errInterrupted
+	}
+	a.warnStaleFeeds()
+	if deferred > 0 {
+		// Start the warmer only after the cache is written, so that
+		// it sees the new entries.
+		a.warmMetadataInBackground(deferred)
+	}
+	return cache.FeedEntries, nil
 }
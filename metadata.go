@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// needsMetadata returns true if addMetadata would fetch anything for the
+// entry. Livestreams and premieres always need it, because their status
+// changes over time.
+func (a *App) needsMetadata(entry FeedEntry) bool {
+	return entry.ExtraMetadata.VideoDuration == 0 ||
+		entry.ExtraMetadata.LiveStatus != "" ||
+		entry.ExtraMetadata.IsShort == nil ||
+		a.needsSponsorSegments(entry)
+}
+
+// scheduleMetadata returns the indices of the entries whose metadata should
+// be fetched in this run, in the order they should be fetched, and the number
+// of entries that were deferred because they didn't fit in the metadata
+// budget. The entries must be sorted newest first.
+func (a *App) scheduleMetadata(entries []FeedEntry) (scheduled []int, deferred int) {
+	return a.prioritizeMetadata(entries, a.MetadataBudget)
+}
+
+// prioritizeMetadata returns up to budget indices of the entries that need
+// metadata, or all of them if budget isn't positive, and the number of
+// entries left out. Entries that will be shown in the picker come first,
+// newest first, so that a refresh that brings in hundreds of new videos
+// doesn't hold up the picker on videos that are hidden anyway. The entries
+// must be sorted newest first.
+func (a *App) prioritizeMetadata(entries []FeedEntry, budget int) (scheduled []int, deferred int) {
+	for i := range entries {
+		if a.needsMetadata(entries[i]) {
+			scheduled = append(scheduled, i)
+		}
+	}
+	if budget <= 0 || len(scheduled) <= budget {
+		return scheduled, 0
+	}
+
+	visible := a.pickerVisibility()
+	sort.SliceStable(scheduled, func(i, j int) bool {
+		return visible(entries[scheduled[i]]) && !visible(entries[scheduled[j]])
+	})
+	return scheduled[:budget], len(scheduled) - budget
+}
+
+// pickerVisibility returns a function that tells whether an entry will be
+// shown in the picker, to fetch its metadata first.
+func (a *App) pickerVisibility() func(entry FeedEntry) bool {
+	// Failing to load the history or the filters only makes the
+	// priorities less accurate, so don't fail the refresh over it.
+	history, err := a.loadHistory()
+	if err != nil {
+		history = make(History)
+	}
+	filter, filterErr := a.newEntryFilter()
+	return func(entry FeedEntry) bool {
+		if a.HideWatched && history.IsWatched(entry.YTVideoID) {
+			return false
+		}
+		return filterErr != nil || filter.Allows(entry)
+	}
+}
+
+// warmMetadataInBackground starts a detached `yt-rss warm-metadata` to fetch
+// the metadata that was deferred, so that it's in the cache by the next run.
+func (a *App) warmMetadataInBackground(deferred int) {
+	if a.ReadOnly {
+		return
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return
+	}
+	// The output is discarded, since it would otherwise be drawn over
+	// the picker.
+	cmd := exec.Command(executable, "warm-metadata")
+	err = cmd.Start()
+	if err != nil {
+		a.logger.Warnf("%s\n", errors.Wrap(err, "start background metadata fetch"))
+		return
+	}
+	go cmd.Wait()
+	a.logger.Infof("Fetching metadata for %d more videos in the background\n", deferred)
+}
+
+// warmLockMaxAge is how long a lock file is respected. A warmer that was
+// killed leaves its lock file behind, and shouldn't block warming forever.
+const warmLockMaxAge = 1 * time.Hour
+
+// acquireWarmLock creates the lock file that stops two warmers from fetching
+// the same metadata at the same time. It returns false if another warmer
+// holds the lock.
+func acquireWarmLock(lockFile string) (bool, error) {
+	if info, err := os.Stat(lockFile); err == nil && time.Since(info.ModTime()) > warmLockMaxAge {
+		os.Remove(lockFile)
+	}
+	f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+	return true, f.Close()
+}
+
+// runWarmMetadata fetches the metadata of every cached entry that is missing
+// it, without a budget. It's started in the background after a refresh that
+// deferred metadata, and isn't meant to be invoked directly.
+func (a *App) runWarmMetadata(args []string) error {
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	lockFile := filepath.Join(getCacheDir(), "yt-rss", "warm-metadata.lock")
+	ok, err := acquireWarmLock(lockFile)
+	if err != nil {
+		return errors.Wrap(err, "acquire lock")
+	}
+	if !ok {
+		return nil
+	}
+	defer os.Remove(lockFile)
+
+	cache, err := a.loadCache()
+	if err != nil {
+		return err
+	}
+	a.MetadataBudget = 0
+	a.progressFormat = progressFormatNone
+	scheduled, _ := a.scheduleMetadata(cache.FeedEntries)
+	if len(scheduled) == 0 {
+		return nil
+	}
+	// The metadata fetched before an interrupt is saved too
+	restore := a.cancelOnInterrupt()
+	defer restore()
+	entries := a.bulkAddMetadata(cache.FeedEntries, scheduled, a.mergeWarmedMetadata)
+	err = a.mergeWarmedMetadata(entries, scheduled)
+	if err == nil && a.ctx.Err() != nil {
+		return errInterrupted
+	}
+	return err
+}
+
+// mergeWarmedMetadata saves the metadata of the entries at the given indices
+// to the cache. The cache may have been refreshed while the metadata was
+// being fetched, so it is merged into the latest cache instead of
+// overwriting it.
+func (a *App) mergeWarmedMetadata(entries []FeedEntry, indices []int) error {
+	fetched := make(map[string]FeedEntry, len(indices))
+	for _, i := range indices {
+		fetched[entries[i].YTVideoID] = entries[i]
+	}
+	cache, err := a.loadCache()
+	if err != nil {
+		return err
+	}
+	for i, v := range cache.FeedEntries {
+		f, ok := fetched[v.YTVideoID]
+		if !ok || !a.needsMetadata(v) {
+			continue
+		}
+		copyFetchedMetadata(&cache.FeedEntries[i], f)
+	}
+	return a.writeToCache(cache)
+}
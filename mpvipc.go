@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// mpvClient sends commands to mpv over its JSON IPC socket. See
+// https://mpv.io/manual/stable/#json-ipc.
+type mpvClient struct {
+	conn      mpvConn
+	reader    *bufio.Reader
+	requestID int
+	events    []mpvResponse // Events received while waiting for a response, for ReadEvent
+}
+
+// mpvConn is the connection to mpv's IPC server: a unix socket, or a named
+// pipe on Windows. See dialMPV.
+type mpvConn interface {
+	io.ReadWriteCloser
+	SetDeadline(t time.Time) error
+}
+
+type mpvRequest struct {
+	Command   []interface{} `json:"command"`
+	RequestID int           `json:"request_id"`
+}
+
+// mpvResponse is a reply to a command. mpv also sends events over the
+// socket, which have an Event instead of a RequestID.
+type mpvResponse struct {
+	Error     string          `json:"error"`
+	Data      json.RawMessage `json:"data"`
+	RequestID int             `json:"request_id"`
+	Event     string          `json:"event"`
+
+	// Set on start-file and end-file events. Playlist entries are numbered
+	// from 1, in the order they were added.
+	PlaylistEntryID int    `json:"playlist_entry_id"`
+	Reason          string `json:"reason"` // Why an end-file event's file ended, e.g. "eof" if it played to the end, or "quit"
+}
+
+func (c *mpvClient) Close() error {
+	return c.conn.Close()
+}
+
+// Command runs an mpv command, e.g. Command("loadfile", url, "append-play"),
+// and returns its data.
+func (c *mpvClient) Command(args ...interface{}) (json.RawMessage, error) {
+	c.requestID++
+	b, err := json.Marshal(mpvRequest{Command: args, RequestID: c.requestID})
+	if err != nil {
+		return nil, err
+	}
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = c.conn.Write(append(b, '\n'))
+	if err != nil {
+		return nil, errors.Wrap(err, "send mpv command")
+	}
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, errors.Wrap(err, "read mpv response")
+		}
+		var resp mpvResponse
+		err = json.Unmarshal(line, &resp)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse mpv response")
+		}
+		if resp.Event != "" {
+			c.events = append(c.events, resp)
+			continue
+		}
+		if resp.RequestID != c.requestID {
+			continue
+		}
+		if resp.Error != "success" {
+			return nil, fmt.Errorf("mpv command %v failed: %s", args[0], resp.Error)
+		}
+		return resp.Data, nil
+	}
+}
+
+// ReadEvent waits for the next event, e.g. "start-file" or "end-file". It
+// returns an error once mpv has quit.
+func (c *mpvClient) ReadEvent() (mpvResponse, error) {
+	if len(c.events) > 0 {
+		event := c.events[0]
+		c.events = c.events[1:]
+		return event, nil
+	}
+	c.conn.SetDeadline(time.Time{})
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return mpvResponse{}, err
+		}
+		var resp mpvResponse
+		err = json.Unmarshal(line, &resp)
+		if err != nil {
+			return mpvResponse{}, errors.Wrap(err, "parse mpv event")
+		}
+		if resp.Event != "" {
+			return resp, nil
+		}
+	}
+}
+
+// queueInMPV appends the URL to the playlist of the mpv instance started by
+// yt-rss, starting a new instance in the background if none is running.
+func (a *App) queueInMPV(entry FeedEntry, url string) error {
+	socketPath := getMPVSocketPath()
+	client, err := dialMPV(socketPath)
+	if err != nil {
+		// No running instance, or a stale socket from one that has quit
+		return a.startMPVWithIPC(entry, url, socketPath)
+	}
+	defer client.Close()
+	_, err = client.Command("loadfile", url, "append-play")
+	return err
+}
+
+// startMPVWithIPC starts the player in the background, listening for
+// commands on socketPath, and waits until the socket is ready.
+func (a *App) startMPVWithIPC(entry FeedEntry, url string, socketPath string) error {
+	if !a.isMPVPlayer() {
+		return errors.New("queueing requires mpv as the player")
+	}
+	args, err := splitCommandLine(a.PlayerCommand)
+	if err != nil {
+		return errors.Wrap(err, "parse player command")
+	}
+	if !strings.Contains(a.PlayerCommand, "{url}") {
+		args = append(args, url)
+	}
+	replacer := strings.NewReplacer("{url}", url, "{title}", entry.MediaGroup.Title)
+	for i := range args {
+		args[i] = replacer.Replace(args[i])
+	}
+	args = append(args[:1], append([]string{"--input-ipc-server=" + socketPath, "--force-window=immediate"}, args[1:]...)...)
+
+	player, err := findPlayer(args[0])
+	if err != nil {
+		return err
+	}
+	os.Remove(socketPath)
+	cmd := exec.Command(player, args[1:]...)
+	err = cmd.Start()
+	if err != nil {
+		return errors.Wrap(err, "start player")
+	}
+	go cmd.Wait() // Reap the process if it exits while yt-rss is running
+
+	client, err := waitForMPV(socketPath)
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+// waitForMPV connects to an mpv that was just started, once it listens on
+// socketPath.
+func waitForMPV(socketPath string) (*mpvClient, error) {
+	for i := 0; i < 50; i++ {
+		if client, err := dialMPV(socketPath); err == nil {
+			return client, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil, errors.New("timed out waiting for mpv to start")
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package main
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+func getMPVSocketPath() string {
+	return filepath.Join(getRuntimeDir(), "yt-rss-mpv.sock")
+}
+
+// getMPVQueueSocketPath returns the socket of the mpv playing the queue. See
+// playQueueInMPV.
+func getMPVQueueSocketPath() string {
+	return filepath.Join(getRuntimeDir(), "yt-rss-queue.sock")
+}
+
+func dialMPV(socketPath string) (*mpvClient, error) {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &mpvClient{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
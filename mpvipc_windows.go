@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"os"
+)
+
+// getMPVSocketPath returns the named pipe that mpv listens on, since mpv
+// uses named pipes instead of unix sockets for IPC on Windows.
+func getMPVSocketPath() string {
+	return `\\.\pipe\yt-rss-mpv`
+}
+
+// getMPVQueueSocketPath returns the named pipe of the mpv playing the queue.
+func getMPVQueueSocketPath() string {
+	return `\\.\pipe\yt-rss-queue`
+}
+
+// dialMPV opens the named pipe. It fails right away if mpv isn't listening
+// on it.
+func dialMPV(socketPath string) (*mpvClient, error) {
+	conn, err := os.OpenFile(socketPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &mpvClient{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
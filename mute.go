@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// mutedChannel is a channel whose entries are hidden from the picker, while
+// it stays subscribed.
+type mutedChannel struct {
+	Name    string     `json:"name"` // For listing muted channels
+	MutedAt time.Time  `json:"muted_at"`
+	Until   *time.Time `json:"until,omitempty"` // When the snooze expires, or nil if muted until unmuted
+}
+
+// Active returns true if the channel is still muted at the time.
+func (m mutedChannel) Active(now time.Time) bool {
+	return m.Until == nil || now.Before(*m.Until)
+}
+
+// IsMuted returns true if the channel is muted, and its snooze hasn't
+// expired.
+func (s *State) IsMuted(channelID string) bool {
+	m, ok := s.MutedChannels[channelID]
+	return ok && m.Active(time.Now())
+}
+
+// removeExpiredMutes forgets channels whose snooze has expired.
+func (s *State) removeExpiredMutes() {
+	now := time.Now()
+	for channelID, m := range s.MutedChannels {
+		if !m.Active(now) {
+			delete(s.MutedChannels, channelID)
+		}
+	}
+}
+
+// resolveChannel returns the ID and name of a channel, given by its ID, a
+// YouTube URL, or its name as in the cache, ignoring case.
+func (a *App) resolveChannel(channel string) (channelID string, name string, err error) {
+	names, err := a.getChannelNames()
+	if err != nil {
+		return "", "", err
+	}
+	if _, ok := names[channel]; ok || channelIDRegex.MatchString(channel) {
+		return channel, getOrDefault(names, channel, channel), nil
+	}
+	if strings.Contains(channel, "youtube.com") || strings.Contains(channel, "://") {
+		feedURL, err := a.resolveFeedURL(channel)
+		if err != nil {
+			return "", "", err
+		}
+		channelID = parseChannelIDFromFeedURL(feedURL)
+		if channelID == "" {
+			return "", "", fmt.Errorf("not a channel: %s", channel)
+		}
+		return channelID, getOrDefault(names, channelID, channelID), nil
+	}
+
+	var matches []string
+	for id, v := range names {
+		if strings.EqualFold(v, channel) {
+			matches = append(matches, id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", "", fmt.Errorf("no channel named %s in the cache", channel)
+	case 1:
+		return matches[0], names[matches[0]], nil
+	default:
+		sort.Strings(matches)
+		return "", "", fmt.Errorf("several channels are named %s, give one of their IDs instead: %s", channel, strings.Join(matches, ", "))
+	}
+}
+
+// runMute hides a channel's entries from the picker without unsubscribing,
+// until it is unmuted, or for a while with --for. Without a channel, the
+// muted channels are listed.
+func (a *App) runMute(args []string) error {
+	fs := flag.NewFlagSet("mute", flag.ExitOnError)
+	snooze := fs.String("for", "", "Only mute the channel for this long, e.g. 30d or 2w, instead of until it is unmuted")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return a.listMutedChannels()
+	}
+	// Allow the flags after the channel too, e.g. `mute <channel> --for 30d`
+	channel := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		return errors.New("usage: yt-rss mute [<channel ID, URL, or name> [--for <duration>]]")
+	}
+
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	channelID, name, err := a.resolveChannel(channel)
+	if err != nil {
+		return err
+	}
+	muted := mutedChannel{Name: name, MutedAt: time.Now()}
+	if *snooze != "" {
+		d, err := parseDuration(*snooze)
+		if err != nil {
+			return errors.Wrap(err, "--for")
+		}
+		until := muted.MutedAt.Add(d)
+		muted.Until = &until
+	}
+
+	state, err := a.loadState()
+	if err != nil {
+		return err
+	}
+	state.removeExpiredMutes()
+	if state.MutedChannels == nil {
+		state.MutedChannels = make(map[string]mutedChannel)
+	}
+	state.MutedChannels[channelID] = muted
+	err = a.saveStateWithUndo(state, "mute "+name)
+	if err != nil {
+		return err
+	}
+	if muted.Until != nil {
+		a.printer.Printf("Muted %s until %s\n", name, a.localTime(*muted.Until).Format("Mon, 02 Jan 2006 15:04"))
+	} else {
+		a.printer.Printf("Muted %s\n", name)
+	}
+	return nil
+}
+
+// runUnmute shows a muted channel's entries in the picker again.
+func (a *App) runUnmute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: yt-rss unmute <channel ID, URL, or name>")
+	}
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	state, err := a.loadState()
+	if err != nil {
+		return err
+	}
+	state.removeExpiredMutes()
+
+	// Muted channels may have dropped out of the cache, so they are
+	// looked up by their muted name first.
+	channelID := ""
+	for id, v := range state.MutedChannels {
+		if id == args[0] || strings.EqualFold(v.Name, args[0]) {
+			channelID = id
+		}
+	}
+	if channelID == "" {
+		channelID, _, err = a.resolveChannel(args[0])
+		if err != nil {
+			return err
+		}
+	}
+	muted, ok := state.MutedChannels[channelID]
+	if !ok {
+		return fmt.Errorf("channel isn't muted: %s", args[0])
+	}
+	delete(state.MutedChannels, channelID)
+	err = a.saveStateWithUndo(state, "unmute "+muted.Name)
+	if err != nil {
+		return err
+	}
+	a.printer.Printf("Unmuted %s\n", muted.Name)
+	return nil
+}
+
+// listMutedChannels prints the muted channels, and when they are muted until.
+func (a *App) listMutedChannels() error {
+	state, err := a.loadState()
+	if err != nil {
+		return err
+	}
+	state.removeExpiredMutes()
+	if len(state.MutedChannels) == 0 {
+		a.printer.Printf("No muted channels\n")
+		return nil
+	}
+	var channelIDs []string
+	for id := range state.MutedChannels {
+		channelIDs = append(channelIDs, id)
+	}
+	sort.Slice(channelIDs, func(i, j int) bool {
+		return strings.ToLower(state.MutedChannels[channelIDs[i]].Name) < strings.ToLower(state.MutedChannels[channelIDs[j]].Name)
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, id := range channelIDs {
+		m := state.MutedChannels[id]
+		until := a.printer.Sprintf("until unmuted")
+		if m.Until != nil {
+			until = a.printer.Sprintf("until %s", a.localTime(*m.Until).Format("Mon, 02 Jan 2006 15:04"))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", m.Name, id, until)
+	}
+	return w.Flush()
+}
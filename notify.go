@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// notifyNewVideosExitCode is the exit code of `yt-rss notify` when there are
+// new videos, so that cron jobs and status bars can act on it.
+const notifyNewVideosExitCode = 3
+
+// Outputs of `yt-rss notify`
+const (
+	notifyOutputText    = "text"    // A summary on stdout
+	notifyOutputJSON    = "json"    // The new entries as a JSON array on stdout
+	notifyOutputDesktop = "desktop" // A desktop notification, and the summary on stdout
+)
+
+// NotifyState records the videos that `yt-rss notify` has already seen, so
+// that each video is only reported as new once.
+type NotifyState struct {
+	LastRun      time.Time `json:"last_run"`
+	SeenVideoIDs []string  `json:"seen_video_ids"`
+}
+
+func (a *App) getNotifyStateFile() string {
+	return a.getDataFile(getStateDir(), "notify.json")
+}
+
+// loadNotifyState returns nil if notify hasn't run before.
+func (a *App) loadNotifyState() (*NotifyState, error) {
+	b, err := os.ReadFile(a.getNotifyStateFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &NotifyState{}
+	err = json.Unmarshal(b, state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (a *App) saveNotifyState(state *NotifyState) error {
+	if a.ReadOnly {
+		return nil
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.getNotifyStateFile(), b, 0600)
+}
+
+// runNotify refreshes the feeds and reports the videos that weren't there the
+// last time it ran. Only videos that would be shown in the picker are
+// reported. On the first run, the current videos are recorded as seen
+// without being reported. Exits with notifyNewVideosExitCode if there are new
+// videos.
+func (a *App) runNotify(args []string) error {
+	fs := flag.NewFlagSet("notify", flag.ExitOnError)
+	output := fs.String("output", notifyOutputText, "How to report new videos: text, json, or desktop")
+	fs.StringVar(&a.selectedCategory, "category", "", "Only report videos from feeds in this category")
+	a.addCacheFlags(fs)
+	fs.Parse(args)
+	if *output != notifyOutputText && *output != notifyOutputJSON && *output != notifyOutputDesktop {
+		return fmt.Errorf("unknown output: %s", *output)
+	}
+
+	entries, err := a.loadFeedEntries()
+	if err != nil {
+		return err
+	}
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	entries, err = a.getVisibleEntries(entries, history)
+	if err != nil {
+		return err
+	}
+	notifyState, err := a.loadNotifyState()
+	if err != nil {
+		return errors.Wrap(err, "load notify state")
+	}
+
+	var newEntries []FeedEntry
+	if notifyState != nil {
+		seen := make(map[string]bool)
+		for _, v := range notifyState.SeenVideoIDs {
+			seen[v] = true
+		}
+		for _, v := range entries {
+			if !seen[v.YTVideoID] {
+				newEntries = append(newEntries, v)
+			}
+		}
+	}
+
+	err = a.reportNewEntries(newEntries, *output)
+	if err != nil {
+		return err
+	}
+
+	// Only the videos that are still visible are kept, so that the seen
+	// set doesn't grow forever.
+	err = a.saveNotifyState(&NotifyState{LastRun: time.Now(), SeenVideoIDs: getVideoIDs(entries)})
+	if err != nil {
+		return errors.Wrap(err, "save notify state")
+	}
+	if len(newEntries) > 0 {
+		os.Exit(notifyNewVideosExitCode)
+	}
+	return nil
+}
+
+func (a *App) reportNewEntries(entries []FeedEntry, output string) error {
+	if output == notifyOutputJSON {
+		if entries == nil {
+			entries = []FeedEntry{} // Print [] rather than null
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	a.printer.Printf("%d new videos\n", len(entries))
+	for _, v := range entries {
+		fmt.Printf("  %s: %s\n", v.Author.Name, v.MediaGroup.Title)
+	}
+	if output == notifyOutputDesktop {
+		title := a.printer.Sprintf("%d new videos", len(entries))
+		body := entries[0].Author.Name + ": " + entries[0].MediaGroup.Title
+		if len(entries) > 1 {
+			body += "\n" + a.printer.Sprintf("and %d more", len(entries)-1)
+		}
+		return sendDesktopNotification(title, body)
+	}
+	return nil
+}
+
+// findNotificationCommand returns the command for sending desktop
+// notifications on this system.
+func findNotificationCommand() (string, bool) {
+	command := "notify-send"
+	if runtime.GOOS == "darwin" {
+		command = "osascript"
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return command, false
+	}
+	return command, true
+}
+
+// sendDesktopNotification shows a desktop notification, with notify-send on
+// Linux and osascript on macOS.
+func sendDesktopNotification(title, body string) error {
+	command, ok := findNotificationCommand()
+	if !ok {
+		return fmt.Errorf("%s not found, can't send desktop notifications", command)
+	}
+	if command == "osascript" {
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		return runShellCommand(command, []string{"-e", script}, nil, nil)
+	}
+	return runShellCommand(command, []string{"--app-name=yt-rss", title, body}, nil, nil)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// nowPlaying is the video being played. It's written to the now-playing file
+// while the player runs, for status bars and stream overlays. See runNow.
+type nowPlaying struct {
+	VideoID         string    `json:"video_id"`
+	Title           string    `json:"title"`
+	Channel         string    `json:"channel"`
+	URL             string    `json:"url"` // Watch page on YouTube, even if the video is played from a download or another backend
+	Thumbnail       string    `json:"thumbnail"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"` // Zero if the duration isn't known
+	StartedAt       time.Time `json:"started_at"`
+	PID             int       `json:"pid"` // Process of the yt-rss playing the video, to tell whether the file is stale
+}
+
+// Elapsed returns how long ago playback started, including any time spent
+// paused.
+func (n nowPlaying) Elapsed() time.Duration {
+	return time.Since(n.StartedAt).Truncate(time.Second)
+}
+
+// getNowPlayingFile returns the path of the now-playing file. By default,
+// it's next to the daemon's socket, since it only describes the running
+// session.
+func (a *App) getNowPlayingFile() string {
+	if a.NowPlayingFile != "" {
+		return a.NowPlayingFile
+	}
+	return filepath.Join(getRuntimeDir(), "yt-rss-now-playing.json")
+}
+
+// writeNowPlaying writes the now-playing file for the entry, with the title
+// the player is given. The returned function removes it once the player has
+// quit. Status bars shouldn't stop playback, so errors are only warned about.
+func (a *App) writeNowPlaying(entry FeedEntry, title string) (done func()) {
+	fileName := a.getNowPlayingFile()
+	b, err := json.MarshalIndent(nowPlaying{
+		VideoID:         entry.YTVideoID,
+		Title:           title,
+		Channel:         entry.Author.Name,
+		URL:             entry.WatchURL(),
+		Thumbnail:       entry.MediaGroup.Thumbnail.URL,
+		DurationSeconds: int(entry.ExtraMetadata.VideoDuration.Seconds()),
+		StartedAt:       time.Now(),
+		PID:             os.Getpid(),
+	}, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(fileName, append(b, '\n'), 0600)
+	}
+	if err != nil {
+		a.logger.Warnf("%s\n", errors.Wrap(err, "write now-playing file"))
+		return func() {}
+	}
+	return func() {
+		os.Remove(fileName)
+	}
+}
+
+// readNowPlaying returns the video being played. ok is false if nothing is
+// playing, including when the file was left behind by a yt-rss that was
+// killed.
+func (a *App) readNowPlaying() (playing nowPlaying, ok bool, err error) {
+	b, err := ioutil.ReadFile(a.getNowPlayingFile())
+	if os.IsNotExist(err) {
+		return nowPlaying{}, false, nil
+	}
+	if err != nil {
+		return nowPlaying{}, false, err
+	}
+	err = json.Unmarshal(b, &playing)
+	if err != nil {
+		return nowPlaying{}, false, errors.Wrap(err, "parse now-playing file")
+	}
+	if !processRunning(playing.PID) {
+		return nowPlaying{}, false, nil
+	}
+	return playing, true, nil
+}
+
+// runNow prints the video being played, for status bars. Nothing is printed
+// if nothing is playing. --format takes a Go template, e.g.
+// '{{.Channel}}: {{.Title}} ({{duration .Elapsed}})'.
+func (a *App) runNow(args []string) error {
+	fs := flag.NewFlagSet("now", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print JSON for a waybar custom module, with the channel and URL in the tooltip")
+	format := fs.String("format", "", "Go template to print the video with, e.g. '{{.Channel}}: {{.Title}} ({{duration .Elapsed}})'")
+	fs.Parse(args)
+
+	var tmpl *template.Template
+	if *format != "" {
+		var err error
+		tmpl, err = template.New("format").Funcs(listTemplateFuncs).Parse(*format)
+		if err != nil {
+			return errors.Wrap(err, "parse format")
+		}
+	}
+
+	playing, ok, err := a.readNowPlaying()
+	if err != nil {
+		return err
+	}
+	switch {
+	case *asJSON:
+		output := waybarOutput{Class: "none"}
+		if ok {
+			output.Text = playing.Title
+			output.Tooltip = fmt.Sprintf("%s\n%s", playing.Channel, playing.URL)
+			output.Class = "playing"
+		}
+		return json.NewEncoder(os.Stdout).Encode(output)
+	case !ok:
+		return nil
+	case tmpl != nil:
+		err = tmpl.Execute(os.Stdout, playing)
+		if err != nil {
+			return errors.Wrap(err, "execute format")
+		}
+		fmt.Println()
+		return nil
+	default:
+		fmt.Printf("%s - %s\n", playing.Channel, playing.Title)
+		return nil
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// processRunning returns true if a process with the PID exists. Signal 0
+// only checks whether the process can be signalled.
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+// stillActive is the exit code GetExitCodeProcess reports for a process
+// that hasn't exited.
+const stillActive = 259
+
+// processRunning returns true if a process with the PID exists and hasn't
+// exited.
+func processRunning(pid int) bool {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(handle)
+	var code uint32
+	err = windows.GetExitCodeProcess(handle, &code)
+	return err == nil && code == stillActive
+}
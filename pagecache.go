@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func getPageCacheDir() string {
+	return filepath.Join(getCacheDir(), "yt-rss", "pages")
+}
+
+// getCachedVideoPage returns the HTML of the video's page at url. Pages are
+// cached on disk for PageCacheDuration, keyed by video ID, so that the
+// duration, live status, and availability checks share a single fetch.
+func (a *App) getCachedVideoPage(videoID string, url string) (string, error) {
+	a.pageCacheMutex.Lock()
+	page, ok := a.pageCache[videoID]
+	a.pageCacheMutex.Unlock()
+	if ok {
+		return page, nil
+	}
+
+	fileName := filepath.Join(getPageCacheDir(), videoID+".html")
+	if a.PageCacheDuration > 0 {
+		if info, err := os.Stat(fileName); err == nil && time.Since(info.ModTime()) < a.PageCacheDuration {
+			if b, err := os.ReadFile(fileName); err == nil {
+				page = string(b)
+				a.storePage(videoID, page)
+				return page, nil
+			}
+		}
+	}
+
+	page, err := a.getVideoPage(url)
+	if err != nil {
+		return "", err
+	}
+	a.storePage(videoID, page)
+	if a.PageCacheDuration > 0 && !a.ReadOnly {
+		a.prunePagesOnce.Do(a.prunePageCache)
+		if err := os.MkdirAll(getPageCacheDir(), 0700); err == nil {
+			// Failing to cache the page isn't fatal, it is fetched
+			// again next time.
+			writeFileAtomic(fileName, []byte(page), 0600)
+		}
+	}
+	return page, nil
+}
+
+func (a *App) storePage(videoID, page string) {
+	a.pageCacheMutex.Lock()
+	a.pageCache[videoID] = page
+	a.pageCacheMutex.Unlock()
+}
+
+// prunePageCache deletes cached pages older than PageCacheDuration.
+func (a *App) prunePageCache() {
+	files, err := os.ReadDir(getPageCacheDir())
+	if err != nil {
+		return
+	}
+	for _, v := range files {
+		info, err := v.Info()
+		if err != nil || time.Since(info.ModTime()) < a.PageCacheDuration {
+			continue
+		}
+		os.Remove(filepath.Join(getPageCacheDir(), v.Name()))
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+)
+
+// getXDGDir returns the XDG base directory in the environment variable, or
+// the default relative to the home directory if it isn't set. On Windows,
+// where the XDG variables are rarely set, the default is windowsDir instead,
+// e.g. os.UserConfigDir for %AppData%.
+func getXDGDir(envVar string, defaultDir string, windowsDir func() (string, error)) string {
+	if dir := os.Getenv(envVar); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		if dir, err := windowsDir(); err == nil {
+			return dir
+		}
+	}
+	usr, _ := user.Current()
+	return filepath.Join(usr.HomeDir, filepath.FromSlash(defaultDir))
+}
+
+func getConfigDir() string {
+	return getXDGDir("XDG_CONFIG_HOME", ".config", os.UserConfigDir)
+}
+
+// getCacheDir returns the base directory for the feed cache, which can be
+// deleted without losing anything. On Windows, it's %LocalAppData%.
+func getCacheDir() string {
+	return getXDGDir("XDG_CACHE_HOME", ".cache", os.UserCacheDir)
+}
+
+// getStateDir returns the base directory for history, pins, downloads, and
+// the operation log, which should be kept but aren't configuration. Windows
+// has no directory for state, so it's %AppData%, with the configuration.
+func getStateDir() string {
+	return getXDGDir("XDG_STATE_HOME", ".local/state", os.UserConfigDir)
+}
+
+// getRuntimeDir returns the directory for sockets and other files that only
+// describe the running session. It is XDG_RUNTIME_DIR, or else yt-rss's cache
+// directory rather than the temporary directory, which is shared with other
+// users, who could create the files there first.
+func getRuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	dir := filepath.Join(getCacheDir(), "yt-rss")
+	// Created here too, since setupDirs doesn't create it with read_only
+	// set. If it fails, creating the file in it fails with the reason.
+	os.MkdirAll(dir, 0700)
+	return dir
+}
+
+// dataFiles are the files that used to be kept in the config directory, and
+// the base directories they now belong in.
+var dataFiles = []struct {
+	Name string
+	Dir  func() string
+}{
+	{"cache.json", getCacheDir},
+	{"history.json", getStateDir},
+	{"state.json", getStateDir},
+	{"oplog.json", getStateDir},
+	{"downloads.json", getStateDir},
+}
+
+// getDataFile returns the path of a file in yt-rss's directory under the
+// base directory. In read-only mode, files aren't migrated, so the file's old
+// location in the config directory is used if it hasn't been migrated yet.
+func (a *App) getDataFile(baseDir string, name string) string {
+	fileName := filepath.Join(baseDir, "yt-rss", name)
+	if a.ReadOnly {
+		legacyFileName := filepath.Join(getConfigDir(), "yt-rss", name)
+		if !fileExists(fileName) && fileExists(legacyFileName) {
+			return legacyFileName
+		}
+	}
+	return fileName
+}
+
+func fileExists(fileName string) bool {
+	_, err := os.Stat(fileName)
+	return err == nil
+}
+
+// setupDirs creates yt-rss's directories, and moves files from the config
+// directory, where all files used to be kept, to the cache and state
+// directories.
+func (a *App) setupDirs() error {
+	if a.ReadOnly {
+		return nil
+	}
+	for _, dir := range []string{getConfigDir(), getCacheDir(), getStateDir()} {
+		err := os.MkdirAll(filepath.Join(dir, "yt-rss"), 0700)
+		if err != nil {
+			return err
+		}
+	}
+	for _, v := range dataFiles {
+		oldFileName := filepath.Join(getConfigDir(), "yt-rss", v.Name)
+		newFileName := filepath.Join(v.Dir(), "yt-rss", v.Name)
+		if oldFileName == newFileName || !fileExists(oldFileName) || fileExists(newFileName) {
+			continue
+		}
+		err := moveFile(oldFileName, newFileName)
+		if err != nil {
+			return err
+		}
+		a.printer.Fprintf(os.Stderr, "Moved %s to %s\n", oldFileName, newFileName)
+	}
+	return nil
+}
+
+// moveFile renames the file, copying it if it has to be moved across file
+// systems.
+func moveFile(oldFileName, newFileName string) error {
+	if err := os.Rename(oldFileName, newFileName); err == nil {
+		return nil
+	}
+	src, err := os.Open(oldFileName)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(newFileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	err = dst.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(oldFileName)
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// metadataCheckpointInterval is how often the metadata fetched so far is
+// saved during a bulk fetch, so that an interrupted fetch resumes where it
+// left off instead of starting over.
+const metadataCheckpointInterval = 15 * time.Second
+
+// metadataPipeline adds metadata to entries with MetadataConcurrency workers,
+// while more entries are still being queued. During a refresh, each feed is
+// merged into the cached entries as it arrives, and the metadata of its
+// entries is fetched while the remaining feeds are still loading, instead of
+// waiting for every feed first.
+type metadataPipeline struct {
+	app        *App
+	progress   *progressReporter
+	checkpoint func(entries []FeedEntry, done []int) error // See bulkAddMetadata
+	known      map[string]VideoMetadata                    // See applyKnownMetadata
+	visible    func(entry FeedEntry) bool                  // See pickerVisibility
+	budget     int                                         // How many more entries can be queued in this run, or -1 if unlimited
+
+	mu             sync.Mutex
+	cond           *sync.Cond // Signaled when entries are queued, or the queue is closed
+	entries        []FeedEntry
+	seen           map[string]int // entryKey to index in entries
+	queued         map[int]bool   // Indices of entries that were queued, including those done
+	queue          []int
+	closed         bool
+	done           []int
+	lastCheckpoint time.Time
+	checkpointMu   sync.Mutex // Held while a checkpoint is saved
+	wg             sync.WaitGroup
+}
+
+// newMetadataPipeline starts the workers, which wait for entries to be
+// queued.
+func (a *App) newMetadataPipeline(entries []FeedEntry, progress *progressReporter, checkpoint func(entries []FeedEntry, done []int) error) *metadataPipeline {
+	p := &metadataPipeline{
+		app:            a,
+		progress:       progress,
+		checkpoint:     checkpoint,
+		budget:         -1,
+		entries:        entries,
+		seen:           make(map[string]int),
+		queued:         make(map[int]bool),
+		lastCheckpoint: time.Now(),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for i, v := range entries {
+		p.seen[entryKey(v)] = i
+	}
+	for i := 0; i < max(a.MetadataConcurrency, 1); i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+// newRefreshPipeline returns a pipeline that feeds are merged into with
+// AddFeed as they arrive. The cached entries that need metadata are queued
+// right away, so that the workers have something to do while the first feeds
+// load.
+//
+// With metadata_budget, entries that will be shown in the picker are queued
+// as their feed arrives, and the rest are queued by Finish once every feed
+// has arrived, in the order of scheduleMetadata. Visible entries are fetched
+// in the order their feeds arrive rather than newest first, which only
+// matters when there are more of them than the budget.
+func (a *App) newRefreshPipeline(cachedFeedEntries []FeedEntry, known map[string]VideoMetadata, checkpoint func(entries []FeedEntry, done []int) error) *metadataPipeline {
+	// Prioritize cached entries if there are duplicates, because the
+	// cached entries have additional metadata fetched already. Fetching
+	// metadata can be expensive, so we should avoid doing it where
+	// unnecessary.
+	//
+	// Entries are the same if they have the same video ID, even if they
+	// come from different feeds, e.g. a channel and a topic channel or
+	// playlist that reposts its videos. The earliest publish date is kept.
+	var entries []FeedEntry
+	seen := make(map[string]int) // video ID to index in entries
+	for _, v := range cachedFeedEntries {
+		i, ok := seen[entryKey(v)]
+		if !ok {
+			// Append if entry has not been added before
+			seen[entryKey(v)] = len(entries)
+			entries = append(entries, v)
+			continue
+		}
+		keepEarliestPublished(&entries[i], v)
+	}
+	a.normalizeTitles(entries)
+
+	p := a.newMetadataPipeline(entries, a.newStreamingProgressReporter("metadata", a.printer.Sprintf("Adding metadata")), checkpoint)
+	p.known = known
+	if a.MetadataBudget > 0 {
+		p.budget = a.MetadataBudget
+		p.visible = a.pickerVisibility()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queueLocked(indices(len(entries)), true)
+	return p
+}
+
+// AddFeed merges the feed's entries into the pipeline's entries, and queues
+// those that need metadata. It is safe to call from several goroutines.
+func (p *metadataPipeline) AddFeed(feed Feed) {
+	applyKnownMetadata(feed.Entries, p.known)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var merged []int
+	for _, v := range feed.Entries {
+		i, ok := p.seen[entryKey(v)]
+		if !ok {
+			// Append if entry has not been added before
+			i = len(p.entries)
+			p.seen[entryKey(v)] = i
+			p.entries = append(p.entries, v)
+		} else {
+			mergeFeedEntry(&p.entries[i], v)
+		}
+		if p.entries[i].ExtraMetadata.NormalizedTitle == "" {
+			p.entries[i].ExtraMetadata.NormalizedTitle = p.app.normalizeTitle(p.entries[i])
+		}
+		merged = append(merged, i)
+	}
+	p.queueLocked(merged, true)
+}
+
+// mergeFeedEntry updates the cached entry from the feed's copy of it.
+func mergeFeedEntry(entry *FeedEntry, v FeedEntry) {
+	keepEarliestPublished(entry, v)
+	// Entries cached before these fields were added won't have them, so
+	// backfill them.
+	if entry.MediaGroup.Description == "" {
+		entry.MediaGroup.Description = v.MediaGroup.Description
+	}
+	if entry.ChannelID == "" {
+		entry.ChannelID = v.ChannelID
+	}
+	if entry.ExtraMetadata.FeedURL == "" {
+		entry.ExtraMetadata.FeedURL = v.ExtraMetadata.FeedURL
+	}
+	if entry.MediaGroup.Thumbnail.URL == "" {
+		entry.MediaGroup.Thumbnail.URL = v.MediaGroup.Thumbnail.URL
+	}
+	if entry.ExtraMetadata.PlaylistTitle == "" {
+		entry.ExtraMetadata.PlaylistTitle = v.ExtraMetadata.PlaylistTitle
+	}
+	// View and like counts change over time, so they are always taken
+	// from the latest feed.
+	entry.MediaGroup.Community = v.MediaGroup.Community
+}
+
+// queueLocked queues the entries at the given indices that need metadata and
+// haven't been queued yet, up to the budget. If visibleOnly is set and there
+// is a budget, entries that won't be shown in the picker are left for Finish.
+// p.mu must be held.
+func (p *metadataPipeline) queueLocked(candidates []int, visibleOnly bool) {
+	if p.app.ctx.Err() != nil {
+		// Interrupted, so nothing more is fetched
+		return
+	}
+	added := 0
+	for _, i := range candidates {
+		if p.budget == 0 {
+			break
+		}
+		if p.queued[i] || !p.app.needsMetadata(p.entries[i]) {
+			continue
+		}
+		if visibleOnly && p.visible != nil && !p.visible(p.entries[i]) {
+			continue
+		}
+		if p.budget > 0 {
+			p.budget--
+		}
+		p.queued[i] = true
+		p.queue = append(p.queue, i)
+		added++
+	}
+	if added > 0 {
+		p.progress.AddTotal(added)
+		p.cond.Broadcast()
+	}
+}
+
+func (p *metadataPipeline) worker() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		i := p.queue[0]
+		p.queue = p.queue[1:]
+		entry := p.entries[i]
+		p.mu.Unlock()
+		if p.app.ctx.Err() != nil {
+			// Interrupted, so the rest of the queue is dropped
+			continue
+		}
+
+		// The entry is copied out, since feeds may be merged into it
+		// while its metadata is fetched, and only the metadata is
+		// copied back.
+		p.progress.Start(entry.YTVideoID)
+		p.app.addMetadata(&entry)
+		p.progress.Finish(entry.YTVideoID, nil)
+
+		p.mu.Lock()
+		if p.app.ctx.Err() != nil {
+			// The metadata may be incomplete, since requests were
+			// aborted, so it's fetched again by the next run.
+			p.mu.Unlock()
+			continue
+		}
+		copyFetchedMetadata(&p.entries[i], entry)
+		p.done = append(p.done, i)
+		p.mu.Unlock()
+		p.saveCheckpoint()
+	}
+}
+
+// copyFetchedMetadata copies the metadata that addMetadata fetches.
+func copyFetchedMetadata(entry *FeedEntry, fetched FeedEntry) {
+	entry.ExtraMetadata.VideoDuration = fetched.ExtraMetadata.VideoDuration
+	entry.ExtraMetadata.LiveStatus = fetched.ExtraMetadata.LiveStatus
+	entry.ExtraMetadata.IsShort = fetched.ExtraMetadata.IsShort
+	entry.ExtraMetadata.SponsorBlock = fetched.ExtraMetadata.SponsorBlock
+	entry.ExtraMetadata.NormalizedTitle = fetched.ExtraMetadata.NormalizedTitle
+}
+
+// saveCheckpoint calls the checkpoint function with a copy of the entries, if
+// metadataCheckpointInterval has passed since the last checkpoint.
+func (p *metadataPipeline) saveCheckpoint() {
+	// Workers don't wait for a checkpoint that is being saved
+	if p.checkpoint == nil || !p.checkpointMu.TryLock() {
+		return
+	}
+	defer p.checkpointMu.Unlock()
+	p.mu.Lock()
+	if time.Since(p.lastCheckpoint) < metadataCheckpointInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastCheckpoint = time.Now()
+	entries, done := slices.Clone(p.entries), slices.Clone(p.done)
+	p.mu.Unlock()
+	err := p.checkpoint(entries, done)
+	if err != nil {
+		p.app.logger.Warnf("failed to save metadata checkpoint: %s\n", err)
+	}
+}
+
+// wait closes the queue, and waits for the workers to finish it.
+func (p *metadataPipeline) wait() []FeedEntry {
+	p.progress.ShowBar()
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+	p.wg.Wait()
+	p.progress.Close()
+	return p.entries
+}
+
+// Finish queues the entries that were left for after every feed arrived, up
+// to the budget, and waits for their metadata. It returns the entries, newest
+// first, and the number of entries whose metadata was deferred.
+func (p *metadataPipeline) Finish() ([]FeedEntry, int) {
+	p.mu.Lock()
+	var remaining []FeedEntry
+	var remainingIndices []int
+	for i, v := range p.entries {
+		if !p.queued[i] {
+			remaining = append(remaining, v)
+			remainingIndices = append(remainingIndices, i)
+		}
+	}
+	order := indices(len(remaining))
+	sort.SliceStable(order, func(i, j int) bool {
+		return remaining[order[i]].GetPublishedDate().After(remaining[order[j]].GetPublishedDate())
+	})
+	sortedRemaining := make([]FeedEntry, len(order))
+	for i, v := range order {
+		sortedRemaining[i] = remaining[v]
+	}
+	var scheduled []int
+	deferred := 0
+	if p.budget == 0 {
+		// The budget was used up while the feeds arrived
+		for _, v := range sortedRemaining {
+			if p.app.needsMetadata(v) {
+				deferred++
+			}
+		}
+	} else {
+		scheduled, deferred = p.app.prioritizeMetadata(sortedRemaining, p.budget)
+	}
+	var queue []int
+	for _, v := range scheduled {
+		queue = append(queue, remainingIndices[order[v]])
+	}
+	p.queueLocked(queue, false)
+	p.mu.Unlock()
+
+	entries := p.wait()
+	sortNewestFirst(entries)
+	return entries, deferred
+}
+
+// bulkAddMetadata adds metadata to the entries at the given indices, in order.
+// If checkpoint isn't nil, it is called every metadataCheckpointInterval with
+// a copy of the entries and the indices of the entries done so far, to save
+// them.
+func (a *App) bulkAddMetadata(entries []FeedEntry, indices []int, checkpoint func(entries []FeedEntry, done []int) error) []FeedEntry {
+	p := a.newMetadataPipeline(entries, a.newStreamingProgressReporter("metadata", a.printer.Sprintf("Adding metadata")), checkpoint)
+	p.mu.Lock()
+	p.queueLocked(indices, false)
+	p.mu.Unlock()
+	return p.wait()
+}
+
+// sortNewestFirst sorts the entries by their publish date, newest first.
+func sortNewestFirst(entries []FeedEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].GetPublishedDate().After(entries[j].GetPublishedDate())
+	})
+}
+
+// indices returns 0, 1, ..., n-1.
+func indices(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
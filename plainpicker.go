@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// runPlainPicker is a fallback for runPicker when fzf can't be used. The
+// lines are printed as a numbered list, and the user enters the numbers of
+// the lines to select, optionally preceded by the name of an action, e.g.
+// "download 1 3". The returned key is the action's key.
+func (a *App) runPlainPicker(fzfContent string, actions []pickerAction) (key string, selections []string, err error) {
+	lines := splitPickerLines(fzfContent)
+	printPickerLines(os.Stderr, lines)
+	var names []string
+	for _, v := range actions {
+		names = append(names, v.Name)
+	}
+	a.printer.Fprintf(os.Stderr, "Enter the numbers of the videos, optionally preceded by an action (%s). Leave empty to quit.\n", strings.Join(names, ", "))
+
+	stdin := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, "> ")
+		input, err := stdin.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if input == "" {
+			// Quit on an empty line or end of input
+			return "", nil, nil
+		}
+		key, selections, parseErr := a.parsePlainSelection(input, lines, actions)
+		if parseErr == nil {
+			return key, selections, nil
+		}
+		if err != nil {
+			return "", nil, parseErr
+		}
+		fmt.Fprintln(os.Stderr, parseErr)
+	}
+}
+
+func splitPickerLines(fzfContent string) []string {
+	if fzfContent == "" {
+		return nil
+	}
+	return strings.Split(fzfContent, "\n")
+}
+
+// printPickerLines prints the displayed part of the picker's lines as a
+// numbered list, without colors.
+func printPickerLines(w io.Writer, lines []string) {
+	for i, line := range lines {
+		display := pickerLineDisplay(line)
+		fmt.Fprintf(w, "%3d) %s\n", i+1, ansiEscapeRegex.ReplaceAllString(display, ""))
+	}
+}
+
+// parsePlainSelection parses the input to the plain picker. Actions that
+// aren't bound to a key are selected through the actions menu.
+func (a *App) parsePlainSelection(input string, lines []string, actions []pickerAction) (key string, selections []string, err error) {
+	fields := strings.Fields(strings.ReplaceAll(input, ",", " "))
+	key = "enter"
+	if _, err := strconv.Atoi(fields[0]); err != nil {
+		action, ok := findActionByName(actions, fields[0])
+		if !ok {
+			return "", nil, fmt.Errorf("unknown action: %s", fields[0])
+		}
+		key = action.Key
+		if key == "" {
+			key = a.ActionsMenuKey
+		}
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return "", nil, errors.New("no videos selected")
+	}
+	for _, v := range fields {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > len(lines) {
+			return "", nil, fmt.Errorf("invalid number: %s", v)
+		}
+		selections = append(selections, lines[n-1])
+	}
+	return key, selections, nil
+}
+
+// runPlainMenu is a fallback for runMenu when fzf can't be used.
+func runPlainMenu(prompt string, options []string) (selection string, ok bool, err error) {
+	for i, v := range options {
+		fmt.Fprintf(os.Stderr, "%3d) %s\n", i+1, v)
+	}
+	stdin := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, prompt)
+		input, err := stdin.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if input == "" {
+			return "", false, nil
+		}
+		n, parseErr := strconv.Atoi(input)
+		if parseErr == nil && n >= 1 && n <= len(options) {
+			return options[n-1], true, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		fmt.Fprintf(os.Stderr, "invalid number: %s\n", input)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// playEntry plays the entry, trying each of the configured playback sources
+// in order until one succeeds. If all of them fail, the failure is
+// classified from the player's output, and the user is offered ways to
+// recover, e.g. retrying with a different format.
+func (a *App) playEntry(entry FeedEntry, audioOnly bool) error {
+	sources := a.PlaybackSources
+	var extraArgs []string
+	for {
+		stderr, err := a.playWithResume(entry, sources, audioOnly, extraArgs)
+		if err == nil {
+			return nil
+		}
+		e, ok := err.(*exec.ExitError)
+		if !ok || e.ExitCode() == mpvExitCodeQuitBySignal {
+			// The player couldn't be started, or the user
+			// interrupted it. Neither can be recovered from.
+			return err
+		}
+
+		recovery, ok, menuErr := a.selectPlaybackRecovery(classifyPlaybackError(stderr))
+		if menuErr != nil {
+			return menuErr
+		}
+		if !ok {
+			return err
+		}
+		switch recovery {
+		case "retry":
+		case "retry-format":
+			extraArgs, err = splitCommandLine(a.RetryFormatArgs)
+			if err != nil {
+				return errors.Wrap(err, "parse retry format arguments")
+			}
+		case "invidious":
+			sources = []string{playbackSourceInvidious}
+		case "browser":
+			return openInBrowser(a.browserURL(entry))
+		}
+	}
+}
+
+// playWithResume plays the entry from the given sources. If the player is
+// mpv and ResumePlayback is enabled, playback resumes from where it last
+// stopped, and the new position is recorded when mpv quits. Videos played to
+// the end are marked as watched.
+func (a *App) playWithResume(entry FeedEntry, sources []string, audioOnly bool, extraArgs []string) (stderr string, err error) {
+	if !a.ResumePlayback || !a.isMPVPlayer() {
+		_, stderr, err = a.playFromSources(entry, sources, audioOnly, extraArgs)
+		return stderr, err
+	}
+
+	history, err := a.loadHistory()
+	if err != nil {
+		return "", err
+	}
+	watchLaterDir, err := os.MkdirTemp("", "yt-rss-watch-later-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(watchLaterDir)
+	args := append(mpvResumeArgs(history, entry.YTVideoID, watchLaterDir), extraArgs...)
+
+	source, stderr, playErr := a.playFromSources(entry, sources, audioOnly, args)
+	if source == playbackSourceBrowser {
+		return stderr, playErr
+	}
+	// mpv also saves the position when it is interrupted, so check for it
+	// even if playback failed.
+	position, ok, err := readSavedPosition(watchLaterDir)
+	if err != nil {
+		return "", errors.Wrap(err, "read playback position")
+	}
+	if ok || playErr == nil {
+		err = a.recordPlaybackPosition(entry, position, !ok)
+		if err != nil {
+			return "", errors.Wrap(err, "record playback position")
+		}
+	}
+	return stderr, playErr
+}
+
+// Playback sources, tried in the order configured in PlaybackSources.
+const (
+	playbackSourceLocal     = "local"     // The downloaded file, if any
+	playbackSourceYouTube   = "youtube"   // Stream from YouTube, or through the Invidious or Piped backend if one is set
+	playbackSourceInvidious = "invidious" // Stream through InvidiousInstance
+	playbackSourceBrowser   = "browser"   // Open the watch page in the browser
+)
+
+// playFromSources tries to play the entry from each source in order, moving
+// on to the next source when the player fails. The source that played the
+// entry is returned. The stderr and error of the last failed attempt are
+// returned if all sources fail.
+func (a *App) playFromSources(entry FeedEntry, sources []string, audioOnly bool, extraArgs []string) (source string, stderr string, err error) {
+	err = errors.New("no playback sources available")
+	for _, source = range sources {
+		var url string
+		switch source {
+		case playbackSourceLocal:
+			if !a.PreferLocalFiles {
+				continue
+			}
+			path, ok, findErr := a.findDownloadedFile(entry.YTVideoID)
+			if findErr != nil {
+				return source, "", findErr
+			}
+			if !ok {
+				continue
+			}
+			url = path
+		case playbackSourceYouTube:
+			url = a.playbackURL(entry)
+		case playbackSourceInvidious:
+			if a.InvidiousInstance == "" {
+				continue
+			}
+			url = strings.TrimSuffix(a.InvidiousInstance, "/") + "/watch?v=" + entry.YTVideoID
+		case playbackSourceBrowser:
+			a.printer.Fprintf(os.Stderr, "Opening %s in the browser\n", a.browserURL(entry))
+			return source, "", openInBrowser(a.browserURL(entry))
+		default:
+			return source, "", fmt.Errorf("unknown playback source: %s", source)
+		}
+
+		a.printer.Fprintf(os.Stderr, "Playing %s\n", url)
+		stderr, err = a.runPlayer(entry, url, audioOnly, extraArgs)
+		if err == nil {
+			return source, "", nil
+		}
+		if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() == mpvExitCodeQuitBySignal {
+			return source, stderr, err
+		}
+		a.printer.Fprintf(os.Stderr, "Playback from %s failed\n", source)
+	}
+	return source, stderr, err
+}
+
+// mpvExitCodeQuitBySignal is mpv's exit code when it is interrupted, e.g.
+// with ctrl-c.
+const mpvExitCodeQuitBySignal = 4
+
+// runPlayer runs the configured player command. The command is a template
+// where {url} and {title} are replaced with the URL and the entry's title. If
+// the template does not reference {url}, the URL is appended as the last
+// argument. If audioOnly is true, AudioOnlyArgs are passed to the player.
+// extraArgs are passed to the player right after the binary. The player's
+// stderr is shown to the user, and also returned. The now-playing file is
+// written while the player runs.
+func (a *App) runPlayer(entry FeedEntry, url string, audioOnly bool, extraArgs []string) (stderr string, err error) {
+	args, err := splitCommandLine(a.PlayerCommand)
+	if err != nil {
+		return "", errors.Wrap(err, "parse player command")
+	}
+	if len(args) == 0 {
+		return "", errors.New("player command is empty")
+	}
+	if audioOnly {
+		audioArgs, err := splitCommandLine(a.AudioOnlyArgs)
+		if err != nil {
+			return "", errors.Wrap(err, "parse audio-only arguments")
+		}
+		extraArgs = append(audioArgs, extraArgs...)
+	}
+	extraArgs = append(extraArgs, a.sponsorScriptOptArgs(entry)...)
+	args = append(args[:1], append(extraArgs, args[1:]...)...)
+
+	title := entry.MediaGroup.Title
+	if entry.ExtraMetadata.NormalizedTitle != "" {
+		title = entry.ExtraMetadata.NormalizedTitle
+	}
+	if !strings.Contains(a.PlayerCommand, "{url}") {
+		args = append(args, url)
+	}
+	replacer := strings.NewReplacer("{url}", url, "{title}", title)
+	for i := range args {
+		args[i] = replacer.Replace(args[i])
+	}
+
+	player, err := findPlayer(args[0])
+	if err != nil {
+		return "", err
+	}
+	b := &bytes.Buffer{}
+	cmd := exec.Command(player, args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, b)
+	defer a.writeNowPlaying(entry, title)()
+	err = cmd.Run()
+	return b.String(), err
+}
+
+// classifyPlaybackError returns a short description of why playback failed,
+// based on the player's (or yt-dlp's) error output.
+func classifyPlaybackError(stderr string) string {
+	lower := strings.ToLower(stderr)
+	switch {
+	case strings.Contains(lower, "requested format is not available"):
+		return "requested format is not available"
+	case strings.Contains(lower, "http error 403"):
+		return "access forbidden (HTTP 403)"
+	case strings.Contains(lower, "http error 429") || strings.Contains(lower, "too many requests"):
+		return "throttled by YouTube (HTTP 429)"
+	case strings.Contains(lower, "sign in to confirm"):
+		return "YouTube requires signing in"
+	default:
+		return "unknown error"
+	}
+}
+
+// selectPlaybackRecovery asks the user how to recover from a playback
+// failure. ok is false if the user chose not to recover.
+func (a *App) selectPlaybackRecovery(reason string) (recovery string, ok bool, err error) {
+	options := []string{
+		"retry         Retry with the same settings",
+		"retry-format  Retry with a different format (" + a.RetryFormatArgs + ")",
+	}
+	if a.InvidiousInstance != "" {
+		options = append(options, "invidious     Retry via Invidious ("+a.InvidiousInstance+")")
+	}
+	options = append(options,
+		"browser       Open in the browser",
+		"cancel        Give up",
+	)
+	selection, ok, err := a.runMenu(a.printer.Sprintf("Playback failed: %s > ", reason), options)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	recovery = strings.Fields(selection)[0]
+	if recovery == "cancel" {
+		return "", false, nil
+	}
+	return recovery, true, nil
+}
+
+// windowsPlayerPaths are where players are installed on Windows by
+// installers that don't add them to PATH, by program name. The first element
+// of each path is the environment variable of the directory it's in.
+var windowsPlayerPaths = map[string][][]string{
+	"mpv": {
+		{"ProgramFiles", "mpv", "mpv.exe"},
+		{"LOCALAPPDATA", "Programs", "mpv", "mpv.exe"},
+		{"USERPROFILE", "scoop", "apps", "mpv", "current", "mpv.exe"},
+	},
+	"vlc": {
+		{"ProgramFiles", "VideoLAN", "VLC", "vlc.exe"},
+		{"ProgramFiles(x86)", "VideoLAN", "VLC", "vlc.exe"},
+	},
+}
+
+// findPlayer returns the path of the player program in PATH, or on Windows,
+// in the directory it's usually installed in if it isn't in PATH.
+func findPlayer(program string) (string, error) {
+	p, err := exec.LookPath(program)
+	if err == nil || runtime.GOOS != "windows" {
+		return p, err
+	}
+	for _, v := range windowsPlayerPaths[programName(program)] {
+		dir := os.Getenv(v[0])
+		if dir == "" {
+			continue
+		}
+		candidate := filepath.Join(append([]string{dir}, v[1:]...)...)
+		if fileExists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", err
+}
+
+// splitCommandLine splits a command line into arguments the way a shell
+// would, honoring single quotes, double quotes, and backslash escapes. On
+// Windows, backslashes are path separators rather than escapes, so that
+// players can be given as e.g. C:\Programs\mpv\mpv.exe.
+func splitCommandLine(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'' && runtime.GOOS != "windows":
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// getPreviewCommand returns the command fzf runs to render the preview pane.
+// {1} is the first field of the line, which is the video ID. The image viewer
+// is passed on, since it can be set for a single run with --thumbnails.
+func (a *App) getPreviewCommand() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", errors.Wrap(err, "find executable")
+	}
+	command := shellQuote(executable) + " preview"
+	if a.PreviewImageViewer != "" {
+		command += " --image-viewer=" + shellQuote(a.PreviewImageViewer)
+	}
+	return command + " {1}", nil
+}
+
+// shellQuote quotes s so that it's interpreted as a single word by the shell
+// that fzf runs commands with: a POSIX shell, or on Windows, cmd.exe unless
+// $SHELL is set, e.g. in Git Bash.
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" && os.Getenv("SHELL") == "" {
+		// cmd.exe has no escapes, but paths can't contain double quotes
+		return `"` + s + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runPreview prints details about a cached video. It is used to render fzf's
+// preview pane, and isn't meant to be invoked directly.
+func (a *App) runPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	fs.StringVar(&a.PreviewImageViewer, "image-viewer", a.PreviewImageViewer, "Image viewer to render the thumbnail with")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: yt-rss preview [--image-viewer <viewer>] <video-id>")
+	}
+	videoID := fs.Arg(0)
+
+	entry, err := a.findEntry(videoID)
+	if err != nil {
+		return err
+	}
+
+	if a.PreviewImageViewer != "" && entry.MediaGroup.Thumbnail.URL != "" {
+		err := a.printThumbnail(entry)
+		if err != nil {
+			// The thumbnail is nice to have; the rest of the
+			// preview is still useful without it.
+			fmt.Printf("(thumbnail unavailable: %s)\n", err)
+		}
+		fmt.Println()
+	}
+
+	// fzf renders hyperlinks in the preview pane
+	return a.printEntry(entry, true)
+}
+
+// findEntry returns the video from the cache, or from the watch-later list if
+// it has expired from the cache.
+func (a *App) findEntry(videoID string) (FeedEntry, error) {
+	entries, err := a.getFromCache()
+	if err != nil {
+		return FeedEntry{}, err
+	}
+	entry, ok := findEntryByVideoID(entries, videoID)
+	if ok {
+		return entry, nil
+	}
+	entry, ok, err = a.findWatchLaterEntry(videoID)
+	if err != nil {
+		return FeedEntry{}, err
+	}
+	if ok {
+		return entry, nil
+	}
+	// Watched videos that dropped out of the cache are still in the
+	// history, e.g. when previewed from `yt-rss history`
+	history, err := a.loadHistory()
+	if err != nil {
+		return FeedEntry{}, err
+	}
+	if record, ok := history[videoID]; ok {
+		return a.entryFromWatchRecord(record), nil
+	}
+	return FeedEntry{}, fmt.Errorf("video not found in cache: %s", videoID)
+}
+
+// printEntry prints the details of the entry and its description, for the
+// preview pane and `yt-rss show`.
+func (a *App) printEntry(entry FeedEntry, hyperlinks bool) error {
+	videoID := entry.YTVideoID
+	bold := color.New(color.Bold).SprintFunc()
+	fmt.Println(bold(entry.MediaGroup.Title))
+	fmt.Println()
+	fmt.Printf("%s %s\n", bold("Channel:  "), a.theme["channel"].Sprint(entry.Author.Name))
+	if entry.ExtraMetadata.PlaylistTitle != "" {
+		fmt.Printf("%s %s\n", bold("Playlist: "), a.theme["category"].Sprint(entry.ExtraMetadata.PlaylistTitle))
+	}
+	publishedDate, err := entry.PublishedDate()
+	switch {
+	case entry.Published == "":
+		// Unknown for videos that are only in the history
+	case err != nil:
+		fmt.Printf("%s %s\n", bold("Published:"), color.RedString(err.Error()))
+	default:
+		published := a.localTime(publishedDate).Format("Mon, 02 Jan 2006 15:04 MST")
+		if localizer := a.dateLocalizer(); localizer != nil {
+			published = localizer.Replace(published)
+		}
+		fmt.Printf("%s %s (%s)\n", bold("Published:"), a.theme["date"].Sprint(published), a.formatRelativeTime(publishedDate, time.Now()))
+	}
+	if entry.ExtraMetadata.LiveStatus != "" {
+		fmt.Printf("%s %s\n", bold("Status:   "), a.theme["live"].Sprint(strings.ToUpper(entry.ExtraMetadata.LiveStatus)))
+	} else {
+		fmt.Printf("%s %s\n", bold("Duration: "), a.theme["duration"].Sprint(entry.ExtraMetadata.VideoDuration.String()))
+	}
+	if entry.Views() > 0 {
+		fmt.Printf("%s %d, %d likes\n", bold("Views:    "), entry.Views(), entry.Likes())
+	}
+	if data := entry.ExtraMetadata.SponsorBlock; a.SponsorBlock && data != nil && len(data.Segments) > 0 {
+		fmt.Printf("%s %s in %d segments\n", bold("Sponsors: "), formatDuration(sponsorTime(entry)), len(data.Segments))
+	}
+	fmt.Printf("%s %s\n", bold("URL:      "), entry.MediaGroup.Content.URL)
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	if record, ok := history[videoID]; ok && !record.WatchedAt.IsZero() {
+		fmt.Printf("%s %s\n", bold("Watched:  "), a.localTime(record.WatchedAt).Format("Mon, 02 Jan 2006 15:04"))
+	}
+	if record, ok := history[videoID]; ok && record.PlayCount > 0 {
+		fmt.Printf("%s %d times, last on %s\n", bold("Played:   "), record.PlayCount, a.localTime(record.LastPlayedAt).Format("Mon, 02 Jan 2006 15:04"))
+	}
+	if record, ok := history[videoID]; ok && record.ResumePosition > 0 {
+		fmt.Printf("%s %s\n", bold("Resume at:"), formatDuration(record.ResumePosition))
+	}
+	fmt.Println()
+	printDescription(entry, hyperlinks)
+	return nil
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
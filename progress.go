@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/text/message"
+)
+
+// Progress output formats, set with --progress.
+const (
+	progressFormatBar  = "bar"  // A progress bar in the terminal, or a progressDisplay during a refresh
+	progressFormatJSON = "json" // JSON lines on stderr, one per progressEvent
+	progressFormatNone = "none" // No progress output
+)
+
+// progressEvent is emitted for each step of a refresh when the progress
+// format is "json", so that other programs can render their own progress. A
+// stage (fetching feeds, or adding metadata) emits stage_started, then
+// started and finished (or error) for each item, then stage_finished. Metadata
+// is fetched while feeds are still arriving, so the two stages overlap, and
+// the metadata stage's total grows as feeds arrive.
+type progressEvent struct {
+	Time  time.Time `json:"time"`
+	Stage string    `json:"stage"`
+	Event string    `json:"event"`
+	Item  string    `json:"item,omitempty"` // Feed URL or video ID
+	Error string    `json:"error,omitempty"`
+	Done  int       `json:"done"`
+	Total int       `json:"total"`
+}
+
+// progressReporter reports the progress of a stage of a refresh, in the
+// configured progress format. It is safe for concurrent use.
+type progressReporter struct {
+	stage       string
+	description string
+	total       int
+	done        int
+	failed      int
+	bar         *progressbar.ProgressBar
+	deferredBar bool             // The bar is shown on ShowBar. See newStreamingProgressReporter.
+	display     *progressDisplay // Shows the stage instead of the bar, if set
+
+	encoder *json.Encoder
+	mu      sync.Mutex
+}
+
+func (a *App) newProgressReporter(stage, description string, total int) *progressReporter {
+	p := &progressReporter{stage: stage, description: description, total: total}
+	if a.progressFormat == progressFormatJSON {
+		p.encoder = json.NewEncoder(os.Stderr)
+		p.emit("stage_started", "", nil)
+	} else if a.progressDisplay != nil {
+		p.display = a.progressDisplay
+		p.show()
+	} else if a.progressFormat == progressFormatBar {
+		p.bar = progressbar.Default(int64(total), description)
+	}
+	return p
+}
+
+// newStreamingProgressReporter returns a reporter for a stage whose items
+// arrive while an earlier stage is still running. Its total grows with
+// AddTotal, and its progress bar isn't shown until ShowBar is called, so that
+// it isn't drawn over the earlier stage's bar.
+func (a *App) newStreamingProgressReporter(stage, description string) *progressReporter {
+	p := &progressReporter{stage: stage, description: description}
+	if a.progressFormat == progressFormatJSON {
+		p.encoder = json.NewEncoder(os.Stderr)
+		p.emit("stage_started", "", nil)
+	} else if a.progressDisplay != nil {
+		p.display = a.progressDisplay
+		p.show()
+	} else if a.progressFormat == progressFormatBar {
+		p.deferredBar = true
+	}
+	return p
+}
+
+// AddTotal adds items to the stage.
+func (p *progressReporter) AddTotal(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += n
+	if p.bar != nil {
+		p.bar.ChangeMax(p.total)
+	}
+	p.show()
+}
+
+// ShowBar shows the deferred progress bar, with the items done so far.
+func (p *progressReporter) ShowBar() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.deferredBar {
+		return
+	}
+	p.deferredBar = false
+	p.bar = progressbar.Default(int64(p.total), p.description)
+	p.bar.Set(p.done)
+}
+
+// Start reports that work on the item has started.
+func (p *progressReporter) Start(item string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit("started", item, nil)
+}
+
+// Finish reports that work on the item has finished, or failed if err is
+// not nil.
+func (p *progressReporter) Finish(item string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if p.bar != nil {
+		p.bar.Add(1)
+	}
+	if err != nil {
+		p.failed++
+		p.emit("error", item, err)
+	} else {
+		p.emit("finished", item, nil)
+	}
+	p.show()
+}
+
+// Close reports that the stage has finished.
+func (p *progressReporter) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit("stage_finished", "", nil)
+}
+
+func (p *progressReporter) emit(event, item string, err error) {
+	if p.encoder == nil {
+		return
+	}
+	e := progressEvent{
+		Time:  time.Now(),
+		Stage: p.stage,
+		Event: event,
+		Item:  item,
+		Done:  p.done,
+		Total: p.total,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	p.encoder.Encode(e)
+}
+
+// show updates the stage's counts in the progress display, if there is one.
+func (p *progressReporter) show() {
+	if p.display != nil {
+		p.display.update(p.stage, p.description, p.done, p.total, p.failed)
+	}
+}
+
+// progressDisplayInterval is how often the progress display is redrawn, so
+// that the elapsed time stays current.
+const progressDisplayInterval = 200 * time.Millisecond
+
+// progressCounts are the counts of a stage shown by the progress display.
+type progressCounts struct {
+	stage       string
+	description string
+	done        int
+	total       int
+	failed      int
+}
+
+// progressDisplay shows the progress of every stage of a refresh on one line
+// on stderr, e.g. "Fetching feeds 40/42, 1 failed | Adding metadata 30/120 |
+// 5s", instead of a progress bar per stage. The stages overlap, so separate
+// bars would be drawn over each other. Nothing is drawn if stderr isn't a
+// terminal. It is safe for concurrent use.
+type progressDisplay struct {
+	mu      sync.Mutex
+	start   time.Time
+	stages  []*progressCounts // In the order they started
+	draw    bool
+	stop    chan struct{}
+	stopped chan struct{}
+	printer *message.Printer
+}
+
+func newProgressDisplay(printer *message.Printer) *progressDisplay {
+	d := &progressDisplay{
+		printer: printer,
+		start:   time.Now(),
+		draw:    !isDumbTerminal() && (isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd())),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+func (d *progressDisplay) run() {
+	defer close(d.stopped)
+	ticker := time.NewTicker(progressDisplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.redraw()
+		}
+	}
+}
+
+// update sets the counts of the stage, adding it if it's new.
+func (d *progressDisplay) update(stage, description string, done, total, failed int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, v := range d.stages {
+		if v.stage == stage {
+			v.done, v.total, v.failed = done, total, failed
+			return
+		}
+	}
+	d.stages = append(d.stages, &progressCounts{stage: stage, description: description, done: done, total: total, failed: failed})
+}
+
+func (d *progressDisplay) redraw() {
+	if !d.draw {
+		return
+	}
+	d.mu.Lock()
+	var parts []string
+	for _, v := range d.stages {
+		if v.failed > 0 {
+			parts = append(parts, d.printer.Sprintf("%s %d/%d, %d failed", v.description, v.done, v.total, v.failed))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s %d/%d", v.description, v.done, v.total))
+		}
+	}
+	parts = append(parts, time.Since(d.start).Round(time.Second).String())
+	d.mu.Unlock()
+	// Clear the rest of the line, in case the previous line was longer
+	fmt.Fprintf(os.Stderr, "\r%s\x1b[K", strings.Join(parts, " | "))
+}
+
+// Close stops drawing the display, and clears its line.
+func (d *progressDisplay) Close() {
+	close(d.stop)
+	<-d.stopped
+	if d.draw {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+	}
+}
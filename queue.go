@@ -0,0 +1,339 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// queueItem is an entry in the play queue. Like watch-later items, the entry
+// is copied into the state, so that it stays queued after it has expired from
+// the cache.
+type queueItem struct {
+	Entry   FeedEntry `json:"entry"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// InQueue returns true if the video is in the play queue.
+func (s *State) InQueue(videoID string) bool {
+	for _, v := range s.Queue {
+		if v.Entry.YTVideoID == videoID {
+			return true
+		}
+	}
+	return false
+}
+
+// AddToQueue adds the entry to the end of the play queue, unless it is
+// already in it. It returns false if it was.
+func (s *State) AddToQueue(entry FeedEntry) bool {
+	if s.InQueue(entry.YTVideoID) {
+		return false
+	}
+	s.Queue = append(s.Queue, queueItem{Entry: entry, AddedAt: time.Now()})
+	return true
+}
+
+// RemoveFromQueue removes the video from the play queue. It returns false if
+// it wasn't in the queue.
+func (s *State) RemoveFromQueue(videoID string) bool {
+	var kept []queueItem
+	for _, v := range s.Queue {
+		if v.Entry.YTVideoID != videoID {
+			kept = append(kept, v)
+		}
+	}
+	removed := len(kept) != len(s.Queue)
+	s.Queue = kept
+	return removed
+}
+
+// QueueEntries returns the entries in the play queue, in order.
+func (s *State) QueueEntries() []FeedEntry {
+	var entries []FeedEntry
+	for _, v := range s.Queue {
+		entries = append(entries, v.Entry)
+	}
+	return entries
+}
+
+// enqueueAction adds the entries to the play queue, in the order they were
+// selected, and reopens the picker so that more can be added.
+func (a *App) enqueueAction(entries []FeedEntry, state *State) (bool, error) {
+	added := 0
+	for _, entry := range entries {
+		if state.AddToQueue(entry) {
+			added++
+		}
+	}
+	a.printer.Fprintf(os.Stderr, "Added %d videos to the queue\n", added)
+	if added == 0 {
+		return true, nil
+	}
+	return true, a.saveStateWithUndo(state, fmt.Sprintf("add %d entries to the queue", added))
+}
+
+// removePlayedFromQueue removes a video that was played to the end from the
+// queue. The state is loaded again, since videos may have been queued in
+// another yt-rss while the queue was playing.
+func (a *App) removePlayedFromQueue(entry FeedEntry) error {
+	state, err := a.loadState()
+	if err != nil {
+		return err
+	}
+	if !state.RemoveFromQueue(entry.YTVideoID) {
+		return nil
+	}
+	return a.saveState(state)
+}
+
+// runQueue manages the play queue: `queue add <video>...` queues videos from
+// the cache, `queue remove <video>...` removes them, `queue list` prints the
+// queue, `queue clear` empties it, and `queue play` plays it. Videos are given
+// by ID or watch URL. Unlike the watch-later list, which is browsed in the
+// picker, the queue is played in order.
+func (a *App) runQueue(args []string) error {
+	usage := errors.New("usage: yt-rss queue <add|remove|list|clear|play> [video ID or URL...]")
+	if len(args) < 1 {
+		return usage
+	}
+	state, err := a.loadState()
+	if err != nil {
+		return err
+	}
+	if args[0] == "play" {
+		fs := flag.NewFlagSet("queue play", flag.ExitOnError)
+		audio := fs.Bool("audio", a.AudioOnly, "Play audio only")
+		fs.Parse(args[1:])
+		return a.playQueue(state, *audio)
+	}
+
+	videoIDs := make([]string, 0, len(args)-1)
+	for _, v := range args[1:] {
+		if id := parseVideoIDFromURL(v); id != "" {
+			v = id
+		}
+		videoIDs = append(videoIDs, v)
+	}
+	switch args[0] {
+	case "add":
+		if len(videoIDs) == 0 {
+			return usage
+		}
+		if err := a.checkWritable(); err != nil {
+			return err
+		}
+		entries, err := a.getFromCache()
+		if err != nil {
+			return err
+		}
+		added := 0
+		for _, videoID := range videoIDs {
+			entry, ok := findEntryByVideoID(entries, videoID)
+			if !ok {
+				return fmt.Errorf("video not found in cache: %s", videoID)
+			}
+			if state.AddToQueue(entry) {
+				added++
+			}
+		}
+		a.printer.Fprintf(os.Stderr, "Added %d videos to the queue\n", added)
+		if added == 0 {
+			return nil
+		}
+		return a.saveStateWithUndo(state, fmt.Sprintf("add %d entries to the queue", added))
+	case "remove":
+		if len(videoIDs) == 0 {
+			return usage
+		}
+		if err := a.checkWritable(); err != nil {
+			return err
+		}
+		removed := 0
+		for _, videoID := range videoIDs {
+			if state.RemoveFromQueue(videoID) {
+				removed++
+			}
+		}
+		a.printer.Fprintf(os.Stderr, "Removed %d videos from the queue\n", removed)
+		if removed == 0 {
+			return nil
+		}
+		return a.saveStateWithUndo(state, fmt.Sprintf("remove %d entries from the queue", removed))
+	case "clear":
+		if err := a.checkWritable(); err != nil {
+			return err
+		}
+		removed := len(state.Queue)
+		state.Queue = nil
+		a.printer.Fprintf(os.Stderr, "Removed %d videos from the queue\n", removed)
+		if removed == 0 {
+			return nil
+		}
+		return a.saveStateWithUndo(state, fmt.Sprintf("clear %d entries from the queue", removed))
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, v := range state.Queue {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+				i+1,
+				v.Entry.YTVideoID,
+				a.formatEntryDuration(v.Entry),
+				v.Entry.Author.Name,
+				v.Entry.ExtraMetadata.NormalizedTitle,
+			)
+		}
+		return w.Flush()
+	default:
+		return usage
+	}
+}
+
+// playQueue plays the queue in order, removing videos from it as they are
+// played to the end. With mpv, the whole queue is given to a single mpv as a
+// playlist. Other players are started for each video in turn, and a video
+// counts as played to the end when the player exits successfully.
+func (a *App) playQueue(state *State, audio bool) error {
+	entries := state.QueueEntries()
+	if len(entries) == 0 {
+		a.printer.Fprintf(os.Stderr, "The queue is empty\n")
+		return nil
+	}
+	if a.isMPVPlayer() {
+		return a.playQueueInMPV(entries, audio)
+	}
+	for _, entry := range entries {
+		// Loaded again for each video, since removePlayedFromQueue saved
+		// the state after the previous one, and playEntries saves it too
+		state, err := a.loadState()
+		if err != nil {
+			return err
+		}
+		err = a.playEntries([]FeedEntry{entry}, state, audio)
+		if err != nil {
+			return err
+		}
+		err = a.removePlayedFromQueue(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// playQueueInMPV starts mpv idle, loads the entries into its playlist over
+// IPC, and follows its events until it quits after the last entry. Loading
+// them after connecting makes sure no events are missed, and numbers the
+// playlist entries in the queue's order. Each video is recorded as played
+// when it starts, and removed from the queue, and marked as watched if
+// ResumePlayback is set, when it plays to the end.
+//
+// Arguments of the player command that refer to a single video, i.e. {url}
+// and {title}, are left out. If the command sets a title with {title}, each
+// video's title is set when it starts instead.
+func (a *App) playQueueInMPV(entries []FeedEntry, audio bool) error {
+	args, err := splitCommandLine(a.PlayerCommand)
+	if err != nil {
+		return errors.Wrap(err, "parse player command")
+	}
+	if len(args) == 0 {
+		return errors.New("player command is empty")
+	}
+	socketPath := getMPVQueueSocketPath()
+	playerArgs := []string{"--input-ipc-server=" + socketPath, "--idle=once"}
+	if audio {
+		audioArgs, err := splitCommandLine(a.AudioOnlyArgs)
+		if err != nil {
+			return errors.Wrap(err, "parse audio-only arguments")
+		}
+		playerArgs = append(playerArgs, audioArgs...)
+	}
+	setTitle := false
+	for _, v := range args[1:] {
+		if strings.Contains(v, "{title}") {
+			setTitle = true
+			continue
+		}
+		if strings.Contains(v, "{url}") {
+			continue
+		}
+		playerArgs = append(playerArgs, v)
+	}
+
+	player, err := findPlayer(args[0])
+	if err != nil {
+		return err
+	}
+	os.Remove(socketPath)
+	cmd := exec.Command(player, playerArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	if err != nil {
+		return errors.Wrap(err, "start player")
+	}
+	client, err := waitForMPV(socketPath)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	defer client.Close()
+	for _, entry := range entries {
+		url, err := a.mpvPlaylistURL(entry)
+		if err != nil {
+			return err
+		}
+		_, err = client.Command("loadfile", url, "append-play")
+		if err != nil {
+			return err
+		}
+	}
+
+	doneNowPlaying := func() {}
+	for {
+		event, err := client.ReadEvent()
+		if err != nil {
+			// mpv has quit
+			break
+		}
+		i := event.PlaylistEntryID - 1
+		if i < 0 || i >= len(entries) {
+			continue
+		}
+		entry := entries[i]
+		switch event.Event {
+		case "start-file":
+			title := entry.MediaGroup.Title
+			if entry.ExtraMetadata.NormalizedTitle != "" {
+				title = entry.ExtraMetadata.NormalizedTitle
+			}
+			if setTitle {
+				client.Command("set_property", "force-media-title", title)
+			}
+			a.printer.Fprintf(os.Stderr, "Playing %s\n", entry.WatchURL())
+			doneNowPlaying = a.writeNowPlaying(entry, title)
+			err = a.recordPlay(entry)
+		case "end-file":
+			doneNowPlaying()
+			doneNowPlaying = func() {}
+			if event.Reason != "eof" {
+				continue
+			}
+			err = a.removePlayedFromQueue(entry)
+			if err == nil && a.ResumePlayback {
+				err = a.recordPlaybackPosition(entry, 0, true)
+			}
+		}
+		if err != nil {
+			a.logger.Warnf("%s\n", err)
+		}
+	}
+	doneNowPlaying()
+	return cmd.Wait()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Bounds for the interval between metadata requests while backing off
+const (
+	minBackoffInterval = 250 * time.Millisecond
+	maxBackoffInterval = 10 * time.Second
+)
+
+// rateLimiter spaces out requests shared between concurrent workers. The
+// interval between requests is jittered, so that requests don't arrive in
+// lockstep, and is increased whenever YouTube responds with a 429. It
+// recovers gradually as requests succeed again.
+type rateLimiter struct {
+	mu          sync.Mutex
+	minInterval time.Duration // Configured interval, zero if unlimited
+	interval    time.Duration // Current interval, after backing off
+	next        time.Time     // Earliest time of the next request
+}
+
+func newRateLimiter(requestsPerSecond int) *rateLimiter {
+	var interval time.Duration
+	if requestsPerSecond > 0 {
+		interval = time.Second / time.Duration(requestsPerSecond)
+	}
+	return &rateLimiter{minInterval: interval, interval: interval}
+}
+
+// Wait blocks until the next request may be made, or the context is
+// cancelled.
+func (l *rateLimiter) Wait(ctx context.Context) {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	if l.interval > 0 {
+		// Jitter by up to ±50% of the interval
+		jitter := time.Duration(rand.Int63n(int64(l.interval))) - l.interval/2
+		l.next = l.next.Add(l.interval + jitter)
+	}
+	l.mu.Unlock()
+	sleepContext(ctx, wait)
+}
+
+// Throttle doubles the interval between requests, after being rate limited.
+func (l *rateLimiter) Throttle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval *= 2
+	if l.interval < minBackoffInterval {
+		l.interval = minBackoffInterval
+	}
+	if l.interval > maxBackoffInterval {
+		l.interval = maxBackoffInterval
+	}
+}
+
+// Recover shortens the interval after a successful request, until it is
+// back to the configured interval.
+func (l *rateLimiter) Recover() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.interval <= l.minInterval {
+		return
+	}
+	l.interval = l.interval * 9 / 10
+	if l.interval < l.minInterval || l.interval < minBackoffInterval {
+		l.interval = l.minInterval
+	}
+}
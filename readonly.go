@@ -0,0 +1,17 @@
+package main
+
+import "github.com/pkg/errors"
+
+// errReadOnly is returned by commands and actions that would make changes
+// in read-only mode. Implicit changes, like refreshing the cache or
+// recording plays, are skipped silently instead, so that browsing and
+// playback still work.
+var errReadOnly = errors.New("can't make changes in read-only mode")
+
+// checkWritable returns errReadOnly in read-only mode.
+func (a *App) checkWritable() error {
+	if a.ReadOnly {
+		return errReadOnly
+	}
+	return nil
+}
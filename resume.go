@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isMPVPlayer returns true if the player command runs mpv, which supports
+// saving and resuming the playback position.
+func (a *App) isMPVPlayer() bool {
+	args, err := splitCommandLine(a.PlayerCommand)
+	if err != nil || len(args) == 0 {
+		return false
+	}
+	return programName(args[0]) == "mpv"
+}
+
+// programName returns the name of the program, without its directory, or
+// its extension on Windows, e.g. "mpv" for C:\Program Files\mpv\mpv.exe.
+func programName(program string) string {
+	name := filepath.Base(program)
+	if runtime.GOOS == "windows" {
+		name = strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	return name
+}
+
+// mpvResumeArgs returns the arguments that make mpv start at the video's
+// resume position, and save the position to watchLaterDir when it quits
+// before the end of the video.
+func mpvResumeArgs(history History, videoID string, watchLaterDir string) []string {
+	args := []string{
+		"--save-position-on-quit",
+		"--watch-later-directory=" + watchLaterDir,
+	}
+	if record, ok := history[videoID]; ok && record.ResumePosition > 0 {
+		args = append(args, fmt.Sprintf("--start=%d", int(record.ResumePosition.Seconds())))
+	}
+	return args
+}
+
+// readSavedPosition returns the playback position mpv saved to
+// watchLaterDir. ok is false if mpv didn't save a position, which happens
+// when the video was played to the end.
+func readSavedPosition(watchLaterDir string) (position time.Duration, ok bool, err error) {
+	files, err := os.ReadDir(watchLaterDir)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, v := range files {
+		position, ok, err := readStartOption(filepath.Join(watchLaterDir, v.Name()))
+		if err != nil || ok {
+			return position, ok, err
+		}
+	}
+	return 0, false, nil
+}
+
+// readStartOption reads the start option from an mpv watch later file.
+func readStartOption(fileName string) (position time.Duration, ok bool, err error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "start=")
+		if !found {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false, err
+		}
+		return time.Duration(seconds * float64(time.Second)), true, nil
+	}
+	return 0, false, scanner.Err()
+}
+
+// recordPlaybackPosition stores where playback of the entry stopped. Videos
+// played to the end are marked as watched, and will start from the beginning
+// if played again.
+func (a *App) recordPlaybackPosition(entry FeedEntry, position time.Duration, finished bool) error {
+	history, err := a.loadHistory()
+	if err != nil {
+		return err
+	}
+	record := history.getOrCreateRecord(entry)
+	if finished {
+		record.ResumePosition = 0
+		record.WatchedAt = time.Now()
+	} else {
+		record.ResumePosition = position
+	}
+	return a.saveHistory(history)
+}
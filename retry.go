@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// httpGet performs a GET request, retrying transient failures with
+// exponential backoff. Network errors, 429s, and 5xx responses are retried up
+// to RetryAttempts times. If the server sends a Retry-After header on a 429,
+// it is honored instead of the computed backoff.
+func (a *App) httpGet(url string) (*http.Response, error) {
+	return a.httpDo(a.httpClient, http.MethodGet, url, nil)
+}
+
+// httpPostJSON performs a POST request with the JSON body, retrying transient
+// failures the same way as httpGet.
+func (a *App) httpPostJSON(url string, body []byte) (*http.Response, error) {
+	return a.httpDo(a.httpClient, http.MethodPost, url, body)
+}
+
+// httpDo performs a request with the client, retrying transient failures the
+// same way as httpGet. The body, if not nil, is sent as JSON.
+func (a *App) httpDo(client *http.Client, method string, url string, body []byte) (*http.Response, error) {
+	var lastErr error
+	backoff := a.RetryBackoff
+	// At least one attempt is made, so that a response or an error is
+	// always returned
+	attempts := max(a.RetryAttempts, 1)
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var resp *http.Response
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(a.ctx, method, url, bodyReader)
+		if err == nil {
+			if body != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if a.AcceptLanguage != "" {
+				req.Header.Set("Accept-Language", a.AcceptLanguage)
+			}
+			resp, err = client.Do(req)
+		}
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		wait := backoff
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("unexpected status: %s", resp.Status)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				a.metadataLimiter.Throttle()
+				if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+					wait = d
+				}
+			}
+			resp.Body.Close()
+		}
+
+		if a.ctx.Err() != nil {
+			// Interrupted, so there's no point in retrying
+			return nil, lastErr
+		}
+		if attempt < attempts {
+			sleepContext(a.ctx, wait)
+			backoff *= 2
+			if backoff > a.RetryMaxBackoff {
+				backoff = a.RetryMaxBackoff
+			}
+		}
+	}
+	return nil, errors.Wrapf(lastErr, "giving up after %d attempts", attempts)
+}
+
+// sleepContext sleeps for d, or until the context is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// parseRetryAfter parses a Retry-After header value, which may be either a
+// number of seconds or an HTTP date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
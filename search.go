@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Weights of matches in each field of an entry, when ranking search results
+const (
+	searchTitleWeight       = 3
+	searchChannelWeight     = 2
+	searchDescriptionWeight = 1
+)
+
+// searchRecencyHalfLife is the age at which a search result's score is
+// halved, so that recent videos rank above older videos that match equally
+// well.
+const searchRecencyHalfLife = 90 * 24 * time.Hour
+
+// searchResult is an entry matching a search, and its score.
+type searchResult struct {
+	Entry FeedEntry
+	Score float64
+}
+
+// searchEntries returns the entries matching every term in the query, in a
+// title, channel name, or description, ranked by relevance and recency.
+func searchEntries(entries []FeedEntry, query string) []FeedEntry {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+	var results []searchResult
+	for _, v := range entries {
+		relevance, ok := matchSearchTerms(v, terms)
+		if !ok {
+			continue
+		}
+		age := time.Since(v.GetPublishedDate())
+		recency := math.Pow(0.5, age.Hours()/searchRecencyHalfLife.Hours())
+		// Recency only breaks ties between results of similar
+		// relevance, rather than dominating it.
+		results = append(results, searchResult{Entry: v, Score: float64(relevance) * (1 + recency)})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	var matches []FeedEntry
+	for _, v := range results {
+		matches = append(matches, v.Entry)
+	}
+	return matches
+}
+
+// matchSearchTerms returns the relevance of the entry to the search terms.
+// ok is false if any term isn't found in the entry.
+func matchSearchTerms(entry FeedEntry, terms []string) (relevance int, ok bool) {
+	title := strings.ToLower(entry.MediaGroup.Title)
+	channel := strings.ToLower(entry.Author.Name)
+	description := strings.ToLower(entry.MediaGroup.Description)
+	for _, term := range terms {
+		termRelevance := 0
+		if strings.Contains(title, term) {
+			termRelevance += searchTitleWeight
+		}
+		if strings.Contains(channel, term) {
+			termRelevance += searchChannelWeight
+		}
+		if strings.Contains(description, term) {
+			termRelevance += searchDescriptionWeight
+		}
+		if termRelevance == 0 {
+			return 0, false
+		}
+		relevance += termRelevance
+	}
+	return relevance, true
+}
+
+// runSearch searches all cached entries, including those that have dropped
+// out of their feeds, and opens the results in the picker, or prints them
+// with --print.
+func (a *App) runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	print := fs.Bool("print", false, "Print the results to stdout instead of opening the picker")
+	watched := fs.Bool("watched", false, "Include watched videos")
+	fs.StringVar(&a.selectedCategory, "category", "", "Only search videos from feeds in this category")
+	a.addCacheFlags(fs)
+	// Flags may come before or after the query, e.g. `search cats --print`
+	var terms []string
+	for fs.Parse(args); fs.NArg() > 0; fs.Parse(args) {
+		terms = append(terms, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	query := strings.Join(terms, " ")
+	if strings.TrimSpace(query) == "" {
+		return errors.New("usage: yt-rss search [flags] <query>")
+	}
+	if *watched {
+		a.HideWatched = false
+	}
+
+	entries, err := a.loadFeedEntries()
+	if err != nil {
+		return err
+	}
+	matches := searchEntries(entries, query)
+
+	if *print {
+		history, err := a.loadHistory()
+		if err != nil {
+			return err
+		}
+		matches, err = a.getVisibleEntries(matches, history)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, v := range matches {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				v.YTVideoID,
+				a.localTime(v.GetPublishedDate()).Format("02 Jan 2006"),
+				formatDuration(v.ExtraMetadata.VideoDuration),
+				v.Author.Name,
+				v.ExtraMetadata.NormalizedTitle,
+			)
+		}
+		return w.Flush()
+	}
+	if len(matches) == 0 {
+		a.printer.Fprintf(os.Stderr, "No videos match %s\n", query)
+		return nil
+	}
+	return a.selectAndRun(matches, "play")
+}
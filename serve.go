@@ -0,0 +1,234 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// webVideo is a video as it is sent to the web UI.
+type webVideo struct {
+	VideoID    string        `json:"video_id"`
+	Title      string        `json:"title"`
+	Channel    string        `json:"channel"`
+	Playlist   string        `json:"playlist,omitempty"`
+	Category   string        `json:"category,omitempty"`
+	Published  time.Time     `json:"published"`
+	Duration   time.Duration `json:"duration"`
+	Views      int64         `json:"views,omitempty"`
+	LiveStatus string        `json:"live_status,omitempty"`
+	Thumbnail  string        `json:"thumbnail"`
+	URL        string        `json:"url"`
+	Watched    bool          `json:"watched"`
+}
+
+// server serves the web UI, and the API it's built on.
+type server struct {
+	app *App
+	// Serializes requests, since refreshing the feeds and playing videos
+	// update the cache, history, and state.
+	mu sync.Mutex
+}
+
+// runServe serves a web UI for browsing and playing videos, as an alternative
+// to the picker. Videos are played on the host running yt-rss, in the same
+// background mpv that the picker's queue action uses, or opened on YouTube.
+func (a *App) runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "127.0.0.1:8080", "Address to listen on. Anyone who can reach it can play videos on this host.")
+	flags.Parse(args)
+
+	a.progressFormat = progressFormatNone
+	s := &server{app: a}
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	mux.HandleFunc("/api/videos", s.handleVideos)
+	mux.HandleFunc("/api/play", s.handlePlay)
+	mux.HandleFunc("/export/", s.handleExport)
+	mux.HandleFunc("/feed.xml", s.handleCombinedFeed("atom"))
+	mux.HandleFunc("/feed.rss", s.handleCombinedFeed("rss"))
+
+	a.printer.Fprintf(os.Stderr, "Serving the web UI at http://%s\n", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
+
+// handleVideos returns the videos that would be shown in the picker, or all
+// cached videos with ?all=1.
+func (s *server) handleVideos(w http.ResponseWriter, r *http.Request) {
+	a := s.app
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := a.loadFeedEntries()
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+	history, err := a.loadHistory()
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("all") == "" {
+		entries, err = a.getVisibleEntries(entries, history)
+		if err != nil {
+			httpError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+	categories, err := a.getFeedCategories()
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	videos := []webVideo{}
+	for _, v := range entries {
+		videos = append(videos, webVideo{
+			VideoID:    v.YTVideoID,
+			Title:      v.ExtraMetadata.NormalizedTitle,
+			Channel:    v.Author.Name,
+			Playlist:   v.ExtraMetadata.PlaylistTitle,
+			Category:   categories[v.ExtraMetadata.FeedURL],
+			Published:  v.GetPublishedDate(),
+			Duration:   v.ExtraMetadata.VideoDuration,
+			Views:      v.Views(),
+			LiveStatus: v.ExtraMetadata.LiveStatus,
+			Thumbnail:  v.MediaGroup.Thumbnail.URL,
+			URL:        v.WatchURL(),
+			Watched:    history.IsWatched(v.YTVideoID),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(videos)
+}
+
+// handlePlay queues a video in the background mpv on the host. The request
+// must have the X-Requested-With header, which browsers don't allow other
+// sites to send without a CORS preflight, so that other pages can't start
+// playback.
+func (s *server) handlePlay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, errors.New("method not allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("X-Requested-With") != "yt-rss" {
+		httpError(w, errors.New("missing X-Requested-With header"), http.StatusForbidden)
+		return
+	}
+	var req struct {
+		VideoID string `json:"video_id"`
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req)
+	if err != nil {
+		httpError(w, errors.Wrap(err, "decode request"), http.StatusBadRequest)
+		return
+	}
+
+	a := s.app
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := a.getFromCache()
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+	entry, ok := findEntryByVideoID(entries, req.VideoID)
+	if !ok {
+		httpError(w, errors.New("video not found"), http.StatusNotFound)
+		return
+	}
+	state, err := a.loadState()
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+	_, err = a.queueAction([]FeedEntry{entry}, state)
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExport serves the visible videos in an export format, e.g.
+// /export/atom.
+func (s *server) handleExport(w http.ResponseWriter, r *http.Request) {
+	a := s.app
+	exporter, err := s.app.getExporter(strings.TrimPrefix(r.URL.Path, "/export/"))
+	if err != nil {
+		httpError(w, err, http.StatusNotFound)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := a.loadFeedEntries()
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+	history, err := a.loadHistory()
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+	entries, err = a.getVisibleEntries(entries, history)
+	if err != nil {
+		httpError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", exporter.ContentType())
+	err = exporter.Export(w, entries)
+	if err != nil {
+		s.app.logger.Errorf("export %s: %s\n", r.URL.Path, err)
+	}
+}
+
+// handleCombinedFeed serves the combined feed of all subscriptions in the
+// format, like `yt-rss export feed`, so that feed readers can subscribe to
+// it.
+func (s *server) handleCombinedFeed(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a := s.app
+		exporter, err := s.app.getExporter(format)
+		if err != nil {
+			httpError(w, err, http.StatusNotFound)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		entries, err := a.loadFeedEntries()
+		if err != nil {
+			httpError(w, err, http.StatusInternalServerError)
+			return
+		}
+		entries, err = a.getCombinedFeedEntries(entries)
+		if err != nil {
+			httpError(w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", exporter.ContentType())
+		err = exporter.Export(w, entries)
+		if err != nil {
+			s.app.logger.Errorf("export %s: %s\n", r.URL.Path, err)
+		}
+	}
+}
+
+func httpError(w http.ResponseWriter, err error, status int) {
+	http.Error(w, err.Error(), status)
+}
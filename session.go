@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Types of session events
+const (
+	sessionEventCommand   = "command"   // The command line yt-rss was run with
+	sessionEventSeed      = "seed"      // The seed the entries were shuffled with
+	sessionEventSelection = "selection" // Entries selected in the picker, and the key pressed
+	sessionEventAction    = "action"    // An action run on entries
+)
+
+// SessionEvent is an entry in the session log. Each run of yt-rss is a
+// session, and every command, selection, and action in it is appended to the
+// log, so that the session can be inspected and replayed later with
+// `yt-rss replay`.
+type SessionEvent struct {
+	Session  string    `json:"session"`
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Args     []string  `json:"args,omitempty"`
+	Key      string    `json:"key,omitempty"`
+	Action   string    `json:"action,omitempty"`
+	VideoIDs []string  `json:"video_ids,omitempty"`
+	Seed     int64     `json:"seed,omitempty"`
+}
+
+// newSessionID returns an ID for a run of yt-rss in the session log, e.g.
+// "20231017-153012-4f2a".
+func newSessionID() string {
+	return fmt.Sprintf("%s-%04x", time.Now().Format("20060102-150405"), rand.Intn(0x10000))
+}
+
+func (a *App) getSessionLogFile() string {
+	return a.getDataFile(getStateDir(), "sessions.jsonl")
+}
+
+// logSessionEvent appends the event to the session log. The log is only for
+// debugging, so failing to write it doesn't interrupt the session.
+func (a *App) logSessionEvent(event SessionEvent) {
+	if a.ReadOnly {
+		return
+	}
+	event.Session = a.sessionID
+	event.Time = time.Now()
+	b, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(a.getSessionLogFile(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(b, '\n'))
+}
+
+func getVideoIDs(entries []FeedEntry) []string {
+	var videoIDs []string
+	for _, v := range entries {
+		videoIDs = append(videoIDs, v.YTVideoID)
+	}
+	return videoIDs
+}
+
+func (a *App) loadSessionEvents() ([]SessionEvent, error) {
+	f, err := os.Open(a.getSessionLogFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []SessionEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var event SessionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Skip lines cut short by an interrupted write
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// runReplay prints the events of a session, and runs its command again in
+// read-only mode, with the same shuffle seed. Selections and actions are
+// only printed, since they were made interactively; the picker prints the
+// entries it would have shown instead. Without a session ID, the recent
+// sessions are listed.
+func (a *App) runReplay(args []string) error {
+	events, err := a.loadSessionEvents()
+	if err != nil {
+		return errors.Wrap(err, "load session log")
+	}
+	if len(args) == 0 {
+		return listSessions(events)
+	}
+	if len(args) != 1 {
+		return errors.New("usage: yt-rss replay [session-id]")
+	}
+
+	var session []SessionEvent
+	for _, v := range events {
+		if v.Session == args[0] {
+			session = append(session, v)
+		}
+	}
+	if len(session) == 0 || session[0].Type != sessionEventCommand {
+		return fmt.Errorf("session not found: %s", args[0])
+	}
+	for _, v := range session {
+		fmt.Fprintf(os.Stderr, "%s  %s\n", a.localTime(v.Time).Format("15:04:05"), formatSessionEvent(v))
+	}
+
+	commandArgs := session[0].Args
+	for _, v := range session {
+		if v.Type == sessionEventSeed {
+			commandArgs = withSeed(commandArgs, v.Seed)
+			break
+		}
+	}
+	a.printer.Fprintf(os.Stderr, "Replaying: yt-rss %s\n", strings.Join(commandArgs, " "))
+	a.ReadOnly = true
+	a.replaying = true
+	return a.runCommandLine(commandArgs)
+}
+
+// printReplayedPicker prints the entries the picker would have shown.
+func printReplayedPicker(fzfContent string) {
+	printPickerLines(os.Stdout, splitPickerLines(fzfContent))
+}
+
+// listSessions prints the most recent sessions and their commands.
+func listSessions(events []SessionEvent) error {
+	const maxSessions = 20
+	var commands []SessionEvent
+	for _, v := range events {
+		if v.Type == sessionEventCommand {
+			commands = append(commands, v)
+		}
+	}
+	if len(commands) > maxSessions {
+		commands = commands[len(commands)-maxSessions:]
+	}
+	for _, v := range commands {
+		fmt.Printf("%s  yt-rss %s\n", v.Session, strings.Join(v.Args, " "))
+	}
+	return nil
+}
+
+func formatSessionEvent(event SessionEvent) string {
+	switch event.Type {
+	case sessionEventCommand:
+		return "command: yt-rss " + strings.Join(event.Args, " ")
+	case sessionEventSeed:
+		return fmt.Sprintf("seed: %d", event.Seed)
+	case sessionEventSelection:
+		return fmt.Sprintf("selection: %s (%s)", strings.Join(event.VideoIDs, ", "), event.Key)
+	case sessionEventAction:
+		return fmt.Sprintf("action: %s %s", event.Action, strings.Join(event.VideoIDs, ", "))
+	default:
+		return event.Type
+	}
+}
+
+// withSeed returns the command line with --seed added after the command
+// name, so that the entries are shuffled the same way again.
+func withSeed(args []string, seed int64) []string {
+	name, rest := getCommandName(args)
+	return append([]string{name, "--seed", fmt.Sprint(seed)}, rest...)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// checkIsShort checks whether the video is a YouTube Short. YouTube serves
+// Shorts at /shorts/<id>, and redirects to the regular watch page for other
+// videos.
+func (a *App) checkIsShort(videoID string) (bool, error) {
+	limiter := a.metadataLimiter
+	limiter.Wait(a.ctx)
+	resp, err := a.httpDo(a.noRedirectClient, http.MethodHead, "https://www.youtube.com/shorts/"+videoID, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	limiter.Recover()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return true, nil
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// addShuffleFlags adds the flags that shuffle the entries to the command's
+// flag set.
+func (a *App) addShuffleFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&a.shuffle, "shuffle", false, "Show videos in a random order")
+	fs.Int64Var(&a.shuffleSeed, "seed", 0, "Seed for --shuffle, to reproduce an earlier order. Implies --shuffle.")
+}
+
+// applyShuffle shuffles the entries if --shuffle or --seed was given. The
+// seed is printed so that the order can be reproduced later with --seed.
+func (a *App) applyShuffle(fs *flag.FlagSet, entries []FeedEntry) []FeedEntry {
+	seedSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seedSet = true
+		}
+	})
+	if !a.shuffle && !seedSet {
+		return entries
+	}
+	if !seedSet {
+		a.shuffleSeed = time.Now().UnixNano()
+	}
+	a.logSessionEvent(SessionEvent{Type: sessionEventSeed, Seed: a.shuffleSeed})
+	// Formatted with fmt, since the printer groups the digits of numbers
+	a.printer.Fprintf(os.Stderr, "Shuffled with --seed %s\n", fmt.Sprint(a.shuffleSeed))
+	return shuffleEntries(entries, a.shuffleSeed)
+}
+
+// shuffleEntries returns a copy of the entries in a random order. The same
+// seed always gives the same order.
+func shuffleEntries(entries []FeedEntry, seed int64) []FeedEntry {
+	shuffled := append([]FeedEntry(nil), entries...)
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Keys that entries can be sorted by, with --sort or the sort setting.
+const (
+	sortByPublished = "published"
+	sortByDuration  = "duration"
+	sortByChannel   = "channel"
+	sortByViews     = "views"
+)
+
+// defaultSortDescending is the direction each key sorts in when none is
+// given: newest, longest, and most viewed first, and channels alphabetically.
+var defaultSortDescending = map[string]bool{
+	sortByPublished: true,
+	sortByDuration:  true,
+	sortByChannel:   false,
+	sortByViews:     true,
+}
+
+// addSortFlags adds the flag that sorts the entries to the command's flag
+// set.
+func (a *App) addSortFlags(fs *flag.FlagSet) {
+	fs.StringVar(&a.SortOrder, "sort", a.SortOrder, "Order of the videos: published, duration, channel, or views, optionally followed by :asc or :desc, e.g. duration:asc")
+}
+
+// parseSortOrder parses a sort order like "views" or "duration:asc" into the
+// key to sort by and the direction.
+func parseSortOrder(order string) (key string, descending bool, err error) {
+	key, direction, _ := strings.Cut(order, ":")
+	descending, ok := defaultSortDescending[key]
+	if !ok {
+		return "", false, fmt.Errorf("unknown sort order: %s (available: published, duration, channel, views)", key)
+	}
+	switch direction {
+	case "":
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		return "", false, fmt.Errorf("unknown sort direction: %s (available: asc, desc)", direction)
+	}
+	return key, descending, nil
+}
+
+// applySort sorts the entries by SortOrder. Entries that are equal by the
+// key stay newest first. Shuffling takes precedence over sorting, so the
+// entries are left as is if --shuffle or --seed was given.
+func (a *App) applySort(fs *flag.FlagSet, entries []FeedEntry) ([]FeedEntry, error) {
+	key, descending, err := parseSortOrder(a.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	shuffled := a.shuffle
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			shuffled = true
+		}
+	})
+	if shuffled || (key == sortByPublished && descending) {
+		// Entries are already newest first
+		return entries, nil
+	}
+
+	// Compare returns a negative number if the first entry sorts before
+	// the second in ascending order.
+	var compare func(a, b FeedEntry) int
+	switch key {
+	case sortByPublished:
+		compare = func(a, b FeedEntry) int {
+			return a.GetPublishedDate().Compare(b.GetPublishedDate())
+		}
+	case sortByDuration:
+		compare = func(a, b FeedEntry) int {
+			return cmp.Compare(a.ExtraMetadata.VideoDuration, b.ExtraMetadata.VideoDuration)
+		}
+	case sortByChannel:
+		compare = func(a, b FeedEntry) int {
+			return strings.Compare(strings.ToLower(a.Author.Name), strings.ToLower(b.Author.Name))
+		}
+	case sortByViews:
+		compare = func(a, b FeedEntry) int {
+			return cmp.Compare(a.Views(), b.Views())
+		}
+	}
+
+	sorted := append([]FeedEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		// Videos without a duration, e.g. livestreams and videos whose
+		// metadata couldn't be fetched, are always last.
+		if key == sortByDuration {
+			iUnknown, jUnknown := sorted[i].ExtraMetadata.VideoDuration == 0, sorted[j].ExtraMetadata.VideoDuration == 0
+			if iUnknown != jUnknown {
+				return jUnknown
+			}
+		}
+		if descending {
+			return compare(sorted[i], sorted[j]) > 0
+		}
+		return compare(sorted[i], sorted[j]) < 0
+	})
+	return sorted, nil
+}
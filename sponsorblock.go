@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sponsorSegment is a part of a video that SponsorBlock users marked as a
+// sponsor read, self-promotion, or another category to skip.
+type sponsorSegment struct {
+	Start    float64 `json:"start"` // Seconds
+	End      float64 `json:"end"`
+	Category string  `json:"category"`
+}
+
+// sponsorBlockData is the cached SponsorBlock segments of a video.
+type sponsorBlockData struct {
+	Segments  []sponsorSegment `json:"segments"`
+	FetchedAt time.Time        `json:"fetched_at"`
+}
+
+// Segments are mostly submitted in the first days after a video is uploaded,
+// so the segments of recent videos are fetched again daily, and those of
+// older videos are only fetched once.
+const (
+	sponsorBlockRefreshInterval = 24 * time.Hour
+	sponsorBlockRefreshMaxAge   = 7 * 24 * time.Hour
+)
+
+// needsSponsorSegments returns true if the segments of the entry should be
+// fetched from SponsorBlock.
+func (a *App) needsSponsorSegments(entry FeedEntry) bool {
+	if !a.SponsorBlock {
+		return false
+	}
+	data := entry.ExtraMetadata.SponsorBlock
+	if data == nil {
+		return true
+	}
+	return time.Since(entry.GetPublishedDate()) < sponsorBlockRefreshMaxAge &&
+		time.Since(data.FetchedAt) > sponsorBlockRefreshInterval
+}
+
+// getSponsorSegments fetches the segments of the video from SponsorBlock, in
+// the categories set with sponsorblock_categories. Only the first characters
+// of the hash of the video ID are sent, so that SponsorBlock doesn't learn
+// which videos are being looked at.
+func (a *App) getSponsorSegments(videoID string) ([]sponsorSegment, error) {
+	hash := sha256.Sum256([]byte(videoID))
+	prefix := hex.EncodeToString(hash[:])[:4]
+	categories, err := json.Marshal(a.SponsorBlockCategories)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("categories", string(categories))
+	query.Set("actionType", "skip")
+	resp, err := a.httpGet(strings.TrimSuffix(a.SponsorBlockAPI, "/") + "/api/skipSegments/" + prefix + "?" + query.Encode())
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch sponsor segments")
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// No video with the prefix has segments
+		return []sponsorSegment{}, nil
+	default:
+		return nil, fmt.Errorf("fetch sponsor segments: unexpected status: %s", resp.Status)
+	}
+
+	var videos []struct {
+		VideoID  string `json:"videoID"`
+		Segments []struct {
+			Segment  [2]float64 `json:"segment"`
+			Category string     `json:"category"`
+		} `json:"segments"`
+	}
+	err = json.NewDecoder(a.limitBody(resp)).Decode(&videos)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode sponsor segments")
+	}
+	segments := []sponsorSegment{}
+	for _, v := range videos {
+		if v.VideoID != videoID {
+			continue
+		}
+		for _, s := range v.Segments {
+			segments = append(segments, sponsorSegment{Start: s.Segment[0], End: s.Segment[1], Category: s.Category})
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Start < segments[j].Start
+	})
+	return segments, nil
+}
+
+// addSponsorSegments fetches the entry's segments, if they are due.
+func (a *App) addSponsorSegments(entry *FeedEntry) {
+	if !a.needsSponsorSegments(*entry) {
+		return
+	}
+	segments, err := a.getSponsorSegments(entry.YTVideoID)
+	if err != nil {
+		a.logger.Warnf("failed to get sponsor segments for %s (%s): %s\n", entry.YTVideoID, entry.Author.Name, err)
+		return
+	}
+	entry.ExtraMetadata.SponsorBlock = &sponsorBlockData{Segments: segments, FetchedAt: time.Now()}
+}
+
+// sponsorTime returns the total length of the entry's segments, counting
+// overlapping segments once. It is zero if the segments aren't known.
+func sponsorTime(entry FeedEntry) time.Duration {
+	data := entry.ExtraMetadata.SponsorBlock
+	if data == nil {
+		return 0
+	}
+	var total, end float64
+	for _, v := range data.Segments {
+		// Segments are sorted by their start
+		start := max(v.Start, end)
+		if v.End > start {
+			total += v.End - start
+			end = v.End
+		}
+	}
+	return time.Duration(total * float64(time.Second))
+}
+
+// formatEntryDuration formats the entry's duration for the picker and
+// `yt-rss list`. With SponsorBlock enabled, it is the time left after
+// skipping the segments, followed by the time skipped, e.g. "12:30 (−1:45)".
+func (a *App) formatEntryDuration(entry FeedEntry) string {
+	duration := entry.ExtraMetadata.VideoDuration
+	skipped := sponsorTime(entry)
+	if !a.SponsorBlock || skipped == 0 || duration == 0 {
+		return formatDuration(duration)
+	}
+	// The skipped time is short, so it is shown without a leading zero
+	return fmt.Sprintf("%s (−%s)", formatDuration(duration-skipped), strings.TrimPrefix(formatDuration(skipped), "0"))
+}
+
+// sponsorScriptOptArgs returns the mpv arguments that pass the entry's
+// segments to an mpv script, in the script option set with
+// sponsorblock_script_opt, as comma-separated start-end pairs in seconds,
+// e.g. "12.5-40.25,300-330". No arguments are returned if the option isn't
+// set, or the segments aren't known.
+func (a *App) sponsorScriptOptArgs(entry FeedEntry) []string {
+	data := entry.ExtraMetadata.SponsorBlock
+	if !a.SponsorBlock || a.SponsorBlockScriptOpt == "" || data == nil || len(data.Segments) == 0 || !a.isMPVPlayer() {
+		return nil
+	}
+	var segments []string
+	for _, v := range data.Segments {
+		segments = append(segments, fmt.Sprintf("%g-%g", v.Start, v.End))
+	}
+	return []string{fmt.Sprintf("--script-opts-append=%s=%s", a.SponsorBlockScriptOpt, strings.Join(segments, ","))}
+}
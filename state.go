@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// State holds user state that should persist across runs. Unlike the cache,
+// which can be thrown away and rebuilt from the feeds at any time, state is
+// created by the user and cannot be recovered once lost.
+type State struct {
+	// PinnedEntryIDs are the IDs of entries pinned to the top of the
+	// picker. Entries stay pinned until they are played or unpinned.
+	PinnedEntryIDs []string `json:"pinned_entry_ids"`
+
+	// WatchLater is the watch-later list, in the order entries were added.
+	// Entries leave the list when they are played or removed.
+	WatchLater []watchLaterItem `json:"watch_later,omitempty"`
+
+	// Queue is the play queue, in the order entries were added. Entries
+	// leave the queue once they are played to the end by `yt-rss queue
+	// play`, or when they are removed.
+	Queue []queueItem `json:"queue,omitempty"`
+
+	// MutedChannels are the channels whose entries are hidden from the
+	// picker, keyed by channel ID. Snoozed channels are shown again once
+	// their snooze expires.
+	MutedChannels map[string]mutedChannel `json:"muted_channels,omitempty"`
+}
+
+func (s *State) IsPinned(entryID string) bool {
+	for _, v := range s.PinnedEntryIDs {
+		if v == entryID {
+			return true
+		}
+	}
+	return false
+}
+
+// TogglePin pins the entry if it is not pinned, and unpins it otherwise.
+func (s *State) TogglePin(entryID string) {
+	if s.IsPinned(entryID) {
+		s.Unpin(entryID)
+		return
+	}
+	s.PinnedEntryIDs = append(s.PinnedEntryIDs, entryID)
+}
+
+func (s *State) Unpin(entryID string) {
+	var pinned []string
+	for _, v := range s.PinnedEntryIDs {
+		if v != entryID {
+			pinned = append(pinned, v)
+		}
+	}
+	s.PinnedEntryIDs = pinned
+}
+
+func (a *App) getStateFile() string {
+	return a.getDataFile(getStateDir(), "state.json")
+}
+
+func (a *App) loadState() (*State, error) {
+	stateFile := a.getStateFile()
+
+	state := &State{}
+	b, err := ioutil.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (a *App) saveState(state *State) error {
+	if a.ReadOnly {
+		return nil
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.getStateFile(), b, 0600)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Orders of the channels in `yt-rss stats`, set with --sort.
+const (
+	channelStatsByUploads = "uploads" // Most uploads per week first
+	channelStatsByLength  = "length"  // Longest average video first
+	channelStatsByWatched = "watched" // Least watched first, to find channels to unsubscribe from
+	channelStatsByName    = "name"
+)
+
+// channelStats summarizes a channel's videos in the cache.
+type channelStats struct {
+	Name            string
+	Videos          int
+	UploadsPerWeek  float64
+	AverageDuration time.Duration // Of the videos whose duration is known
+	Watched         int           // Videos in the cache that were watched
+	LastUpload      time.Time
+}
+
+// WatchedRatio returns the share of the channel's cached videos that were
+// watched.
+func (s channelStats) WatchedRatio() float64 {
+	if s.Videos == 0 {
+		return 0
+	}
+	return float64(s.Watched) / float64(s.Videos)
+}
+
+// getChannelStats returns the stats of each channel with videos in the
+// cache. Uploads per week are counted from the channel's oldest cached video
+// until now, which is at least a week, since feeds only have a channel's
+// latest videos.
+func getChannelStats(entries []FeedEntry, history History, now time.Time) []channelStats {
+	type totals struct {
+		stats         channelStats
+		oldest        time.Time
+		totalDuration time.Duration
+		withDuration  int
+	}
+	byChannel := make(map[string]*totals)
+	var keys []string
+	for _, v := range entries {
+		key := v.ChannelID
+		if key == "" {
+			key = v.Author.Name
+		}
+		t, ok := byChannel[key]
+		if !ok {
+			t = &totals{stats: channelStats{Name: v.Author.Name}}
+			byChannel[key] = t
+			keys = append(keys, key)
+		}
+		t.stats.Videos++
+		if history.IsWatched(v.YTVideoID) {
+			t.stats.Watched++
+		}
+		if d := v.ExtraMetadata.VideoDuration; d > 0 {
+			t.totalDuration += d
+			t.withDuration++
+		}
+		published, err := v.PublishedDate()
+		if err != nil {
+			continue
+		}
+		if t.oldest.IsZero() || published.Before(t.oldest) {
+			t.oldest = published
+		}
+		if published.After(t.stats.LastUpload) {
+			t.stats.LastUpload = published
+		}
+	}
+
+	const week = 7 * 24 * time.Hour
+	var stats []channelStats
+	for _, key := range keys {
+		t := byChannel[key]
+		if t.withDuration > 0 {
+			t.stats.AverageDuration = t.totalDuration / time.Duration(t.withDuration)
+		}
+		weeks := 1.0
+		if !t.oldest.IsZero() {
+			weeks = max(weeks, float64(now.Sub(t.oldest))/float64(week))
+		}
+		t.stats.UploadsPerWeek = float64(t.stats.Videos) / weeks
+		stats = append(stats, t.stats)
+	}
+	return stats
+}
+
+// sortChannelStats sorts the stats in the given order, by name if they are
+// otherwise equal.
+func sortChannelStats(stats []channelStats, order string) error {
+	var less func(a, b channelStats) bool
+	switch order {
+	case channelStatsByUploads:
+		less = func(a, b channelStats) bool { return a.UploadsPerWeek > b.UploadsPerWeek }
+	case channelStatsByLength:
+		less = func(a, b channelStats) bool { return a.AverageDuration > b.AverageDuration }
+	case channelStatsByWatched:
+		less = func(a, b channelStats) bool { return a.WatchedRatio() < b.WatchedRatio() }
+	case channelStatsByName:
+		less = func(a, b channelStats) bool { return false }
+	default:
+		return fmt.Errorf("unknown sort order: %s (available: %s, %s, %s, %s)", order, channelStatsByUploads, channelStatsByLength, channelStatsByWatched, channelStatsByName)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if less(stats[i], stats[j]) != less(stats[j], stats[i]) {
+			return less(stats[i], stats[j])
+		}
+		return strings.ToLower(stats[i].Name) < strings.ToLower(stats[j].Name)
+	})
+	return nil
+}
+
+// channelWatchCount is the number of videos watched from a channel.
+type channelWatchCount struct {
+	Name  string
+	Count int
+}
+
+// mostWatchedChannels returns the channels with the most watched videos in
+// the whole history, including videos that are no longer in the cache, up to
+// limit.
+func mostWatchedChannels(history History, limit int) []channelWatchCount {
+	counts := make(map[string]int)
+	for _, record := range history {
+		if !record.WatchedAt.IsZero() && record.Channel != "" {
+			counts[record.Channel]++
+		}
+	}
+	var channels []channelWatchCount
+	for name, count := range counts {
+		channels = append(channels, channelWatchCount{Name: name, Count: count})
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].Count != channels[j].Count {
+			return channels[i].Count > channels[j].Count
+		}
+		return channels[i].Name < channels[j].Name
+	})
+	return channels[:min(limit, len(channels))]
+}
+
+// printChannelStats prints a summary of the cache and the watch history,
+// then a table of each channel's uploads per week, average video length, and
+// how many of its cached videos were watched, and the most watched channels.
+func (a *App) printChannelStats(w io.Writer, entries []FeedEntry, history History, order string, top int, now time.Time) error {
+	stats := getChannelStats(entries, history, now)
+	err := sortChannelStats(stats, order)
+	if err != nil {
+		return err
+	}
+
+	watchedInCache, watched, plays := 0, 0, 0
+	for _, v := range entries {
+		if history.IsWatched(v.YTVideoID) {
+			watchedInCache++
+		}
+	}
+	for _, record := range history {
+		if !record.WatchedAt.IsZero() {
+			watched++
+		}
+		plays += record.PlayCount
+	}
+	a.printer.Fprintf(w, "Cache: %d videos from %d channels, %d watched\n", len(entries), len(stats), watchedInCache)
+	a.printer.Fprintf(w, "History: %d videos watched, %d plays\n", watched, plays)
+
+	if len(stats) > 0 {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, a.printer.Sprintf("Channel\tUploads/week\tAvg length\tWatched\tLast upload"))
+		for _, v := range stats {
+			averageDuration := "?"
+			if v.AverageDuration > 0 {
+				averageDuration = formatDuration(v.AverageDuration)
+			}
+			lastUpload := "?"
+			if !v.LastUpload.IsZero() {
+				lastUpload = a.formatRelativeTime(v.LastUpload, now)
+			}
+			fmt.Fprintf(tw, "%s\t%.1f\t%s\t%d/%d\t%s\n", v.Name, v.UploadsPerWeek, averageDuration, v.Watched, v.Videos, lastUpload)
+		}
+		err = tw.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	if mostWatched := mostWatchedChannels(history, top); len(mostWatched) > 0 {
+		fmt.Fprintln(w)
+		a.printer.Fprintf(w, "Most watched channels:\n")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for i, v := range mostWatched {
+			fmt.Fprintf(tw, "%3d.\t%s\t%s\n", i+1, v.Name, a.printer.Sprintf("%d videos", v.Count))
+		}
+		return tw.Flush()
+	}
+	return nil
+}
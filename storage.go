@@ -0,0 +1,445 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	_ "modernc.org/sqlite"
+)
+
+// Storage backends, set with the storage setting. The JSON backend keeps the
+// cache and the watch history in cache.json and history.json, which are
+// rewritten in full on every change. The SQLite backend keeps them in a
+// database, where only the rows that changed are written.
+const (
+	storageJSON   = "json"
+	storageSQLite = "sqlite"
+)
+
+// databaseSchemaVersion is stored in the database's user_version. Increment
+// it when the schema changes, and migrate older versions in openDatabase.
+const databaseSchemaVersion = 1
+
+const databaseSchema = `
+CREATE TABLE IF NOT EXISTS entries (
+	id TEXT PRIMARY KEY,
+	video_id TEXT NOT NULL,
+	channel_id TEXT NOT NULL,
+	feed_url TEXT NOT NULL,
+	published INTEGER NOT NULL, -- Unix time
+	data TEXT NOT NULL -- The FeedEntry as JSON
+);
+CREATE INDEX IF NOT EXISTS entries_published ON entries (published);
+CREATE INDEX IF NOT EXISTS entries_feed_url ON entries (feed_url);
+
+CREATE TABLE IF NOT EXISTS feeds (
+	url TEXT PRIMARY KEY,
+	fetched_at INTEGER NOT NULL -- Unix time
+);
+
+CREATE TABLE IF NOT EXISTS channels (
+	channel_id TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS history (
+	video_id TEXT PRIMARY KEY,
+	watched_at INTEGER NOT NULL, -- Unix time, or 0 if not watched
+	data TEXT NOT NULL -- The WatchRecord as JSON
+);
+
+CREATE TABLE IF NOT EXISTS meta (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// databaseSnapshot is the JSON of each row as it was last read, keyed by
+// primary key, so that writes can skip the rows that haven't changed.
+type databaseSnapshot struct {
+	entries map[string]string
+	history map[string]string
+}
+
+// useDatabase returns true if the cache and history are stored in the
+// SQLite database.
+func (a *App) useDatabase() (bool, error) {
+	switch a.Storage {
+	case storageJSON:
+		return false, nil
+	case storageSQLite:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown storage: %s (expected %s or %s)", a.Storage, storageJSON, storageSQLite)
+	}
+}
+
+func (a *App) getDatabaseFile() string {
+	return filepath.Join(getStateDir(), "yt-rss", "yt-rss.db")
+}
+
+// openDatabase opens the database, creating it if it doesn't exist. A new
+// database is populated from the JSON cache and history, if they exist, so
+// that switching to SQLite doesn't lose anything.
+func (a *App) openDatabase() (*sql.DB, error) {
+	if a.db != nil {
+		return a.db, nil
+	}
+	fileName := a.getDatabaseFile()
+	isNew := !fileExists(fileName)
+	if isNew && a.ReadOnly {
+		return nil, fmt.Errorf("the database %s doesn't exist yet, and can't be created in read-only mode", fileName)
+	}
+
+	dsn := "file:" + fileName + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	if a.ReadOnly {
+		dsn += "&mode=ro"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "open database")
+	}
+	var version int
+	err = db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "read database version")
+	}
+	if version > databaseSchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("the database was written by a newer version of yt-rss (schema version %d, supported version %d)", version, databaseSchemaVersion)
+	}
+	if !a.ReadOnly && version < databaseSchemaVersion {
+		_, err = db.Exec(databaseSchema + fmt.Sprintf("PRAGMA user_version = %d;", databaseSchemaVersion))
+		if err != nil {
+			db.Close()
+			return nil, errors.Wrap(err, "create database schema")
+		}
+	}
+	a.db = db
+
+	if isNew {
+		err = a.importJSONIntoDatabase()
+		if err != nil {
+			return nil, errors.Wrap(err, "import the JSON cache and history")
+		}
+	}
+	return db, nil
+}
+
+// importJSONIntoDatabase copies the JSON cache and history into the database.
+func (a *App) importJSONIntoDatabase() error {
+	cache, err := a.loadCacheFile()
+	if err != nil {
+		return err
+	}
+	if len(cache.FeedEntries) > 0 {
+		err = a.writeCacheToDatabase(cache)
+		if err != nil {
+			return err
+		}
+	}
+	history, err := a.loadHistoryFile()
+	if err != nil {
+		return err
+	}
+	if len(history) > 0 {
+		err = a.saveHistoryToDatabase(history)
+		if err != nil {
+			return err
+		}
+	}
+	if len(cache.FeedEntries) > 0 || len(history) > 0 {
+		a.printer.Fprintf(os.Stderr, "Imported %d cached videos and %d watch history records into %s\n", len(cache.FeedEntries), len(history), a.getDatabaseFile())
+	}
+	return nil
+}
+
+func (a *App) loadCacheFromDatabase() (*Cache, error) {
+	db, err := a.openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	cache := &Cache{
+		Version:       cacheVersion,
+		FeedFetchedAt: make(map[string]time.Time),
+	}
+
+	snapshot := make(map[string]string)
+	rows, err := db.Query("SELECT id, data FROM entries ORDER BY published DESC, rowid")
+	if err != nil {
+		return nil, errors.Wrap(err, "query entries")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, data string
+		err = rows.Scan(&id, &data)
+		if err != nil {
+			return nil, err
+		}
+		var entry FeedEntry
+		err = json.Unmarshal([]byte(data), &entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode entry %s", id)
+		}
+		cache.FeedEntries = append(cache.FeedEntries, entry)
+		snapshot[id] = data
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	feedRows, err := db.Query("SELECT url, fetched_at FROM feeds")
+	if err != nil {
+		return nil, errors.Wrap(err, "query feeds")
+	}
+	defer feedRows.Close()
+	for feedRows.Next() {
+		var url string
+		var fetchedAt int64
+		err = feedRows.Scan(&url, &fetchedAt)
+		if err != nil {
+			return nil, err
+		}
+		cache.FeedFetchedAt[url] = time.Unix(fetchedAt, 0)
+	}
+	if err = feedRows.Err(); err != nil {
+		return nil, err
+	}
+
+	meta, err := a.loadDatabaseMeta()
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := meta["last_query_timestamp"]; ok {
+		cache.LastQueryTimestamp, _ = time.Parse(time.RFC3339Nano, v)
+	}
+	if v, ok := meta["known_metadata"]; ok {
+		err = json.Unmarshal([]byte(v), &cache.KnownMetadata)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode known metadata")
+		}
+	}
+	if v, ok := meta["feed_last_upload"]; ok {
+		err = json.Unmarshal([]byte(v), &cache.FeedLastUpload)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode last uploads")
+		}
+	}
+	a.dbSnapshot.entries = snapshot
+	return cache, nil
+}
+
+func (a *App) loadDatabaseMeta() (map[string]string, error) {
+	rows, err := a.db.Query("SELECT key, value FROM meta")
+	if err != nil {
+		return nil, errors.Wrap(err, "query meta")
+	}
+	defer rows.Close()
+	meta := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		err = rows.Scan(&key, &value)
+		if err != nil {
+			return nil, err
+		}
+		meta[key] = value
+	}
+	return meta, rows.Err()
+}
+
+// writeCacheToDatabase writes the entries that changed since the cache was
+// loaded, and deletes the ones that were removed from it, e.g. by pruning.
+func (a *App) writeCacheToDatabase(cache *Cache) error {
+	db, err := a.openDatabase()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once committed
+
+	snapshot := make(map[string]string, len(cache.FeedEntries))
+	for _, v := range cache.FeedEntries {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data := string(b)
+		snapshot[v.ID] = data
+		if a.dbSnapshot.entries[v.ID] == data {
+			continue
+		}
+		_, err = tx.Exec(`INSERT INTO entries (id, video_id, channel_id, feed_url, published, data) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET video_id = excluded.video_id, channel_id = excluded.channel_id,
+			feed_url = excluded.feed_url, published = excluded.published, data = excluded.data`,
+			v.ID, v.YTVideoID, v.ChannelID, v.ExtraMetadata.FeedURL, v.GetPublishedDate().Unix(), data)
+		if err != nil {
+			return errors.Wrap(err, "write entry")
+		}
+		if v.ChannelID != "" && v.Author.Name != "" && v.ExtraMetadata.PlaylistTitle == "" {
+			_, err = tx.Exec("INSERT INTO channels (channel_id, name) VALUES (?, ?) ON CONFLICT (channel_id) DO UPDATE SET name = excluded.name",
+				v.ChannelID, v.Author.Name)
+			if err != nil {
+				return errors.Wrap(err, "write channel")
+			}
+		}
+	}
+	for id := range a.dbSnapshot.entries {
+		if _, ok := snapshot[id]; !ok {
+			_, err = tx.Exec("DELETE FROM entries WHERE id = ?", id)
+			if err != nil {
+				return errors.Wrap(err, "delete entry")
+			}
+		}
+	}
+
+	for url, fetchedAt := range cache.FeedFetchedAt {
+		_, err = tx.Exec("INSERT INTO feeds (url, fetched_at) VALUES (?, ?) ON CONFLICT (url) DO UPDATE SET fetched_at = excluded.fetched_at",
+			url, fetchedAt.Unix())
+		if err != nil {
+			return errors.Wrap(err, "write feed")
+		}
+	}
+	knownMetadata, err := json.Marshal(cache.KnownMetadata)
+	if err != nil {
+		return err
+	}
+	feedLastUpload, err := json.Marshal(cache.FeedLastUpload)
+	if err != nil {
+		return err
+	}
+	meta := map[string]string{
+		"last_query_timestamp": cache.LastQueryTimestamp.Format(time.RFC3339Nano),
+		"known_metadata":       string(knownMetadata),
+		"feed_last_upload":     string(feedLastUpload),
+	}
+	for key, value := range meta {
+		_, err = tx.Exec("INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", key, value)
+		if err != nil {
+			return errors.Wrap(err, "write meta")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	a.dbSnapshot.entries = snapshot
+	return nil
+}
+
+func (a *App) loadHistoryFromDatabase() (History, error) {
+	db, err := a.openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT video_id, data FROM history")
+	if err != nil {
+		return nil, errors.Wrap(err, "query history")
+	}
+	defer rows.Close()
+	history := make(History)
+	snapshot := make(map[string]string)
+	for rows.Next() {
+		var videoID, data string
+		err = rows.Scan(&videoID, &data)
+		if err != nil {
+			return nil, err
+		}
+		record := &WatchRecord{}
+		err = json.Unmarshal([]byte(data), record)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode watch record %s", videoID)
+		}
+		history[videoID] = record
+		snapshot[videoID] = data
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	a.dbSnapshot.history = snapshot
+	return history, nil
+}
+
+// saveHistoryToDatabase writes the records that changed since the history
+// was loaded, and deletes the ones that were removed from it.
+func (a *App) saveHistoryToDatabase(history History) error {
+	db, err := a.openDatabase()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once committed
+
+	snapshot := make(map[string]string, len(history))
+	for videoID, record := range history {
+		b, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data := string(b)
+		snapshot[videoID] = data
+		if a.dbSnapshot.history[videoID] == data {
+			continue
+		}
+		var watchedAt int64
+		if !record.WatchedAt.IsZero() {
+			watchedAt = record.WatchedAt.Unix()
+		}
+		_, err = tx.Exec("INSERT INTO history (video_id, watched_at, data) VALUES (?, ?, ?) ON CONFLICT (video_id) DO UPDATE SET watched_at = excluded.watched_at, data = excluded.data",
+			videoID, watchedAt, data)
+		if err != nil {
+			return errors.Wrap(err, "write watch record")
+		}
+	}
+	for videoID := range a.dbSnapshot.history {
+		if _, ok := snapshot[videoID]; !ok {
+			_, err = tx.Exec("DELETE FROM history WHERE video_id = ?", videoID)
+			if err != nil {
+				return errors.Wrap(err, "delete watch record")
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	a.dbSnapshot.history = snapshot
+	return nil
+}
+
+// getChannelNamesFromDatabase returns a lookup of channel IDs to channel
+// names, including channels whose videos have been pruned from the cache.
+func (a *App) getChannelNamesFromDatabase() (map[string]string, error) {
+	db, err := a.openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT channel_id, name FROM channels")
+	if err != nil {
+		return nil, errors.Wrap(err, "query channels")
+	}
+	defer rows.Close()
+	names := make(map[string]string)
+	for rows.Next() {
+		var channelID, name string
+		err = rows.Scan(&channelID, &name)
+		if err != nil {
+			return nil, err
+		}
+		names[channelID] = name
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const playlistFeedURLPrefix = "https://www.youtube.com/feeds/videos.xml?playlist_id="
+
+func playlistFeedURL(playlistID string) string {
+	return playlistFeedURLPrefix + playlistID
+}
+
+var (
+	channelIDRegex = regexp.MustCompile(`^UC[\w-]{22}$`)
+
+	// Ways the channel ID is embedded in YouTube's pages, in order of
+	// preference. Channel pages (including handles, custom URLs, and
+	// channel tabs) have a canonical link to the channel, while video pages
+	// reference the uploader's channel ID.
+	pageChannelIDRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`<link rel="canonical" href="https://www\.youtube\.com/channel/(UC[\w-]{22})"`),
+		regexp.MustCompile(`<meta itemprop="channelId" content="(UC[\w-]{22})"`),
+		regexp.MustCompile(`"channelId":"(UC[\w-]{22})"`),
+	}
+)
+
+// resolveFeedURL returns the feed URL for any YouTube URL: feeds, playlists,
+// channels (by ID, handle, custom URL, or any channel tab), and videos
+// (watch, Shorts, live, and youtu.be links). Videos resolve to the feed of
+// the channel that uploaded them. Where the channel ID isn't in the URL
+// itself, the page is fetched to find it.
+func (a *App) resolveFeedURL(rawURL string) (string, error) {
+	if !strings.Contains(rawURL, "://") {
+		// Allow URLs copied without the scheme, e.g. youtube.com/@handle
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", errors.Wrap(err, "parse url")
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case u.Path == "/feeds/videos.xml":
+		return rawURL, nil
+	case segments[0] == "playlist" && u.Query().Get("list") != "":
+		return playlistFeedURL(u.Query().Get("list")), nil
+	case segments[0] == "channel" && len(segments) > 1 && channelIDRegex.MatchString(segments[1]):
+		return channelFeedURL(segments[1]), nil
+	}
+
+	page, err := a.getVideoPage(u.String())
+	if err != nil {
+		return "", errors.Wrap(err, "fetch page")
+	}
+	for _, re := range pageChannelIDRegexes {
+		if matches := re.FindStringSubmatch(page); len(matches) > 1 {
+			return channelFeedURL(matches[1]), nil
+		}
+	}
+	return "", fmt.Errorf("no channel found for %s", rawURL)
+}
+
+// runSubscribe adds a feed to the URLs file. Any options after the URL are
+// added to the subscription as-is, e.g. `yt-rss subscribe <url> audio`.
+func (a *App) runSubscribe(args []string) error {
+	fs := flag.NewFlagSet("subscribe", flag.ExitOnError)
+	category := fs.String("category", "", "Add the feed to this category")
+	fs.Parse(args)
+	args = fs.Args()
+	if len(args) == 0 {
+		return errors.New("usage: yt-rss subscribe [--category <name>] <youtube url> [options...]")
+	}
+
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	feedURL, err := a.resolveFeedURL(args[0])
+	if err != nil {
+		return err
+	}
+	subscriptions, err := a.getSubscriptions()
+	if err != nil {
+		return err
+	}
+	for _, v := range subscriptions {
+		if v.URL == feedURL {
+			a.printer.Printf("Already subscribed to %s\n", feedURL)
+			return nil
+		}
+	}
+
+	lines, err := a.readURLsFile()
+	if err != nil {
+		return err
+	}
+	line := strings.Join(append([]string{feedURL}, args[1:]...), " ")
+	lines = insertSubscription(lines, line, *category)
+	err = a.writeURLsFileWithUndo(lines, "subscribe to "+feedURL)
+	if err != nil {
+		return err
+	}
+	a.printer.Printf("Subscribed to %s\n", feedURL)
+	return nil
+}
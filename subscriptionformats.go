@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Formats of `yt-rss export subscriptions`, for keeping the subscriptions of
+// privacy-focused YouTube frontends in sync with yt-rss.
+const (
+	subscriptionFormatNewPipe  = "newpipe"
+	subscriptionFormatFreeTube = "freetube"
+)
+
+var subscriptionFormats = []string{subscriptionFormatNewPipe, subscriptionFormatFreeTube}
+
+// newPipeYouTubeServiceID is NewPipe's ID for YouTube. NewPipe also supports
+// other services, e.g. PeerTube and SoundCloud, which yt-rss can't follow.
+const newPipeYouTubeServiceID = 0
+
+// newPipeSubscriptions is NewPipe's subscription export, from Settings >
+// Content > Export subscriptions.
+type newPipeSubscriptions struct {
+	AppVersion    string                `json:"app_version"`
+	AppVersionInt int                   `json:"app_version_int"`
+	Subscriptions []newPipeSubscription `json:"subscriptions"`
+}
+
+type newPipeSubscription struct {
+	ServiceID int    `json:"service_id"`
+	URL       string `json:"url"`
+	Name      string `json:"name"`
+}
+
+// freeTubeProfile is a line in FreeTube's profile database, which is what
+// FreeTube exports from Settings > Data Settings > Export Subscriptions. The
+// "allChannels" profile has every subscription, and the other profiles group
+// some of them, like categories.
+type freeTubeProfile struct {
+	Name          string                 `json:"name"`
+	BGColor       string                 `json:"bgColor"`
+	TextColor     string                 `json:"textColor"`
+	Subscriptions []freeTubeSubscription `json:"subscriptions"`
+	ID            string                 `json:"_id"`
+}
+
+type freeTubeSubscription struct {
+	ID        string `json:"id"` // The channel ID
+	Name      string `json:"name"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+const freeTubeAllChannelsProfileID = "allChannels"
+
+// readNewPipeSubscriptionList reads the channels to import from NewPipe's
+// subscriptions export. Subscriptions to other services than YouTube are
+// left out.
+func readNewPipeSubscriptionList(fileName string) ([]*importedSubscription, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var exported newPipeSubscriptions
+	err = json.Unmarshal(b, &exported)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse newpipe subscriptions")
+	}
+	var subscriptions []*importedSubscription
+	for _, v := range exported.Subscriptions {
+		if v.ServiceID != newPipeYouTubeServiceID {
+			continue
+		}
+		subscriptions = append(subscriptions, &importedSubscription{Input: v.URL, Title: v.Name})
+	}
+	return subscriptions, nil
+}
+
+// readFreeTubeSubscriptionList reads the channels to import from FreeTube's
+// profile database, a JSON object per line. Channels in a profile other than
+// "All Channels" are put in a category named after the profile, or the first
+// of them if there are several.
+func readFreeTubeSubscriptionList(fileName string) ([]*importedSubscription, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var subscriptions []*importedSubscription
+	lookup := make(map[string]*importedSubscription) // By channel ID
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var profile freeTubeProfile
+		err = json.Unmarshal([]byte(line), &profile)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse freetube profiles")
+		}
+		for _, v := range profile.Subscriptions {
+			subscription, ok := lookup[v.ID]
+			if !ok {
+				subscription = &importedSubscription{Input: channelFeedURL(v.ID), Title: v.Name}
+				lookup[v.ID] = subscription
+				subscriptions = append(subscriptions, subscription)
+			}
+			if profile.ID != freeTubeAllChannelsProfileID && subscription.Category == "" {
+				subscription.Category = profile.Name
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read freetube profiles")
+	}
+	return subscriptions, nil
+}
+
+// exportedChannel is a subscribed channel, for exporting subscriptions.
+type exportedChannel struct {
+	ID       string
+	Name     string
+	Category string
+}
+
+// getExportedChannels returns the subscribed channels, named as in the cache.
+// Only channel feeds can be exported, since neither NewPipe nor FreeTube can
+// subscribe to playlists, so playlist feeds are reported and left out.
+func (a *App) getExportedChannels() ([]exportedChannel, error) {
+	subscriptions, err := a.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	names, err := a.getChannelNames()
+	if err != nil {
+		return nil, err
+	}
+	var channels []exportedChannel
+	for _, v := range subscriptions {
+		channelID := parseChannelIDFromFeedURL(v.URL)
+		if channelID == "" {
+			a.logger.Warnf("Skipping %s, which isn't a channel\n", v.URL)
+			continue
+		}
+		name := names[channelID]
+		if name == "" {
+			// Not fetched yet
+			name = channelID
+		}
+		channels = append(channels, exportedChannel{ID: channelID, Name: name, Category: v.Category})
+	}
+	return channels, nil
+}
+
+// exportSubscriptions writes the subscribed channels in one of the
+// subscriptionFormats. Categories are exported as FreeTube profiles.
+func (a *App) exportSubscriptions(w io.Writer, format string) error {
+	if !slices.Contains(subscriptionFormats, format) {
+		return fmt.Errorf("unknown subscriptions format: %s (available: %s)", format, strings.Join(subscriptionFormats, ", "))
+	}
+	channels, err := a.getExportedChannels()
+	if err != nil {
+		return err
+	}
+
+	if format == subscriptionFormatNewPipe {
+		exported := newPipeSubscriptions{
+			// The oldest version with this format, which newer
+			// versions still import.
+			AppVersion:    "0.19.8",
+			AppVersionInt: 953,
+			Subscriptions: []newPipeSubscription{},
+		}
+		for _, v := range channels {
+			exported.Subscriptions = append(exported.Subscriptions, newPipeSubscription{
+				ServiceID: newPipeYouTubeServiceID,
+				URL:       "https://www.youtube.com/channel/" + v.ID,
+				Name:      v.Name,
+			})
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(exported)
+	}
+
+	profiles := []*freeTubeProfile{{
+		Name:          "All Channels",
+		BGColor:       "#000000",
+		TextColor:     "#FFFFFF",
+		Subscriptions: []freeTubeSubscription{},
+		ID:            freeTubeAllChannelsProfileID,
+	}}
+	categories := make(map[string]*freeTubeProfile)
+	for _, v := range channels {
+		subscription := freeTubeSubscription{ID: v.ID, Name: v.Name}
+		profiles[0].Subscriptions = append(profiles[0].Subscriptions, subscription)
+		if v.Category == "" {
+			continue
+		}
+		profile, ok := categories[v.Category]
+		if !ok {
+			profile = &freeTubeProfile{
+				Name:      v.Category,
+				BGColor:   "#000000",
+				TextColor: "#FFFFFF",
+				ID:        "yt-rss-" + v.Category,
+			}
+			categories[v.Category] = profile
+			profiles = append(profiles, profile)
+		}
+		profile.Subscriptions = append(profile.Subscriptions, subscription)
+	}
+	// FreeTube's database has a profile per line
+	encoder := json.NewEncoder(w)
+	for _, v := range profiles {
+		err = encoder.Encode(v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Subscription is a feed in the URLs file. Each line in the file is a feed
+// URL, optionally followed by whitespace-separated options, which are either
+// flags ("audio") or key-value pairs ("key=value" or key="some value"). Feeds
+// can be grouped into categories with headers. For example:
+//
+//	https://www.youtube.com/feeds/videos.xml?channel_id=UC... audio
+//
+//	[music]
+//	https://www.youtube.com/feeds/videos.xml?channel_id=UC...
+type Subscription struct {
+	URL      string
+	Options  map[string]string
+	Category string
+}
+
+func (s Subscription) HasOption(name string) bool {
+	_, ok := s.Options[name]
+	return ok
+}
+
+// parseSubscription parses a line in the URLs file. ok is false if the line
+// is blank, a comment, or a category header. Option values containing spaces
+// can be quoted.
+func parseSubscription(line string) (subscription Subscription, ok bool) {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return Subscription{}, false
+	}
+	if _, ok := parseCategoryHeader(line); ok {
+		return Subscription{}, false
+	}
+	fields, err := splitCommandLine(line)
+	if err != nil {
+		// Fall back to splitting on whitespace for malformed quoting
+		fields = strings.Fields(line)
+	}
+	if len(fields) == 0 {
+		return Subscription{}, false
+	}
+	subscription = Subscription{
+		URL:     fields[0],
+		Options: make(map[string]string),
+	}
+	for _, v := range fields[1:] {
+		key, value, _ := strings.Cut(v, "=")
+		subscription.Options[key] = value
+	}
+	return subscription, true
+}
+
+func (a *App) getSubscriptions() ([]Subscription, error) {
+	lines, err := a.readURLsFile()
+	if err != nil {
+		return nil, err
+	}
+	var subscriptions []Subscription
+	category := ""
+	for _, line := range lines {
+		if c, ok := parseCategoryHeader(line); ok {
+			category = c
+			continue
+		}
+		if subscription, ok := parseSubscription(line); ok {
+			subscription.Category = category
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+// getSubscriptionForEntry returns the subscription the entry was fetched
+// from.
+func (a *App) getSubscriptionForEntry(entry FeedEntry) (Subscription, bool, error) {
+	subscriptions, err := a.getSubscriptions()
+	if err != nil {
+		return Subscription{}, false, err
+	}
+	for _, v := range subscriptions {
+		if v.URL == entry.ExtraMetadata.FeedURL {
+			return v, true, nil
+		}
+	}
+	return Subscription{}, false, nil
+}
+
+const channelFeedURLPrefix = "https://www.youtube.com/feeds/videos.xml?channel_id="
+
+func channelFeedURL(channelID string) string {
+	return channelFeedURLPrefix + channelID
+}
+
+// parseChannelIDFromFeedURL returns the channel ID of a channel feed URL, or
+// an empty string if the URL is not a channel feed.
+func parseChannelIDFromFeedURL(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("channel_id")
+}
+
+// readURLsFile returns the lines of the URLs file, including comments.
+func (a *App) readURLsFile() ([]string, error) {
+	b, err := ioutil.ReadFile(a.configFile)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimRight(string(b), "\n")
+	if content == "" {
+		return nil, nil
+	}
+	return strings.Split(content, "\n"), nil
+}
+
+func (a *App) writeURLsFile(lines []string) error {
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	return os.WriteFile(a.configFile, []byte(content), 0644)
+}
+
+// writeURLsFileWithUndo writes the URLs file, recording its previous content
+// in the operation log so the change can be reverted with `yt-rss undo`.
+func (a *App) writeURLsFileWithUndo(lines []string, description string) error {
+	previousLines, err := a.readURLsFile()
+	if err != nil {
+		return err
+	}
+	err = a.recordOperation(Operation{
+		Timestamp:        time.Now(),
+		Description:      description,
+		PreviousURLsFile: previousLines,
+	})
+	if err != nil {
+		return errors.Wrap(err, "record operation")
+	}
+	return a.writeURLsFile(lines)
+}
+
+// removeFeedURLs returns the lines of the URLs file with the given feed URLs
+// removed.
+func removeFeedURLs(lines []string, feedURLs []string) []string {
+	remove := make(map[string]bool)
+	for _, v := range feedURLs {
+		remove[v] = true
+	}
+	var kept []string
+	for _, line := range lines {
+		if subscription, ok := parseSubscription(line); ok && remove[subscription.URL] {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+// takeoutSubscription is a row in the subscriptions.csv file exported by
+// Google Takeout.
+type takeoutSubscription struct {
+	ChannelID string
+	URL       string
+	Title     string
+}
+
+// readTakeoutSubscriptions parses Google Takeout's subscriptions.csv, which
+// has the columns: Channel Id, Channel Url, Channel Title. The file can also be
+// read straight from the Takeout archive, in which case it is found by name.
+func readTakeoutSubscriptions(fileName string) ([]takeoutSubscription, error) {
+	if strings.EqualFold(filepath.Ext(fileName), ".zip") {
+		return readTakeoutArchiveSubscriptions(fileName)
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseTakeoutSubscriptions(f)
+}
+
+// readTakeoutArchiveSubscriptions reads subscriptions.csv from a Takeout
+// .zip archive, where it is in e.g. "Takeout/YouTube and YouTube
+// Music/subscriptions/subscriptions.csv". The directory names are translated
+// in some languages, but the file name isn't.
+func readTakeoutArchiveSubscriptions(fileName string) ([]takeoutSubscription, error) {
+	archive, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, errors.Wrap(err, "open takeout archive")
+	}
+	defer archive.Close()
+	for _, f := range archive.File {
+		if path.Base(f.Name) != "subscriptions.csv" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "open %s", f.Name)
+		}
+		defer r.Close()
+		return parseTakeoutSubscriptions(r)
+	}
+	return nil, fmt.Errorf("no subscriptions.csv in %s", fileName)
+}
+
+func parseTakeoutSubscriptions(r io.Reader) ([]takeoutSubscription, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "parse subscriptions csv")
+	}
+
+	var subscriptions []takeoutSubscription
+	for i, record := range records {
+		if len(record) < 3 {
+			// Skip malformed rows
+			continue
+		}
+		// The file may start with a byte order mark. The header is
+		// translated in some languages, so it is told apart from a
+		// subscription by its channel ID.
+		channelID := strings.TrimSpace(strings.TrimPrefix(record[0], "\ufeff"))
+		if i == 0 && !channelIDRegex.MatchString(channelID) {
+			continue
+		}
+		subscriptions = append(subscriptions, takeoutSubscription{
+			ChannelID: channelID,
+			URL:       strings.TrimSpace(record[1]),
+			Title:     strings.TrimSpace(record[2]),
+		})
+	}
+	return subscriptions, nil
+}
+
+// getChannelNames returns a lookup of channel IDs to channel names, based on
+// the cached feed entries.
+func (a *App) getChannelNames() (map[string]string, error) {
+	useDatabase, err := a.useDatabase()
+	if err != nil {
+		return nil, err
+	}
+	if useDatabase {
+		return a.getChannelNamesFromDatabase()
+	}
+	entries, err := a.getFromCache()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string)
+	for _, v := range entries {
+		if v.ChannelID != "" {
+			names[v.ChannelID] = v.Author.Name
+		}
+	}
+	return names, nil
+}
+
+// runDiffSubscriptions compares the local subscriptions against a Google
+// Takeout subscriptions export, and offers to reconcile the differences.
+func (a *App) runDiffSubscriptions(args []string) error {
+	fs := flag.NewFlagSet("diff-subscriptions", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Only show the differences, without prompting to reconcile them")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: yt-rss diff-subscriptions [--dry-run] <subscriptions.csv>")
+	}
+
+	exported, err := readTakeoutSubscriptions(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	feedURLs, err := a.getFeedURLs()
+	if err != nil {
+		return err
+	}
+	channelNames, err := a.getChannelNames()
+	if err != nil {
+		return err
+	}
+
+	local := make(map[string]string) // channel ID to feed URL
+	for _, v := range feedURLs {
+		if channelID := parseChannelIDFromFeedURL(v); channelID != "" {
+			local[channelID] = v
+		}
+	}
+	exportedLookup := make(map[string]takeoutSubscription)
+	for _, v := range exported {
+		exportedLookup[v.ChannelID] = v
+	}
+
+	var onlyExported []takeoutSubscription
+	for _, v := range exported {
+		if _, ok := local[v.ChannelID]; !ok {
+			onlyExported = append(onlyExported, v)
+		}
+	}
+	var onlyLocal []string
+	for channelID := range local {
+		if _, ok := exportedLookup[channelID]; !ok {
+			onlyLocal = append(onlyLocal, channelID)
+		}
+	}
+	sort.Strings(onlyLocal)
+
+	if len(onlyExported) == 0 && len(onlyLocal) == 0 {
+		a.printer.Printf("Subscriptions are in sync\n")
+		return nil
+	}
+	a.printer.Printf("Only in account export (%d):\n", len(onlyExported))
+	for _, v := range onlyExported {
+		fmt.Printf("  + %s (%s)\n", v.Title, v.ChannelID)
+	}
+	a.printer.Printf("Only in local subscriptions (%d):\n", len(onlyLocal))
+	for _, channelID := range onlyLocal {
+		fmt.Printf("  - %s (%s)\n", getOrDefault(channelNames, channelID, a.printer.Sprintf("unknown channel")), channelID)
+	}
+	if *dryRun {
+		return nil
+	}
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	// Interactively reconcile the differences
+	fmt.Println()
+	stdin := bufio.NewReader(os.Stdin)
+	var toAdd []string
+	for _, v := range onlyExported {
+		if a.confirm(stdin, a.printer.Sprintf("Subscribe to %s locally?", v.Title)) {
+			toAdd = append(toAdd, channelFeedURL(v.ChannelID))
+		}
+	}
+	var toRemove []string
+	for _, channelID := range onlyLocal {
+		if a.confirm(stdin, a.printer.Sprintf("Unsubscribe from %s locally?", getOrDefault(channelNames, channelID, channelID))) {
+			toRemove = append(toRemove, local[channelID])
+		}
+	}
+	if len(toAdd) == 0 && len(toRemove) == 0 {
+		return nil
+	}
+
+	lines, err := a.readURLsFile()
+	if err != nil {
+		return err
+	}
+	lines = removeFeedURLs(lines, toRemove)
+	for _, v := range toAdd {
+		lines = insertSubscription(lines, v, "")
+	}
+	err = a.writeURLsFileWithUndo(lines, fmt.Sprintf("subscribe to %d and unsubscribe from %d channels", len(toAdd), len(toRemove)))
+	if err != nil {
+		return err
+	}
+	a.printer.Printf("Subscribed to %d channels, unsubscribed from %d channels\n", len(toAdd), len(toRemove))
+	return nil
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no.
+func (a *App) confirm(stdin *bufio.Reader, question string) bool {
+	a.printer.Printf("%s [y/N] ", question)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	switch answer {
+	case "y", "yes", a.printer.Sprintf("y"), a.printer.Sprintf("yes"):
+		return true
+	}
+	return false
+}
+
+func getOrDefault(m map[string]string, key, defaultValue string) string {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return defaultValue
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// openInBrowser opens the URL in the default browser. $BROWSER is used if
+// xdg-open isn't installed, e.g. on a system without a desktop environment.
+func openInBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return runShellCommand("open", []string{url}, nil, nil)
+	case "windows":
+		return runShellCommand("rundll32", []string{"url.dll,FileProtocolHandler", url}, nil, nil)
+	}
+	if _, err := exec.LookPath("xdg-open"); err == nil {
+		return runShellCommand("xdg-open", []string{url}, nil, nil)
+	}
+	browser := os.Getenv("BROWSER")
+	if browser == "" {
+		return errors.New("no browser found, install xdg-open or set $BROWSER")
+	}
+	// $BROWSER may have arguments, e.g. "firefox --new-window"
+	args, err := splitCommandLine(browser)
+	if err != nil || len(args) == 0 {
+		return fmt.Errorf("invalid browser: %s", browser)
+	}
+	return runShellCommand(args[0], append(args[1:], url), nil, nil)
+}
+
+// clipboardCommand is a command that writes stdin to the clipboard.
+type clipboardCommand struct {
+	Args []string
+	// Display is the environment variable that is set when the clipboard
+	// that the command writes to is available, e.g. WAYLAND_DISPLAY for
+	// wl-copy. Empty if the clipboard is always available.
+	Display string
+}
+
+// clipboardCommands are the commands that can write stdin to the clipboard,
+// in order of preference.
+var clipboardCommands = []clipboardCommand{
+	{Args: []string{"pbcopy"}},
+	{Args: []string{"wl-copy"}, Display: "WAYLAND_DISPLAY"},
+	{Args: []string{"xclip", "-selection", "clipboard"}, Display: "DISPLAY"},
+	{Args: []string{"xsel", "--clipboard", "--input"}, Display: "DISPLAY"},
+	{Args: []string{"clip.exe"}},
+}
+
+// findClipboardCommand returns the clipboard command for this system: the
+// first one that is installed and whose display is available, e.g. wl-copy
+// in a Wayland session and xclip under X11, even if both are installed. If
+// no display is detected, e.g. over SSH, the first installed command is
+// returned.
+func findClipboardCommand() ([]string, bool) {
+	var installed []clipboardCommand
+	for _, v := range clipboardCommands {
+		if _, err := exec.LookPath(v.Args[0]); err == nil {
+			installed = append(installed, v)
+		}
+	}
+	for _, v := range installed {
+		if v.Display == "" || os.Getenv(v.Display) != "" {
+			return v.Args, true
+		}
+	}
+	if len(installed) > 0 {
+		return installed[0].Args, true
+	}
+	return nil, false
+}
+
+// copyToClipboard copies the text to the system clipboard.
+func copyToClipboard(text string) error {
+	command, ok := findClipboardCommand()
+	if !ok {
+		return errors.New("no clipboard tool found, install one of wl-copy, xclip, xsel, or pbcopy")
+	}
+	return runShellCommand(command[0], command[1:], strings.NewReader(text), nil)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Picker backends
+const (
+	pickerAuto  = "auto"  // fzf, or tui if fzf isn't installed, or plain on dumb terminals
+	pickerFZF   = "fzf"   // fzf
+	pickerTUI   = "tui"   // The built-in terminal UI
+	pickerPlain = "plain" // A numbered list on stdout, read from stdin
+)
+
+// pickerBackends are the values of the picker setting.
+var pickerBackends = append([]string{pickerAuto, pickerFZF, pickerTUI, pickerPlain}, launcherPickers...)
+
+// isDumbTerminal returns true if the terminal can't handle ANSI escape
+// sequences, e.g. TERM=dumb, or a Windows console without VT support.
+func isDumbTerminal() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return true
+	}
+	return !supportsVT()
+}
+
+// getPickerBackend returns the picker to use. With "auto", the plain picker
+// is used if the terminal is dumb, and the built-in terminal UI if fzf isn't
+// installed.
+func (a *App) getPickerBackend() string {
+	if a.PickerBackend != pickerAuto {
+		return a.PickerBackend
+	}
+	if isDumbTerminal() {
+		return pickerPlain
+	}
+	if _, err := exec.LookPath("fzf"); err != nil {
+		return pickerTUI
+	}
+	return pickerFZF
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// supportsVT returns true if the terminal handles ANSI escape sequences.
+// Terminals without a TERM, e.g. some basic SSH sessions, are assumed not to.
+func supportsVT() bool {
+	return os.Getenv("TERM") != ""
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// Built-in themes, set with the theme setting
+const (
+	themeDefault = "default"
+	themeBright  = "bright"
+	themeMuted   = "muted"
+	themeMono    = "mono"
+	themeNone    = "none" // Disables colors entirely, like NO_COLOR
+)
+
+var themeNames = []string{themeDefault, themeBright, themeMuted, themeMono, themeNone}
+
+// themeElements are the parts of the picker and the preview that can be
+// colored, e.g. with `colors = "channel=bold cyan"`.
+var themeElements = []string{"date", "duration", "views", "channel", "title", "watched", "pinned", "later", "favorite", "category", "live"}
+
+// builtinThemes are the styles of each element in the built-in themes.
+// Elements that aren't listed have no style. See parseStyle.
+var builtinThemes = map[string]map[string]string{
+	themeDefault: {
+		"date":     "yellow",
+		"duration": "blue",
+		"views":    "blue",
+		"channel":  "green",
+		"watched":  "faint",
+		"pinned":   "magenta",
+		"later":    "blue",
+		"favorite": "yellow",
+		"category": "cyan",
+		"live":     "red",
+	},
+	themeBright: {
+		"date":     "hi-yellow",
+		"duration": "hi-blue",
+		"views":    "hi-blue",
+		"channel":  "bold hi-green",
+		"title":    "hi-white",
+		"watched":  "faint",
+		"pinned":   "bold hi-magenta",
+		"later":    "bold hi-blue",
+		"favorite": "bold hi-yellow",
+		"category": "hi-cyan",
+		"live":     "bold hi-red",
+	},
+	themeMuted: {
+		"date":     "faint",
+		"duration": "faint",
+		"views":    "faint",
+		"channel":  "cyan",
+		"watched":  "faint",
+		"pinned":   "magenta",
+		"later":    "blue",
+		"favorite": "yellow",
+		"category": "faint cyan",
+		"live":     "red",
+	},
+	themeMono: {
+		"date":     "faint",
+		"duration": "faint",
+		"views":    "faint",
+		"channel":  "bold",
+		"watched":  "faint",
+		"pinned":   "underline",
+		"later":    "underline",
+		"favorite": "bold",
+		"category": "italic",
+		"live":     "bold",
+	},
+	themeNone: {},
+}
+
+var styleAttributes = map[string]color.Attribute{
+	"bold":      color.Bold,
+	"faint":     color.Faint,
+	"italic":    color.Italic,
+	"underline": color.Underline,
+
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+
+	"hi-black":   color.FgHiBlack,
+	"hi-red":     color.FgHiRed,
+	"hi-green":   color.FgHiGreen,
+	"hi-yellow":  color.FgHiYellow,
+	"hi-blue":    color.FgHiBlue,
+	"hi-magenta": color.FgHiMagenta,
+	"hi-cyan":    color.FgHiCyan,
+	"hi-white":   color.FgHiWhite,
+
+	"bg-black":   color.BgBlack,
+	"bg-red":     color.BgRed,
+	"bg-green":   color.BgGreen,
+	"bg-yellow":  color.BgYellow,
+	"bg-blue":    color.BgBlue,
+	"bg-magenta": color.BgMagenta,
+	"bg-cyan":    color.BgCyan,
+	"bg-white":   color.BgWhite,
+}
+
+// textStyle is the color and attributes of an element of the theme.
+type textStyle []color.Attribute
+
+// parseStyle parses a style such as "bold hi-green". "none" has no style.
+func parseStyle(style string) (textStyle, error) {
+	var attributes textStyle
+	for _, v := range strings.Fields(strings.ToLower(style)) {
+		if v == "none" {
+			continue
+		}
+		attribute, ok := styleAttributes[v]
+		if !ok {
+			return nil, fmt.Errorf("unknown color or attribute: %s", v)
+		}
+		attributes = append(attributes, attribute)
+	}
+	return attributes, nil
+}
+
+func (s textStyle) Sprint(a ...interface{}) string {
+	if len(s) == 0 {
+		return fmt.Sprint(a...)
+	}
+	return color.New(s...).Sprint(a...)
+}
+
+func (s textStyle) Sprintf(format string, a ...interface{}) string {
+	return s.Sprint(fmt.Sprintf(format, a...))
+}
+
+// colorTheme holds the style of each of the themeElements.
+type colorTheme map[string]textStyle
+
+// newColorTheme returns the theme, with the overrides in colors, e.g.
+// "channel=bold cyan".
+func newColorTheme(name string, colors []string) (colorTheme, error) {
+	builtin, ok := builtinThemes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme: %s (available: %s)", name, strings.Join(themeNames, ", "))
+	}
+	styles := make(map[string]string)
+	for k, v := range builtin {
+		styles[k] = v
+	}
+	for _, v := range colors {
+		element, style, ok := strings.Cut(v, "=")
+		element = strings.TrimSpace(element)
+		if !ok || !slices.Contains(themeElements, element) {
+			return nil, fmt.Errorf("expected element=style, with an element of %s: %s", strings.Join(themeElements, ", "), v)
+		}
+		styles[element] = style
+	}
+	theme := make(colorTheme)
+	for element, v := range styles {
+		style, err := parseStyle(v)
+		if err != nil {
+			return nil, errors.Wrap(err, element)
+		}
+		theme[element] = style
+	}
+	return theme, nil
+}
+
+// setupTheme loads the theme. Colors are disabled entirely by the "none"
+// theme, or if NO_COLOR is set (see https://no-color.org).
+func (a *App) setupTheme() error {
+	theme, err := newColorTheme(a.Theme, a.Colors)
+	if err != nil {
+		return err
+	}
+	a.theme = theme
+	if a.Theme == themeNone || os.Getenv("NO_COLOR") != "" {
+		color.NoColor = true
+	}
+	return nil
+}
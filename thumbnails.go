@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Image viewers that render thumbnails, set with preview_image_viewer.
+const (
+	imageViewerAuto  = "auto"  // The first of kitty, iterm, or chafa that works in this terminal
+	imageViewerChafa = "chafa" // chafa, which picks the best protocol the terminal supports
+	imageViewerKitty = "kitty" // The kitty graphics protocol, through `kitty +kitten icat`
+	imageViewerSixel = "sixel" // Sixel graphics, through chafa or img2sixel
+	imageViewerITerm = "iterm" // iTerm2's inline images protocol, also supported by WezTerm
+)
+
+// maxThumbnailSize is the size of the largest thumbnail that is downloaded.
+// YouTube's thumbnails are far smaller than this.
+const maxThumbnailSize = 2 << 20
+
+func getThumbnailCacheDir() string {
+	return filepath.Join(getCacheDir(), "yt-rss", "thumbnails")
+}
+
+// resolveImageViewer returns the image viewer to render thumbnails with, and
+// whether it's usable. "auto" is resolved to a viewer based on the terminal.
+// detail describes why the viewer was chosen or can't be used.
+func (a *App) resolveImageViewer() (viewer string, detail string, ok bool) {
+	switch a.PreviewImageViewer {
+	case "":
+		return "", "preview_image_viewer isn't set", false
+	case imageViewerITerm:
+		return imageViewerITerm, "built in", true
+	case imageViewerSixel:
+		for _, command := range []string{"chafa", "img2sixel"} {
+			if detail, ok := lookPath(command); ok {
+				return imageViewerSixel, detail, true
+			}
+		}
+		return imageViewerSixel, "neither chafa nor img2sixel found", false
+	case imageViewerAuto:
+		if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty" {
+			if detail, ok := lookPath("kitty"); ok {
+				return imageViewerKitty, detail, true
+			}
+		}
+		switch os.Getenv("TERM_PROGRAM") {
+		case "iTerm.app", "WezTerm":
+			return imageViewerITerm, "built in, for " + os.Getenv("TERM_PROGRAM"), true
+		}
+		if detail, ok := lookPath("chafa"); ok {
+			return imageViewerChafa, detail, true
+		}
+		return "", "no supported image viewer found for this terminal", false
+	default:
+		detail, ok := lookPath(a.PreviewImageViewer)
+		return a.PreviewImageViewer, detail, ok
+	}
+}
+
+// getThumbnail returns the entry's thumbnail image. Thumbnails are cached on
+// disk, and the cache is kept under ThumbnailCacheSize by deleting the least
+// recently used thumbnails.
+func (a *App) getThumbnail(entry FeedEntry) ([]byte, error) {
+	url := entry.MediaGroup.Thumbnail.URL
+	if url == "" {
+		return nil, errors.New("the video has no thumbnail")
+	}
+	fileName := filepath.Join(getThumbnailCacheDir(), entry.YTVideoID+path.Ext(url))
+	if b, err := os.ReadFile(fileName); err == nil {
+		// Mark the thumbnail as recently used
+		now := time.Now()
+		os.Chtimes(fileName, now, now)
+		return b, nil
+	}
+
+	resp, err := a.httpGet(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxThumbnailSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxThumbnailSize {
+		return nil, fmt.Errorf("the thumbnail is larger than %s", formatSize(maxThumbnailSize))
+	}
+
+	if !a.ReadOnly && a.ThumbnailCacheSize != "" {
+		if err := os.MkdirAll(getThumbnailCacheDir(), 0700); err == nil {
+			// Failing to cache the thumbnail isn't fatal, it is
+			// fetched again next time.
+			writeFileAtomic(fileName, b, 0600)
+			a.pruneThumbnailCache()
+		}
+	}
+	return b, nil
+}
+
+// pruneThumbnailCache deletes the least recently used thumbnails until the
+// cache is under ThumbnailCacheSize.
+func (a *App) pruneThumbnailCache() {
+	maxSize, err := parseSize(a.ThumbnailCacheSize)
+	if err != nil {
+		return
+	}
+	files, err := os.ReadDir(getThumbnailCacheDir())
+	if err != nil {
+		return
+	}
+	var infos []os.FileInfo
+	var size int64
+	for _, v := range files {
+		info, err := v.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		infos = append(infos, info)
+		size += info.Size()
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if size <= maxSize {
+			break
+		}
+		if os.Remove(filepath.Join(getThumbnailCacheDir(), info.Name())) == nil {
+			size -= info.Size()
+		}
+	}
+}
+
+// printThumbnail renders the entry's thumbnail in the terminal with the
+// configured image viewer, sized to fit a third of fzf's preview pane.
+func (a *App) printThumbnail(entry FeedEntry) error {
+	viewer, detail, ok := a.resolveImageViewer()
+	if !ok {
+		return errors.New(detail)
+	}
+	image, err := a.getThumbnail(entry)
+	if err != nil {
+		return err
+	}
+
+	// fzf exposes the size of the preview pane through these variables.
+	// Use a third of the height for the thumbnail.
+	var columns, lines int
+	fmt.Sscanf(getEnvOrDefault("FZF_PREVIEW_COLUMNS", "80"), "%d", &columns)
+	fmt.Sscanf(getEnvOrDefault("FZF_PREVIEW_LINES", "30"), "%d", &lines)
+	height := max(lines/3, 1)
+	size := fmt.Sprintf("%dx%d", columns, height)
+
+	if viewer == imageViewerITerm {
+		fmt.Printf("\x1b]1337;File=inline=1;size=%d;height=%d;preserveAspectRatio=1:%s\a\n",
+			len(image), height, base64.StdEncoding.EncodeToString(image))
+		return nil
+	}
+
+	// The other viewers are external commands, which read the image
+	// from a file.
+	f, err := os.CreateTemp("", "yt-rss-thumbnail-*"+path.Ext(entry.MediaGroup.Thumbnail.URL))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(image)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	switch viewer {
+	case imageViewerChafa:
+		return runShellCommand("chafa", []string{"--size=" + size, f.Name()}, nil, os.Stdout)
+	case imageViewerSixel:
+		if _, err := exec.LookPath("chafa"); err == nil {
+			return runShellCommand("chafa", []string{"--format=sixels", "--size=" + size, f.Name()}, nil, os.Stdout)
+		}
+		// img2sixel sizes images in pixels. Assume cells are about 8
+		// pixels wide.
+		return runShellCommand("img2sixel", []string{fmt.Sprintf("--width=%d", columns*8), f.Name()}, nil, os.Stdout)
+	case imageViewerKitty:
+		place := fmt.Sprintf("%s@%sx%s", size, getEnvOrDefault("FZF_PREVIEW_LEFT", "0"), getEnvOrDefault("FZF_PREVIEW_TOP", "0"))
+		err := runShellCommand("kitty", []string{"+kitten", "icat", "--clear", "--transfer-mode=memory", "--stdin=no", "--place=" + place, f.Name()}, nil, os.Stdout)
+		if err != nil {
+			return err
+		}
+		// The image is drawn over the pane rather than inline, so
+		// leave room for it.
+		fmt.Print(strings.Repeat("\n", height))
+		return nil
+	default:
+		return fmt.Errorf("unsupported image viewer: %s", viewer)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+var (
+	// Emoji, including flags, keycaps, skin tones, and the joiners and
+	// variation selectors between them. Other symbols, e.g. © and °, are
+	// kept.
+	emojiRegex = regexp.MustCompile(`[\x{1F000}-\x{1FAFF}\x{2600}-\x{27BF}\x{2B00}-\x{2BFF}\x{231A}\x{231B}\x{23E9}-\x{23FA}\x{FE0F}\x{200D}\x{20E3}\x{E0020}-\x{E007F}]`)
+
+	// Brackets left empty once the words or emoji in them are stripped
+	emptyBracketsRegex = regexp.MustCompile(`\(\s*\)|\[\s*\]|\{\s*\}`)
+
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// titleRewrite is a rewrite rule for titles. See parseTitleRewrite.
+type titleRewrite struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// parseTitleRewrite parses a rewrite rule: a regular expression whose
+// matches are removed, e.g. `^EP\. \d+ \| `, or "pattern => replacement",
+// where the replacement can refer to groups as $1.
+func parseTitleRewrite(rule string) (titleRewrite, error) {
+	pattern, replacement, ok := strings.Cut(rule, "=>")
+	if ok {
+		pattern = strings.TrimSpace(pattern)
+		replacement = strings.TrimSpace(replacement)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return titleRewrite{}, err
+	}
+	return titleRewrite{Pattern: re, Replacement: replacement}, nil
+}
+
+// titleNormalizer rewrites titles for display, according to the title_*
+// settings and the rewrite= options in the URLs file.
+type titleNormalizer struct {
+	SentenceCase  bool
+	StripHashtags bool
+	StripEmoji    bool
+	Clickbait     *regexp.Regexp          // Matches any of the clickbait words, or nil if they aren't stripped
+	Rewrites      []titleRewrite          // Applied to every title, after the feed's rewrites
+	PerFeed       map[string]titleRewrite // Keyed by feed URL
+}
+
+// Normalize returns the title of an entry from the feed, normalized.
+// Rewrites are applied first, so that their patterns match the title as
+// it's shown on YouTube.
+func (n *titleNormalizer) Normalize(title string, feedURL string) string {
+	if v, ok := n.PerFeed[feedURL]; ok {
+		title = v.Pattern.ReplaceAllString(title, v.Replacement)
+	}
+	for _, v := range n.Rewrites {
+		title = v.Pattern.ReplaceAllString(title, v.Replacement)
+	}
+	if n.Clickbait != nil {
+		title = n.Clickbait.ReplaceAllString(title, "")
+	}
+	if n.StripHashtags {
+		title = hashtagRegex.ReplaceAllString(title, "")
+	}
+	if n.StripEmoji {
+		title = emojiRegex.ReplaceAllString(title, "")
+	}
+	if n.Clickbait != nil || n.StripEmoji {
+		title = emptyBracketsRegex.ReplaceAllString(title, "")
+	}
+	title = strings.TrimSpace(whitespaceRegex.ReplaceAllString(title, " "))
+	if n.SentenceCase && title != "" {
+		r := []rune(cases.Lower(language.English).String(title))
+		r[0] = unicode.ToUpper(r[0])
+		title = string(r)
+	}
+	return title
+}
+
+// newTitleNormalizer compiles the title rules. Global rewrites come from the
+// title_rewrite setting, and per-channel rewrites from the rewrite= option in
+// the URLs file, e.g.
+//
+//	https://www.youtube.com/feeds/videos.xml?channel_id=UC... rewrite='^EP\. \d+ \| '
+//
+// Titles are shown in every command, so invalid rules are skipped with a
+// warning rather than failing it. `yt-rss config validate` reports them too.
+func (a *App) newTitleNormalizer() *titleNormalizer {
+	n := &titleNormalizer{
+		SentenceCase:  a.TitleSentenceCase,
+		StripHashtags: a.TitleStripHashtags,
+		StripEmoji:    a.TitleStripEmoji,
+		PerFeed:       make(map[string]titleRewrite),
+	}
+	if a.TitleStripClickbait && len(a.TitleClickbaitWords) > 0 {
+		var words []string
+		for _, v := range a.TitleClickbaitWords {
+			words = append(words, regexp.QuoteMeta(strings.TrimSpace(v)))
+		}
+		// Words only match whole, so that e.g. "insane" doesn't
+		// match "insanely"
+		n.Clickbait = regexp.MustCompile(`(?i)\b(?:` + strings.Join(words, "|") + `)\b`)
+	}
+	for _, v := range a.TitleRewrites {
+		rewrite, err := parseTitleRewrite(v)
+		if err != nil {
+			a.logger.Warnf("Ignoring the invalid title_rewrite %s: %s\n", v, err)
+			continue
+		}
+		n.Rewrites = append(n.Rewrites, rewrite)
+	}
+
+	subscriptions, err := a.getSubscriptions()
+	if err != nil {
+		a.logger.Warnf("%s\n", errors.Wrap(err, "read rewrite rules"))
+		return n
+	}
+	for _, v := range subscriptions {
+		if rule, ok := v.Options["rewrite"]; ok {
+			rewrite, err := parseTitleRewrite(rule)
+			if err != nil {
+				a.logger.Warnf("Ignoring the invalid rewrite rule for %s: %s\n", v.URL, err)
+				continue
+			}
+			n.PerFeed[v.URL] = rewrite
+		}
+	}
+	return n
+}
+
+// normalizeTitle returns the entry's title, normalized. The rules are
+// compiled on first use.
+func (a *App) normalizeTitle(entry FeedEntry) string {
+	a.titleNormalizerOnce.Do(func() {
+		a.titleNormalizer = a.newTitleNormalizer()
+	})
+	return a.titleNormalizer.Normalize(entry.MediaGroup.Title, entry.ExtraMetadata.FeedURL)
+}
+
+// normalizeTitles sets the normalized titles of the entries. It's cheap, so
+// it's done for every entry, even those whose metadata was deferred.
+func (a *App) normalizeTitles(entries []FeedEntry) {
+	for i := range entries {
+		entries[i].ExtraMetadata.NormalizedTitle = a.normalizeTitle(entries[i])
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/muesli/termenv"
+	"github.com/pkg/errors"
+)
+
+// tuiItem is a line in the built-in picker.
+type tuiItem struct {
+	value   string // Returned when the item is selected, e.g. a picker line with the video ID
+	display string // Shown in the list, with colors
+	plain   string // display without colors, lowercased, for matching the query
+}
+
+// tuiPreviewMsg carries the preview of an item, rendered in the background.
+type tuiPreviewMsg struct {
+	value string
+	text  string
+}
+
+// tuiModel is the built-in picker, an alternative to fzf. Like fzf, typing
+// filters the list, tab selects multiple items, enter accepts, and the keys
+// bound to actions accept with that action.
+type tuiModel struct {
+	items    []tuiItem
+	matches  []int // Indices of the items that match the query, in order
+	cursor   int   // Index into matches
+	offset   int   // Index into matches of the first visible line
+	selected map[int]bool
+	multi    bool
+
+	input  textinput.Model
+	header string
+	keys   map[string]string // bubbletea key names to the fzf key names returned by runPicker
+
+	// preview renders an item for the preview pane. It is nil if the
+	// preview is disabled. Previews are cached, since rendering one runs
+	// yt-rss.
+	preview      func(item tuiItem, width, height int) string
+	previewCache map[string]string
+	loadingText  string // Shown in the preview pane until the preview is rendered
+
+	width, height int
+
+	// Set when the picker exits. accepted is false if it was dismissed.
+	accepted bool
+	key      string
+}
+
+func newTUIModel(items []tuiItem, prompt, query, header string, pos int) *tuiModel {
+	input := textinput.New()
+	input.Prompt = prompt
+	input.SetValue(query)
+	input.Focus()
+	m := &tuiModel{
+		items:        items,
+		selected:     make(map[int]bool),
+		input:        input,
+		header:       header,
+		keys:         make(map[string]string),
+		previewCache: make(map[string]string),
+	}
+	m.filter()
+	if pos > 1 {
+		m.cursor = min(pos-1, max(len(m.matches)-1, 0))
+	}
+	return m
+}
+
+// filter updates the matches for the query. Like fzf's default, each
+// space-separated term must appear in the line, ignoring case.
+func (m *tuiModel) filter() {
+	terms := strings.Fields(strings.ToLower(m.input.Value()))
+	m.matches = m.matches[:0]
+	for i, item := range m.items {
+		ok := true
+		for _, term := range terms {
+			if !strings.Contains(item.plain, term) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			m.matches = append(m.matches, i)
+		}
+	}
+	m.cursor, m.offset = 0, 0
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *tuiModel) listHeight() int {
+	// The prompt and the header take a line each
+	return max(m.height-2, 1)
+}
+
+func (m *tuiModel) listWidth() int {
+	if m.preview == nil {
+		return m.width
+	}
+	return m.width / 2
+}
+
+func (m *tuiModel) moveCursor(delta int) {
+	m.cursor = max(min(m.cursor+delta, len(m.matches)-1), 0)
+}
+
+// previewCmd renders the preview of the item under the cursor in the
+// background, unless it is cached.
+func (m *tuiModel) previewCmd() tea.Cmd {
+	if m.preview == nil || len(m.matches) == 0 || m.width == 0 {
+		return nil
+	}
+	item := m.items[m.matches[m.cursor]]
+	if _, ok := m.previewCache[item.value]; ok {
+		return nil
+	}
+	width, height := m.width-m.listWidth()-2, m.listHeight()
+	return func() tea.Msg {
+		return tuiPreviewMsg{value: item.value, text: m.preview(item, width, height)}
+	}
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		if msg.Width != m.width {
+			// Previews are wrapped to the width of the pane
+			m.previewCache = make(map[string]string)
+		}
+		m.width, m.height = msg.Width, msg.Height
+		return m, m.previewCmd()
+	case tuiPreviewMsg:
+		m.previewCache[msg.value] = msg.text
+		return m, nil
+	case tea.KeyMsg:
+		key := msg.String()
+		switch key {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+		case "enter":
+			m.accepted = true
+			return m, tea.Quit
+		case "up", "ctrl+p", "ctrl+k":
+			m.moveCursor(-1)
+			return m, m.previewCmd()
+		case "down", "ctrl+n", "ctrl+j":
+			m.moveCursor(1)
+			return m, m.previewCmd()
+		case "pgup":
+			m.moveCursor(-m.listHeight())
+			return m, m.previewCmd()
+		case "pgdown":
+			m.moveCursor(m.listHeight())
+			return m, m.previewCmd()
+		case "tab", "shift+tab":
+			if m.multi && len(m.matches) > 0 {
+				i := m.matches[m.cursor]
+				m.selected[i] = !m.selected[i]
+				if key == "tab" {
+					m.moveCursor(1)
+				} else {
+					m.moveCursor(-1)
+				}
+			}
+			return m, m.previewCmd()
+		}
+		if fzfKey, ok := m.keys[key]; ok {
+			m.accepted = true
+			m.key = fzfKey
+			return m, tea.Quit
+		}
+		query := m.input.Value()
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		if m.input.Value() != query {
+			m.filter()
+		}
+		return m, tea.Batch(cmd, m.previewCmd())
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+var (
+	tuiCursorStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5"))
+	tuiSelectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	tuiFaintStyle    = lipgloss.NewStyle().Faint(true)
+	tuiPreviewStyle  = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).PaddingLeft(1)
+)
+
+func (m *tuiModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	// Keep the cursor in view
+	height := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+
+	listWidth := m.listWidth()
+	count := tuiFaintStyle.Render(fmt.Sprintf(" %d/%d", len(m.matches), len(m.items)))
+	var lines []string
+	lines = append(lines, truncate.String(m.input.View()+count, uint(listWidth)))
+	lines = append(lines, tuiFaintStyle.Render(truncate.String(m.header, uint(listWidth))))
+	for i := m.offset; i < len(m.matches) && i < m.offset+height; i++ {
+		item := m.matches[i]
+		cursor, selected := " ", " "
+		if i == m.cursor {
+			cursor = tuiCursorStyle.Render(">")
+		}
+		if m.selected[item] {
+			selected = tuiSelectedStyle.Render("•")
+		}
+		lines = append(lines, cursor+selected+" "+truncate.String(m.items[item].display, uint(max(listWidth-3, 0))))
+	}
+	for len(lines) < m.height {
+		lines = append(lines, "")
+	}
+	list := lipgloss.NewStyle().Width(listWidth).Render(strings.Join(lines, "\n"))
+	if m.preview == nil {
+		return list
+	}
+
+	var preview string
+	if len(m.matches) > 0 {
+		text, ok := m.previewCache[m.items[m.matches[m.cursor]].value]
+		if !ok {
+			text = tuiFaintStyle.Render(m.loadingText)
+		}
+		previewLines := strings.Split(text, "\n")
+		if len(previewLines) > m.height {
+			previewLines = previewLines[:m.height]
+		}
+		preview = strings.Join(previewLines, "\n")
+	}
+	preview = tuiPreviewStyle.Height(m.height).MaxHeight(m.height).Render(preview)
+	return lipgloss.JoinHorizontal(lipgloss.Top, list, preview)
+}
+
+// runTUI runs the built-in picker on the terminal, drawing on stderr so that
+// stdout can be redirected.
+func runTUI(m *tuiModel) (accepted bool, err error) {
+	// By default, bubbletea queries the terminal's colors on startup,
+	// which takes seconds on terminals that don't answer. The colors
+	// aren't needed.
+	output := termenv.NewOutput(os.Stderr)
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(output))
+	_, err = p.Run()
+	if err != nil {
+		return false, err
+	}
+	return m.accepted, nil
+}
+
+// fzfKeyToTUIKey converts an fzf key name, e.g. ctrl-x, to bubbletea's name
+// for it, e.g. ctrl+x.
+func fzfKeyToTUIKey(key string) string {
+	for _, modifier := range []string{"ctrl-", "alt-", "shift-"} {
+		if strings.HasPrefix(key, modifier) {
+			return strings.TrimSuffix(modifier, "-") + "+" + strings.TrimPrefix(key, modifier)
+		}
+	}
+	return key
+}
+
+// runTUIPicker is the built-in alternative to fzf in runPicker. It shows
+// the picker's lines, with the same keys, and a preview pane rendered by
+// `yt-rss preview`, like fzf's.
+func (a *App) runTUIPicker(fzfContent string, actions []pickerAction, query string, pos int) (newQuery string, key string, selections []string, err error) {
+	var items []tuiItem
+	for _, line := range splitPickerLines(fzfContent) {
+		display := pickerLineDisplay(line)
+		items = append(items, tuiItem{
+			value:   line,
+			display: display,
+			plain:   strings.ToLower(ansiEscapeRegex.ReplaceAllString(display, "")),
+		})
+	}
+
+	header := []string{"enter: " + actionNameForKey(actions, "enter"), "tab: select", a.ActionsMenuKey + ": actions"}
+	m := newTUIModel(items, "> ", query, "", pos)
+	m.multi = true
+	m.keys[fzfKeyToTUIKey(a.ActionsMenuKey)] = a.ActionsMenuKey
+	for _, v := range actions {
+		if v.Key == "" || v.Key == "enter" {
+			continue
+		}
+		m.keys[fzfKeyToTUIKey(v.Key)] = v.Key
+		header = append(header, v.Key+": "+v.Name)
+	}
+	m.header = strings.Join(header, ", ")
+	if a.EnablePreview {
+		executable, err := os.Executable()
+		if err != nil {
+			return "", "", nil, errors.Wrap(err, "find executable")
+		}
+		m.loadingText = a.printer.Sprintf("Loading...")
+		m.preview = func(item tuiItem, width, height int) string {
+			videoID, _, _ := strings.Cut(item.value, "\t")
+			// Thumbnails are drawn with escape sequences that the
+			// TUI can't lay out, so they are left out.
+			cmd := exec.Command(executable, "preview", "--image-viewer=", videoID)
+			cmd.Env = append(os.Environ(), fmt.Sprintf("FZF_PREVIEW_COLUMNS=%d", width), fmt.Sprintf("FZF_PREVIEW_LINES=%d", height))
+			out, err := cmd.CombinedOutput()
+			if err != nil && len(out) == 0 {
+				return err.Error()
+			}
+			return lipgloss.NewStyle().Width(width).Render(strings.TrimRight(string(out), "\n"))
+		}
+	}
+
+	accepted, err := runTUI(m)
+	if err != nil || !accepted || len(m.matches) == 0 {
+		return m.input.Value(), "", nil, err
+	}
+	for i, item := range m.items {
+		if m.selected[i] {
+			selections = append(selections, item.value)
+		}
+	}
+	if len(selections) == 0 {
+		selections = []string{m.items[m.matches[m.cursor]].value}
+	}
+	return m.input.Value(), m.key, selections, nil
+}
+
+// runTUIMenu is the built-in alternative to fzf in runMenu.
+func runTUIMenu(prompt string, options []string) (selection string, ok bool, err error) {
+	var items []tuiItem
+	for _, v := range options {
+		items = append(items, tuiItem{value: v, display: v, plain: strings.ToLower(v)})
+	}
+	m := newTUIModel(items, prompt, "", "enter: select, esc: back", 0)
+	accepted, err := runTUI(m)
+	if err != nil || !accepted || len(m.matches) == 0 {
+		return "", false, err
+	}
+	return m.items[m.matches[m.cursor]].value, true, nil
+}
+
+// actionNameForKey returns the name of the action bound to the key.
+func actionNameForKey(actions []pickerAction, key string) string {
+	action, _ := findActionByKey(actions, key)
+	return action.Name
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxOperations is the number of operations kept in the operation log. Older
+// operations can no longer be undone.
+const maxOperations = 50
+
+// Operation records a state mutation, so that it can be undone later.
+type Operation struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Description string    `json:"description"`
+	// StateChanges are the changes the operation made to the state.
+	// Undoing the operation reverses them.
+	StateChanges *stateChanges `json:"state_changes,omitempty"`
+	// PreviousWatchRecords are the watch records changed by the operation,
+	// as they were before it was applied. A nil record means the record
+	// did not exist, and is deleted when the operation is undone.
+	PreviousWatchRecords map[string]*WatchRecord `json:"previous_watch_records,omitempty"`
+	// PreviousURLsFile is the content of the URLs file before the
+	// operation was applied, one line per item.
+	PreviousURLsFile []string `json:"previous_urls_file,omitempty"`
+}
+
+// stateChanges are the changes an operation made to the state. Undoing the
+// operation reverses only these, so that changes made to the state since,
+// e.g. by playing videos, aren't reverted along with it.
+type stateChanges struct {
+	Pinned                []string         `json:"pinned,omitempty"` // Entry IDs
+	Unpinned              []string         `json:"unpinned,omitempty"`
+	AddedToWatchLater     []string         `json:"added_to_watch_later,omitempty"` // Video IDs
+	RemovedFromWatchLater []watchLaterItem `json:"removed_from_watch_later,omitempty"`
+	AddedToQueue          []string         `json:"added_to_queue,omitempty"` // Video IDs
+	RemovedFromQueue      []queueItem      `json:"removed_from_queue,omitempty"`
+	// PreviousMutes are the muted channels changed by the operation, as
+	// they were before it was applied. A nil value means the channel
+	// wasn't muted.
+	PreviousMutes map[string]*mutedChannel `json:"previous_mutes,omitempty"`
+}
+
+// diffState returns the changes that turn the previous state into the
+// current one.
+func diffState(previous, current *State) *stateChanges {
+	c := &stateChanges{}
+	for _, id := range current.PinnedEntryIDs {
+		if !previous.IsPinned(id) {
+			c.Pinned = append(c.Pinned, id)
+		}
+	}
+	for _, id := range previous.PinnedEntryIDs {
+		if !current.IsPinned(id) {
+			c.Unpinned = append(c.Unpinned, id)
+		}
+	}
+	for _, v := range current.WatchLater {
+		if !previous.InWatchLater(v.Entry.YTVideoID) {
+			c.AddedToWatchLater = append(c.AddedToWatchLater, v.Entry.YTVideoID)
+		}
+	}
+	for _, v := range previous.WatchLater {
+		if !current.InWatchLater(v.Entry.YTVideoID) {
+			c.RemovedFromWatchLater = append(c.RemovedFromWatchLater, v)
+		}
+	}
+	for _, v := range current.Queue {
+		if !previous.InQueue(v.Entry.YTVideoID) {
+			c.AddedToQueue = append(c.AddedToQueue, v.Entry.YTVideoID)
+		}
+	}
+	for _, v := range previous.Queue {
+		if !current.InQueue(v.Entry.YTVideoID) {
+			c.RemovedFromQueue = append(c.RemovedFromQueue, v)
+		}
+	}
+	for channelID, m := range previous.MutedChannels {
+		if v, ok := current.MutedChannels[channelID]; !ok || !reflect.DeepEqual(v, m) {
+			if c.PreviousMutes == nil {
+				c.PreviousMutes = make(map[string]*mutedChannel)
+			}
+			m := m
+			c.PreviousMutes[channelID] = &m
+		}
+	}
+	for channelID := range current.MutedChannels {
+		if _, ok := previous.MutedChannels[channelID]; !ok {
+			if c.PreviousMutes == nil {
+				c.PreviousMutes = make(map[string]*mutedChannel)
+			}
+			c.PreviousMutes[channelID] = nil
+		}
+	}
+	return c
+}
+
+// revert reverses the changes in the state. Removed items are put back where
+// they were, since the watch-later list and the queue are in the order items
+// were added.
+func (c *stateChanges) revert(state *State) {
+	for _, id := range c.Pinned {
+		state.Unpin(id)
+	}
+	for _, id := range c.Unpinned {
+		if !state.IsPinned(id) {
+			state.PinnedEntryIDs = append(state.PinnedEntryIDs, id)
+		}
+	}
+	for _, videoID := range c.AddedToWatchLater {
+		state.RemoveFromWatchLater(videoID)
+	}
+	for _, item := range c.RemovedFromWatchLater {
+		if state.InWatchLater(item.Entry.YTVideoID) {
+			continue
+		}
+		i := slices.IndexFunc(state.WatchLater, func(v watchLaterItem) bool { return v.AddedAt.After(item.AddedAt) })
+		if i < 0 {
+			i = len(state.WatchLater)
+		}
+		state.WatchLater = slices.Insert(state.WatchLater, i, item)
+	}
+	for _, videoID := range c.AddedToQueue {
+		state.RemoveFromQueue(videoID)
+	}
+	for _, item := range c.RemovedFromQueue {
+		if state.InQueue(item.Entry.YTVideoID) {
+			continue
+		}
+		i := slices.IndexFunc(state.Queue, func(v queueItem) bool { return v.AddedAt.After(item.AddedAt) })
+		if i < 0 {
+			i = len(state.Queue)
+		}
+		state.Queue = slices.Insert(state.Queue, i, item)
+	}
+	for channelID, m := range c.PreviousMutes {
+		if m == nil {
+			delete(state.MutedChannels, channelID)
+			continue
+		}
+		if state.MutedChannels == nil {
+			state.MutedChannels = make(map[string]mutedChannel)
+		}
+		state.MutedChannels[channelID] = *m
+	}
+}
+
+func (a *App) getOperationLogFile() string {
+	return a.getDataFile(getStateDir(), "oplog.json")
+}
+
+func (a *App) loadOperationLog() ([]Operation, error) {
+	b, err := ioutil.ReadFile(a.getOperationLogFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var operations []Operation
+	err = json.Unmarshal(b, &operations)
+	if err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+func (a *App) saveOperationLog(operations []Operation) error {
+	if a.ReadOnly {
+		return nil
+	}
+	if len(operations) > maxOperations {
+		operations = operations[len(operations)-maxOperations:]
+	}
+	b, err := json.Marshal(operations)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.getOperationLogFile(), b, 0600)
+}
+
+// recordOperation appends the operation to the operation log.
+func (a *App) recordOperation(operation Operation) error {
+	operations, err := a.loadOperationLog()
+	if err != nil {
+		return errors.Wrap(err, "load operation log")
+	}
+	operations = append(operations, operation)
+	return a.saveOperationLog(operations)
+}
+
+// saveStateWithUndo saves the state like saveState, but also records the
+// changes in the operation log so that they can be reverted with `yt-rss
+// undo`.
+func (a *App) saveStateWithUndo(state *State, description string) error {
+	previousState, err := a.loadState()
+	if err != nil {
+		return err
+	}
+	err = a.recordOperation(Operation{
+		Timestamp:    time.Now(),
+		Description:  description,
+		StateChanges: diffState(previousState, state),
+	})
+	if err != nil {
+		return errors.Wrap(err, "record operation")
+	}
+	return a.saveState(state)
+}
+
+// runUndo reverts the most recent operation in the operation log.
+func (a *App) runUndo(args []string) error {
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	operations, err := a.loadOperationLog()
+	if err != nil {
+		return errors.Wrap(err, "load operation log")
+	}
+	if len(operations) == 0 {
+		return errors.New("nothing to undo")
+	}
+
+	last := operations[len(operations)-1]
+	if last.StateChanges != nil {
+		state, err := a.loadState()
+		if err != nil {
+			return err
+		}
+		last.StateChanges.revert(state)
+		err = a.saveState(state)
+		if err != nil {
+			return err
+		}
+	}
+	if len(last.PreviousWatchRecords) > 0 {
+		history, err := a.loadHistory()
+		if err != nil {
+			return err
+		}
+		for id, record := range last.PreviousWatchRecords {
+			if record == nil {
+				delete(history, id)
+			} else {
+				history[id] = record
+			}
+		}
+		err = a.saveHistory(history)
+		if err != nil {
+			return err
+		}
+	}
+	if last.PreviousURLsFile != nil {
+		err = a.writeURLsFile(last.PreviousURLsFile)
+		if err != nil {
+			return err
+		}
+	}
+	err = a.saveOperationLog(operations[:len(operations)-1])
+	if err != nil {
+		return errors.Wrap(err, "save operation log")
+	}
+	a.printer.Fprintf(os.Stderr, "Undid %s (%s)\n", last.Description, last.Timestamp.Format("02 Jan 15:04"))
+	return nil
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -13,39 +15,244 @@ import (
 var (
 	youtubeDurationRegex = regexp.MustCompile(`<meta itemprop="duration" content="(.+?)">`)
 
-	// This is not a proper ISO8601 parser. I'm only parsing the format
-	// typically seen in youtube's HTML.
-	iso8601DurationSimplifiedRegex = regexp.MustCompile(`PT(?P<minutes>\d+)M(?P<seconds>\d+)S`)
+	// ISO8601 durations, e.g. PT1H2M3S or P1DT2H. Years and months aren't
+	// supported, since their length varies, and YouTube doesn't use them.
+	iso8601DurationRegex = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+	// Fields in the player response embedded in the watch page, used to
+	// detect livestreams and premieres.
+	isLiveNowRegex     = regexp.MustCompile(`"isLiveNow":true`)
+	isUpcomingRegex    = regexp.MustCompile(`"isUpcoming":true`)
+	isLiveContentRegex = regexp.MustCompile(`"isLiveContent":true`)
+)
+
+// Live statuses of a video. Regular videos have an empty status.
+const (
+	liveStatusLive     = "live"     // Currently streaming
+	liveStatusUpcoming = "upcoming" // A scheduled livestream that hasn't started
+	liveStatusPremiere = "premiere" // A premiere that hasn't started
 )
 
-func getVideoDuration(url string) (time.Duration, error) {
-	resp, err := http.Get(url)
+// getVideoPage returns the HTML of the video's watch page.
+func (a *App) getVideoPage(url string) (string, error) {
+	limiter := a.metadataLimiter
+	limiter.Wait(a.ctx)
+	resp, err := a.httpGet(url)
 	if err != nil {
-		return 0, err
+		return "", err
 	}
+	limiter.Recover()
 	defer resp.Body.Close()
 
-	b, err := ioutil.ReadAll(resp.Body)
+	b, err := a.readBody(resp)
 	if err != nil {
-		return 0, err
+		return "", err
 	}
+	return string(b), nil
+}
 
-	matches := youtubeDurationRegex.FindStringSubmatch(string(b))
-	if len(matches) < 2 {
+// innertubePlayerURL is YouTube's internal API endpoint for the player
+// response of a video. It returns the same JSON that is embedded in the
+// watch page, without the consent and region interstitials that the watch
+// page is sometimes replaced with.
+const innertubePlayerURL = "https://www.youtube.com/youtubei/v1/player?prettyPrint=false"
+
+// innertubeClientVersion is the version of the web client that yt-rss
+// identifies as to the innertube API. Old versions keep working for a long
+// time, so it only needs updating if YouTube starts rejecting it.
+const innertubeClientVersion = "2.20250101.00.00"
+
+// playerResponseMarker precedes the player response embedded in the watch
+// page.
+const playerResponseMarker = "ytInitialPlayerResponse = "
+
+// playerResponse holds the fields of YouTube's player response that yt-rss
+// uses. It is embedded in the watch page as ytInitialPlayerResponse, and
+// returned by the innertube player endpoint.
+type playerResponse struct {
+	PlayabilityStatus struct {
+		Status string `json:"status"` // e.g. OK, UNPLAYABLE, or LOGIN_REQUIRED
+		Reason string `json:"reason"`
+	} `json:"playabilityStatus"`
+	VideoDetails struct {
+		VideoID       string `json:"videoId"`
+		LengthSeconds string `json:"lengthSeconds"`
+		IsLive        bool   `json:"isLive"`
+		IsUpcoming    bool   `json:"isUpcoming"`
+		IsLiveContent bool   `json:"isLiveContent"`
+	} `json:"videoDetails"`
+	Microformat struct {
+		PlayerMicroformatRenderer struct {
+			LiveBroadcastDetails struct {
+				IsLiveNow bool `json:"isLiveNow"`
+			} `json:"liveBroadcastDetails"`
+		} `json:"playerMicroformatRenderer"`
+	} `json:"microformat"`
+}
+
+// Duration returns the video's duration. Livestreams and premieres that
+// haven't ended have a duration of zero.
+func (r playerResponse) Duration() (time.Duration, error) {
+	if r.VideoDetails.LengthSeconds == "" {
+		if r.PlayabilityStatus.Reason != "" {
+			return 0, fmt.Errorf("duration not found: %s", r.PlayabilityStatus.Reason)
+		}
 		return 0, errors.New("duration not found")
 	}
-	durationString := matches[1] // iso8601 duration
+	seconds, err := strconv.Atoi(r.VideoDetails.LengthSeconds)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %s", r.VideoDetails.LengthSeconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// LiveStatus returns the video's live status, in the same way as
+// parseLiveStatus.
+func (r playerResponse) LiveStatus() string {
+	switch {
+	case r.VideoDetails.IsLive || r.Microformat.PlayerMicroformatRenderer.LiveBroadcastDetails.IsLiveNow:
+		return liveStatusLive
+	case r.VideoDetails.IsUpcoming && r.VideoDetails.IsLiveContent:
+		return liveStatusUpcoming
+	case r.VideoDetails.IsUpcoming:
+		return liveStatusPremiere
+	default:
+		return ""
+	}
+}
+
+// parsePlayerResponse extracts the player response embedded in the watch
+// page. ok is false if the page has none, e.g. a consent page.
+func parsePlayerResponse(page string) (r playerResponse, ok bool) {
+	i := strings.Index(page, playerResponseMarker)
+	if i < 0 {
+		return playerResponse{}, false
+	}
+	// The JSON object is followed by the rest of the script, which the
+	// decoder doesn't read.
+	err := json.NewDecoder(strings.NewReader(page[i+len(playerResponseMarker):])).Decode(&r)
+	if err != nil || r.VideoDetails.VideoID == "" {
+		return playerResponse{}, false
+	}
+	return r, true
+}
 
-	// Parse ISO8601 duration
-	matches = iso8601DurationSimplifiedRegex.FindStringSubmatch(durationString)
-	if len(matches) < 3 {
-		return 0, errors.New("duration not parsed correctly")
+// getInnertubePlayerResponse fetches the video's player response from the
+// innertube player endpoint.
+func (a *App) getInnertubePlayerResponse(videoID string) (playerResponse, error) {
+	body, err := json.Marshal(map[string]any{
+		"videoId": videoID,
+		"context": map[string]any{
+			"client": map[string]any{
+				"clientName":    "WEB",
+				"clientVersion": innertubeClientVersion,
+				"hl":            "en",
+			},
+		},
+	})
+	if err != nil {
+		return playerResponse{}, err
 	}
-	minutes := matches[1]
-	seconds := matches[2]
-	duration, err := time.ParseDuration(fmt.Sprintf("%sm%ss", minutes, seconds))
+	limiter := a.metadataLimiter
+	limiter.Wait(a.ctx)
+	resp, err := a.httpPostJSON(innertubePlayerURL, body)
 	if err != nil {
-		return 0, err
+		return playerResponse{}, err
+	}
+	limiter.Recover()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return playerResponse{}, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	b, err := a.readBody(resp)
+	if err != nil {
+		return playerResponse{}, err
+	}
+	var r playerResponse
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return playerResponse{}, errors.Wrap(err, "parse player response")
+	}
+	return r, nil
+}
+
+// getVideoDuration returns the video's duration and live status. They are
+// read from the player response embedded in the watch page, or from the
+// duration meta tag if the page has none. If the page can't be fetched, or
+// has neither, e.g. because it's a consent or region page, the player
+// response is fetched from the innertube API instead.
+func (a *App) getVideoDuration(entry FeedEntry) (duration time.Duration, liveStatus string, err error) {
+	page, pageErr := a.getCachedVideoPage(entry.YTVideoID, entry.MediaGroup.Content.URL)
+	if pageErr == nil {
+		if r, ok := parsePlayerResponse(page); ok {
+			if duration, err := r.Duration(); err == nil {
+				return duration, r.LiveStatus(), nil
+			}
+		}
+		liveStatus = parseLiveStatus(page)
+		duration, err = parseVideoDuration(page)
+		if err == nil || liveStatus != "" {
+			return duration, liveStatus, nil
+		}
+		pageErr = err
+	}
+
+	r, err := a.getInnertubePlayerResponse(entry.YTVideoID)
+	if err == nil {
+		duration, err = r.Duration()
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("%s, and from the innertube API: %s", errors.Wrap(pageErr, "from the watch page"), err)
+	}
+	a.logger.Debugf("Got the duration of %s from the innertube API, since the watch page failed: %s\n", entry.YTVideoID, pageErr)
+	return duration, r.LiveStatus(), nil
+}
+
+// parseVideoDuration parses the video duration from the duration meta tag of
+// the watch page, for pages without a player response.
+func parseVideoDuration(page string) (time.Duration, error) {
+	matches := youtubeDurationRegex.FindStringSubmatch(page)
+	if len(matches) < 2 {
+		return 0, errors.New("duration not found")
+	}
+	return parseISO8601Duration(matches[1])
+}
+
+// parseISO8601Duration parses an ISO8601 duration in weeks, days, hours,
+// minutes, and seconds, e.g. PT1H2M3S.
+func parseISO8601Duration(s string) (time.Duration, error) {
+	matches := iso8601DurationRegex.FindStringSubmatch(s)
+	if matches == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid ISO8601 duration: %s", s)
+	}
+	var duration time.Duration
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, unit := range units {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(matches[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO8601 duration: %s", s)
+		}
+		duration += time.Duration(n * float64(unit))
 	}
 	return duration, nil
 }
+
+// parseLiveStatus detects livestreams and premieres from the watch page.
+// Premieres are distinguished from scheduled livestreams by not being live
+// content.
+func parseLiveStatus(page string) string {
+	switch {
+	case isLiveNowRegex.MatchString(page):
+		return liveStatusLive
+	case isUpcomingRegex.MatchString(page) && isLiveContentRegex.MatchString(page):
+		return liveStatusUpcoming
+	case isUpcomingRegex.MatchString(page):
+		return liveStatusPremiere
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// roundTripFunc serves the requests of a test App without a network.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// newTestApp returns an App that makes each request once, without the page
+// cache or rate limiting, with the responses returned by serve.
+func newTestApp(serve roundTripFunc) *App {
+	config := defaultConfig()
+	config.PageCacheDuration = 0
+	config.MetadataRateLimit = 0
+	config.RetryAttempts = 1
+	a := newApp(config)
+	a.httpClient = &http.Client{Transport: serve}
+	return a
+}
+
+func testResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}
+}
+
+func TestParsePlayerResponse(t *testing.T) {
+	tests := []struct {
+		page       string
+		ok         bool
+		videoID    string
+		duration   time.Duration
+		liveStatus string
+	}{
+		{page: "watch_page.html", ok: true, videoID: "dQw4w9WgXcQ", duration: 212 * time.Second},
+		{page: "consent_page.html", ok: false},
+		{page: "watch_page_meta_duration.html", ok: false},
+	}
+	for _, tt := range tests {
+		r, ok := parsePlayerResponse(readTestdata(t, tt.page))
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.page, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if r.VideoDetails.VideoID != tt.videoID {
+			t.Errorf("%s: video ID = %q, want %q", tt.page, r.VideoDetails.VideoID, tt.videoID)
+		}
+		duration, err := r.Duration()
+		if err != nil || duration != tt.duration {
+			t.Errorf("%s: Duration() = %v, %v, want %v", tt.page, duration, err, tt.duration)
+		}
+		if r.LiveStatus() != tt.liveStatus {
+			t.Errorf("%s: LiveStatus() = %q, want %q", tt.page, r.LiveStatus(), tt.liveStatus)
+		}
+	}
+}
+
+func TestGetInnertubePlayerResponse(t *testing.T) {
+	body := readTestdata(t, "innertube_player.json")
+	a := newTestApp(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost || req.URL.String() != innertubePlayerURL {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			return testResponse(req, http.StatusNotFound, ""), nil
+		}
+		var request struct {
+			VideoID string `json:"videoId"`
+		}
+		err := json.NewDecoder(req.Body).Decode(&request)
+		if err != nil || request.VideoID != "dQw4w9WgXcQ" {
+			t.Errorf("unexpected request body: %+v, %v", request, err)
+		}
+		return testResponse(req, http.StatusOK, body), nil
+	})
+
+	r, err := a.getInnertubePlayerResponse("dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	duration, err := r.Duration()
+	if err != nil || duration != 212*time.Second {
+		t.Errorf("Duration() = %v, %v, want %v", duration, err, 212*time.Second)
+	}
+
+	a = newTestApp(func(req *http.Request) (*http.Response, error) {
+		return testResponse(req, http.StatusForbidden, ""), nil
+	})
+	_, err = a.getInnertubePlayerResponse("dQw4w9WgXcQ")
+	if err == nil {
+		t.Error("expected an error for a 403 response")
+	}
+}
+
+func TestGetVideoDuration(t *testing.T) {
+	tests := []struct {
+		page      string
+		duration  time.Duration
+		innertube bool // Whether the duration should come from the innertube API
+	}{
+		{page: "watch_page.html", duration: 212 * time.Second},
+		{page: "watch_page_meta_duration.html", duration: time.Hour + 2*time.Minute + 3*time.Second},
+		{page: "consent_page.html", duration: 212 * time.Second, innertube: true},
+	}
+	for _, tt := range tests {
+		page := readTestdata(t, tt.page)
+		player := readTestdata(t, "innertube_player.json")
+		var usedInnertube bool
+		a := newTestApp(func(req *http.Request) (*http.Response, error) {
+			if req.URL.String() == innertubePlayerURL {
+				usedInnertube = true
+				return testResponse(req, http.StatusOK, player), nil
+			}
+			return testResponse(req, http.StatusOK, page), nil
+		})
+		entry := FeedEntry{YTVideoID: "dQw4w9WgXcQ"}
+		entry.MediaGroup.Content.URL = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+
+		duration, liveStatus, err := a.getVideoDuration(entry)
+		if err != nil {
+			t.Errorf("%s: %s", tt.page, err)
+			continue
+		}
+		if duration != tt.duration || liveStatus != "" {
+			t.Errorf("%s: got %v, %q, want %v", tt.page, duration, liveStatus, tt.duration)
+		}
+		if usedInnertube != tt.innertube {
+			t.Errorf("%s: used the innertube API = %v, want %v", tt.page, usedInnertube, tt.innertube)
+		}
+	}
+}